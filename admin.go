@@ -0,0 +1,352 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// adminSessionCookie is the name of the cookie holding the admin session token
+	adminSessionCookie = "admin_session"
+
+	// adminContextKey is the gin context key set on requests with a valid
+	// admin session
+	adminContextKey = "admin"
+
+	// adminTimelineDays is the number of days shown in per-link click charts
+	adminTimelineDays = 30
+)
+
+// registerAdminRoutes wires the server-rendered admin dashboard into the router
+func registerAdminRoutes(router *gin.Engine, config *Config) {
+	router.GET("/admin/login", adminLoginPageHandler())
+	router.POST("/admin/login", adminLoginHandler(config))
+	router.POST("/admin/logout", adminLogoutHandler(config))
+
+	admin := router.Group("/admin")
+	admin.Use(adminAuthMiddleware(config))
+	{
+		admin.GET("", adminDashboardHandler(config))
+		admin.GET("/links/:alias", adminLinkHandler(config))
+		admin.POST("/links/:alias", adminUpdateLinkHandler(config))
+		admin.POST("/links/:alias/delete", adminDeleteLinkHandler(config))
+		admin.POST("/cleanup", adminCleanupHandler(config))
+		admin.GET("/events", eventsHandler(config))
+	}
+}
+
+// templateFuncs returns helper functions available to HTML templates
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"add":        func(a, b int) int { return a + b },
+		"displayURL": displayURL,
+	}
+}
+
+// adminAuthMiddleware rejects requests without a valid admin session
+func adminAuthMiddleware(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := c.Cookie(adminSessionCookie)
+		if err != nil || !verifyAdminSession(config, token) {
+			c.Redirect(http.StatusFound, "/admin/login")
+			c.Abort()
+			return
+		}
+
+		c.Set(adminContextKey, true)
+		c.Next()
+	}
+}
+
+// adminLoginPageHandler renders the login form
+func adminLoginPageHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.HTML(http.StatusOK, "admin_login.html", gin.H{})
+	}
+}
+
+// adminLoginHandler checks credentials and starts an admin session
+func adminLoginHandler(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username := c.PostForm("username")
+		password := c.PostForm("password")
+
+		userOK := subtle.ConstantTimeCompare([]byte(username), []byte(config.AdminUsername)) == 1
+		passOK := subtle.ConstantTimeCompare([]byte(password), []byte(config.AdminPassword)) == 1
+		if !userOK || !passOK {
+			log.Printf("Failed admin login attempt from %s (username=%s)", c.ClientIP(), username)
+			c.HTML(http.StatusUnauthorized, "admin_login.html", gin.H{
+				"error":    "Invalid username or password",
+				"username": username,
+			})
+			return
+		}
+
+		log.Printf("Admin login from %s", c.ClientIP())
+		setAdminSessionCookie(c, config, newAdminSession(config), int(config.AdminSessionTTL.Seconds()))
+		c.Redirect(http.StatusSeeOther, "/admin")
+	}
+}
+
+// adminLogoutHandler clears the admin session
+func adminLogoutHandler(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setAdminSessionCookie(c, config, "", -1)
+		c.Redirect(http.StatusSeeOther, "/admin/login")
+	}
+}
+
+// adminDashboardHandler renders global stats and the searchable link list
+func adminDashboardHandler(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		status := c.Query("status")
+		search := c.Query("q")
+		health := c.Query("health")
+
+		stats, err := GetStats(config)
+		if err != nil {
+			log.Printf("Error retrieving stats for admin dashboard: %v", err)
+			adminErrorPage(c, http.StatusInternalServerError, "Failed to retrieve statistics")
+			return
+		}
+
+		list, err := queryURLs(config, page, 25, status, search, health)
+		if err != nil {
+			log.Printf("Error retrieving URLs for admin dashboard: %v", err)
+			adminErrorPage(c, http.StatusInternalServerError, "Failed to retrieve URLs")
+			return
+		}
+
+		// Preserve filters across pagination links
+		query := url.Values{}
+		if status != "" {
+			query.Set("status", status)
+		}
+		if search != "" {
+			query.Set("q", search)
+		}
+		if health != "" {
+			query.Set("health", health)
+		}
+
+		c.HTML(http.StatusOK, "admin.html", gin.H{
+			"stats":   stats,
+			"list":    list,
+			"status":  status,
+			"search":  search,
+			"health":  health,
+			"query":   query.Encode(),
+			"notice":  c.Query("notice"),
+			"baseURL": config.BaseURL,
+		})
+	}
+}
+
+// adminLinkHandler renders a single link with its click chart and edit form
+func adminLinkHandler(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alias := aliasParam(c)
+
+		urlData, err := GetURLByAlias(config, alias)
+		if err != nil {
+			log.Printf("Database error retrieving %s for admin: %v", alias, err)
+			adminErrorPage(c, http.StatusInternalServerError, "Failed to retrieve URL")
+			return
+		}
+		if urlData == nil {
+			adminErrorPage(c, http.StatusNotFound, fmt.Sprintf("No URL found for alias: %s", alias))
+			return
+		}
+
+		timeline, err := GetClickTimeline(config, alias, adminTimelineDays)
+		if err != nil {
+			log.Printf("Error retrieving click timeline for %s: %v", alias, err)
+			adminErrorPage(c, http.StatusInternalServerError, "Failed to retrieve click history")
+			return
+		}
+
+		c.HTML(http.StatusOK, "admin_link.html", gin.H{
+			"url":          urlData,
+			"chart":        buildAdminChart(timeline),
+			"destinations": buildDestinationInfo(urlData.Destinations),
+			"days":         adminTimelineDays,
+			"notice":       c.Query("notice"),
+			"error":        c.Query("error"),
+			"expired":      urlData.Clicks >= urlData.MaxClicks,
+			"maxLimit":     maxClicksLimit,
+		})
+	}
+}
+
+// adminUpdateLinkHandler saves edits to a link's destination and click limit
+func adminUpdateLinkHandler(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alias := aliasParam(c)
+		linkPath := "/admin/links/" + url.PathEscape(alias)
+
+		sanitizedURL, err := sanitizeURL(config, c.PostForm("url"))
+		if err != nil {
+			c.Redirect(http.StatusSeeOther, linkPath+"?error="+url.QueryEscape(err.Error()))
+			return
+		}
+
+		maxClicks, err := strconv.Atoi(c.PostForm("max_clicks"))
+		if err != nil || maxClicks <= 0 || maxClicks > maxClicksLimit {
+			c.Redirect(http.StatusSeeOther, linkPath+"?error="+url.QueryEscape(fmt.Sprintf("Max clicks must be between 1 and %d", maxClicksLimit)))
+			return
+		}
+
+		var finalURL string
+		if config.ResolveRedirects {
+			resolved, err := resolveRedirects(config, sanitizedURL)
+			if err != nil {
+				c.Redirect(http.StatusSeeOther, linkPath+"?error="+url.QueryEscape(err.Error()))
+				return
+			}
+			if resolved != sanitizedURL {
+				finalURL = resolved
+			}
+		}
+
+		found, err := UpdateURL(config, alias, sanitizedURL, finalURL, maxClicks)
+		if err != nil {
+			log.Printf("Admin update of %s failed: %v", alias, err)
+			adminErrorPage(c, http.StatusInternalServerError, "Failed to update URL")
+			return
+		}
+		if !found {
+			adminErrorPage(c, http.StatusNotFound, fmt.Sprintf("No URL found for alias: %s", alias))
+			return
+		}
+
+		wakeMetadataFetcher(config)
+
+		log.Printf("Admin updated %s: url=%s, max_clicks=%d", alias, sanitizedURL, maxClicks)
+		c.Redirect(http.StatusSeeOther, linkPath+"?notice="+url.QueryEscape("Link updated"))
+	}
+}
+
+// adminDeleteLinkHandler deletes a link
+func adminDeleteLinkHandler(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alias := aliasParam(c)
+
+		found, err := DeleteURL(config, alias)
+		if err != nil {
+			log.Printf("Admin delete of %s failed: %v", alias, err)
+			adminErrorPage(c, http.StatusInternalServerError, "Failed to delete URL")
+			return
+		}
+		if !found {
+			adminErrorPage(c, http.StatusNotFound, fmt.Sprintf("No URL found for alias: %s", alias))
+			return
+		}
+
+		log.Printf("Admin deleted %s", alias)
+		c.Redirect(http.StatusSeeOther, "/admin?notice="+url.QueryEscape(fmt.Sprintf("Deleted %s", alias)))
+	}
+}
+
+// adminCleanupHandler removes expired links
+func adminCleanupHandler(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deletedCount, err := CleanupExpiredURLs(config)
+		if err != nil {
+			log.Printf("Admin cleanup failed: %v", err)
+			adminErrorPage(c, http.StatusInternalServerError, "Failed to cleanup expired URLs")
+			return
+		}
+
+		log.Printf("Admin cleanup completed: %d URLs deleted", deletedCount)
+		c.Redirect(http.StatusSeeOther, "/admin?notice="+url.QueryEscape(fmt.Sprintf("Cleanup removed %d expired links", deletedCount)))
+	}
+}
+
+// adminErrorPage renders an error inside the admin layout
+func adminErrorPage(c *gin.Context, status int, message string) {
+	c.HTML(status, "admin_error.html", gin.H{
+		"status":  status,
+		"message": message,
+	})
+}
+
+// adminChartBar is a single bar of the server-rendered click chart
+type adminChartBar struct {
+	Day     string
+	Clicks  int
+	Percent int
+}
+
+// buildAdminChart scales a click timeline into bar heights
+func buildAdminChart(timeline []ClickBucket) []adminChartBar {
+	maxClicks := 0
+	for _, bucket := range timeline {
+		if bucket.Clicks > maxClicks {
+			maxClicks = bucket.Clicks
+		}
+	}
+
+	bars := make([]adminChartBar, 0, len(timeline))
+	for _, bucket := range timeline {
+		percent := 0
+		if maxClicks > 0 {
+			percent = bucket.Clicks * 100 / maxClicks
+		}
+		bars = append(bars, adminChartBar{Day: bucket.Day, Clicks: bucket.Clicks, Percent: percent})
+	}
+
+	return bars
+}
+
+// newAdminSession creates a signed session token of the form "<expiry>.<signature>"
+func newAdminSession(config *Config) string {
+	expiry := strconv.FormatInt(time.Now().Add(config.AdminSessionTTL).Unix(), 10)
+	return expiry + "." + signAdminSession(config, expiry)
+}
+
+// verifyAdminSession checks a session token's signature and expiry
+func verifyAdminSession(config *Config, token string) bool {
+	expiry, signature, found := strings.Cut(token, ".")
+	if !found {
+		return false
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(signAdminSession(config, expiry))) {
+		return false
+	}
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return time.Now().Unix() < expiresAt
+}
+
+// signAdminSession signs the expiry together with the configured credentials,
+// so changing the username or password invalidates existing sessions
+func signAdminSession(config *Config, expiry string) string {
+	mac := hmac.New(sha256.New, []byte(config.AdminSessionSecret))
+	mac.Write([]byte(config.AdminUsername + "\x00" + config.AdminPassword + "\x00" + expiry))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// setAdminSessionCookie writes the session cookie with strict same-site rules,
+// which also protects the dashboard's POST forms from cross-site submission
+func setAdminSessionCookie(c *gin.Context, config *Config, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(adminSessionCookie, value, maxAge, "/admin", "", strings.HasPrefix(config.BaseURL, "https://"), true)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// builtinReservedWords cannot be used as aliases. Route names are reserved
+// as well, see reserveRoutes.
+var builtinReservedWords = []string{
+	"api", "admin", "www", "mail", "ftp", "localhost", "health", "stats",
+	"static", "assets", "public", "private", "secure", "login", "logout",
+	"register", "signup", "signin", "dashboard", "profile", "settings",
+}
+
+// aliasPrefixPattern matches alias prefixes assigned to API keys
+var aliasPrefixPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,19}$`)
+
+// aliasPolicy decides which aliases may be used: reserved words and route
+// names are refused outright, blocklisted words anywhere in the alias
+type aliasPolicy struct {
+	reserved  map[string]bool // Lowercase
+	blocklist []string        // In the form of blockedWords
+}
+
+// newAliasPolicy builds the alias policy from the built-in reserved words,
+// RESERVED_ALIASES_FILE and the configured blocklists
+func newAliasPolicy(config *Config) (*aliasPolicy, error) {
+	policy := &aliasPolicy{reserved: make(map[string]bool)}
+	for _, word := range builtinReservedWords {
+		policy.reserved[word] = true
+	}
+
+	if config.ReservedAliasesFile != "" {
+		words, err := loadWordListFile(config.ReservedAliasesFile)
+		if err != nil {
+			return nil, fmt.Errorf("RESERVED_ALIASES_FILE: %v", err)
+		}
+		for _, word := range words {
+			policy.reserved[word] = true
+		}
+	}
+
+	if config.AliasProfanityFilter {
+		policy.blocklist = append(policy.blocklist, profanityWords...)
+	}
+	for _, path := range config.AliasBlocklistFiles {
+		words, err := loadWordListFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("ALIAS_BLOCKLIST_FILES: %v", err)
+		}
+		policy.blocklist = append(policy.blocklist, blockedWords(words)...)
+	}
+
+	return policy, nil
+}
+
+// loadWordListFile reads a word list file in the format of parseWordList
+func loadWordListFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseWordList(file)
+}
+
+// reserveRoutes reserves the first path segment of every route, so custom
+// aliases cannot shadow them. Call it before the policy is used.
+func (p *aliasPolicy) reserveRoutes(routes gin.RoutesInfo) {
+	for _, route := range routes {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route.Path, "/"), "/")
+		if segment == "" || strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			continue
+		}
+		p.reserved[strings.ToLower(segment)] = true
+	}
+}
+
+// check returns an error describing why an alias may not be used
+func (p *aliasPolicy) check(alias string) error {
+	lowerAlias := strings.ToLower(alias)
+	if p.reserved[lowerAlias] {
+		return fmt.Errorf("'%s' is a reserved word and cannot be used as alias", alias)
+	}
+	if containsBlockedWord(alias, p.blocklist) {
+		return fmt.Errorf("'%s' contains a blocked word and cannot be used as alias", alias)
+	}
+	return nil
+}
+
+// allows reports whether an alias may be used
+func (p *aliasPolicy) allows(alias string) bool {
+	return p.check(alias) == nil
+}
+
+// hasAliasPrefix reports whether alias starts with prefix, ignoring case
+// when aliases are unique regardless of case
+func hasAliasPrefix(config *Config, alias, prefix string) bool {
+	if config.AliasCaseInsensitive {
+		return strings.HasPrefix(strings.ToLower(alias), strings.ToLower(prefix))
+	}
+	return strings.HasPrefix(alias, prefix)
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math/bits"
+	"math/rand/v2"
+	"slices"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Alias generation settings
+const (
+	defaultAliasAlphabet    = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	minAliasAlphabetSize    = 16
+	maxAliasAttempts        = 20  // Candidates tried per link before giving up
+	maxGeneratedAliasLength = 16  // Random aliases stop growing here
+	aliasGrowthWindow       = 100 // Candidates per collision rate measurement
+)
+
+// errAliasesExhausted is returned when every candidate alias was taken
+var errAliasesExhausted = errors.New("failed to generate unique alias")
+
+// aliasStrategy generates aliases for links created without a custom alias.
+// Candidates are checked by the database's unique constraint, so a strategy
+// only proposes them.
+type aliasStrategy interface {
+	// alias returns a candidate for the link stored in row id; attempt
+	// counts the candidates for this link that were already taken
+	alias(id int64, attempt int) string
+
+	// observe reports whether a candidate collided with an existing alias
+	observe(collided bool)
+}
+
+// aliasStrategies maps ALIAS_STRATEGY values to their constructors
+var aliasStrategies = map[string]func(config *Config) aliasStrategy{
+	"random": newRandomAliasStrategy,
+	"hashid": newHashidAliasStrategy,
+	"words":  newWordAliasStrategy,
+}
+
+// newAliasStrategies creates every alias strategy the configuration allows,
+// so requests can pick one by name
+func newAliasStrategies(config *Config) map[string]aliasStrategy {
+	strategies := make(map[string]aliasStrategy, len(aliasStrategies))
+	for name, newStrategy := range aliasStrategies {
+		if name == "hashid" && config.AliasSalt == "" {
+			continue // Needs a secret salt
+		}
+		strategies[name] = newStrategy(config)
+	}
+	return strategies
+}
+
+// aliasStrategyNames lists the alias styles requests may choose
+func (c *Config) aliasStrategyNames() []string {
+	names := make([]string, 0, len(c.aliases))
+	for name := range c.aliases {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// validateAliasAlphabet checks that an alphabet only holds characters that
+// are allowed in aliases and has enough distinct ones
+func validateAliasAlphabet(alphabet string) error {
+	seen := make(map[rune]bool, len(alphabet))
+	for _, char := range alphabet {
+		if !((char >= 'a' && char <= 'z') ||
+			(char >= 'A' && char <= 'Z') ||
+			(char >= '0' && char <= '9') ||
+			char == '-' || char == '_') {
+			return fmt.Errorf("%q is not allowed in aliases (letters, numbers, hyphens, underscores)", char)
+		}
+		if seen[char] {
+			return fmt.Errorf("%q appears more than once", char)
+		}
+		seen[char] = true
+	}
+
+	if len(seen) < minAliasAlphabetSize {
+		return fmt.Errorf("needs at least %d characters, got %d", minAliasAlphabetSize, len(seen))
+	}
+	return nil
+}
+
+// pendingAlias returns a unique placeholder for a row whose alias is
+// generated after insertion. "~" never appears in real aliases.
+func pendingAlias() string {
+	return "~" + generateRandomAlias(defaultAliasAlphabet, 22)
+}
+
+// assignGeneratedAlias replaces the placeholder alias of a freshly inserted
+// row with one from the configured strategy, trying candidates until one is
+// allowed by the alias policy and accepted by the unique constraint
+func assignGeneratedAlias(tx *sql.Tx, config *Config, urlData *URLData, id int64) error {
+	style := urlData.aliasStyle
+	if style == "" {
+		style = config.AliasStrategy
+	}
+	strategy, found := config.aliases[style]
+	if !found {
+		return fmt.Errorf("alias style %q is not available", style)
+	}
+
+	for attempt := 0; attempt < maxAliasAttempts; attempt++ {
+		alias := urlData.aliasPrefix + strategy.alias(id, attempt)
+		if !config.aliasPolicy.allows(alias) {
+			strategy.observe(true)
+			continue
+		}
+
+		shortURL := fmt.Sprintf("%s/%s", config.BaseURL, alias)
+		_, err := tx.Exec("UPDATE urls SET alias = ?, alias_skeleton = ?, short_url = ? WHERE id = ?",
+			alias, aliasSkeleton(alias, true), shortURL, id)
+		collided := isUniqueViolation(err)
+		strategy.observe(collided)
+
+		if err == nil {
+			urlData.Alias = alias
+			urlData.ShortURL = shortURL
+			return nil
+		}
+		if !collided {
+			return fmt.Errorf("failed to assign alias: %v", err)
+		}
+	}
+
+	return errAliasesExhausted
+}
+
+// isUniqueViolation reports whether err is a SQLite unique constraint failure
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// randomAliasStrategy picks random aliases and grows their length when too
+// many candidates collide, which happens as the keyspace fills up
+type randomAliasStrategy struct {
+	alphabet string
+
+	mu     sync.Mutex
+	length int
+	growth collisionTracker
+}
+
+// newRandomAliasStrategy creates the random strategy
+func newRandomAliasStrategy(config *Config) aliasStrategy {
+	return &randomAliasStrategy{
+		alphabet: config.AliasAlphabet,
+		length:   config.AliasLength,
+		growth:   collisionTracker{threshold: config.AliasGrowthThreshold},
+	}
+}
+
+// alias returns a random candidate of the current length
+func (s *randomAliasStrategy) alias(id int64, attempt int) string {
+	s.mu.Lock()
+	length := s.length
+	s.mu.Unlock()
+
+	return generateRandomAlias(s.alphabet, length)
+}
+
+// observe tracks the collision rate and grows the length when it is too high
+func (s *randomAliasStrategy) observe(collided bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rate, grow := s.growth.record(collided); grow && s.length < maxGeneratedAliasLength {
+		s.length++
+		log.Printf("📏 Alias collision rate %.0f%% exceeds %.0f%%, random aliases now have %d characters",
+			rate*100, s.growth.threshold*100, s.length)
+	}
+}
+
+// collisionTracker measures how many candidate aliases were taken over
+// windows of aliasGrowthWindow candidates
+type collisionTracker struct {
+	threshold float64 // Collision rate that triggers growth, 0 never grows
+
+	candidates int
+	collisions int
+}
+
+// record counts a candidate. At the end of a window it returns the window's
+// collision rate and whether it exceeded the threshold.
+func (t *collisionTracker) record(collided bool) (float64, bool) {
+	t.candidates++
+	if collided {
+		t.collisions++
+	}
+	if t.candidates < aliasGrowthWindow {
+		return 0, false
+	}
+
+	rate := float64(t.collisions) / float64(t.candidates)
+	t.candidates, t.collisions = 0, 0
+	return rate, t.threshold > 0 && rate > t.threshold
+}
+
+// hashidAliasStrategy encodes the row id of a link, Hashids-style, so every
+// link gets a distinct alias without retries. A salted permutation keeps
+// consecutive ids from producing guessable aliases. Aliases use the
+// configured length until its keyspace is used up, then grow by one.
+type hashidAliasStrategy struct {
+	salt      string
+	alphabet  string // Shuffled by the salt
+	minLength int
+}
+
+// newHashidAliasStrategy creates the hashid strategy
+func newHashidAliasStrategy(config *Config) aliasStrategy {
+	return &hashidAliasStrategy{
+		salt:      config.AliasSalt,
+		alphabet:  shuffleAlphabet(config.AliasAlphabet, config.AliasSalt),
+		minLength: config.AliasLength,
+	}
+}
+
+// alias encodes id. Only custom aliases can collide with an encoded id; a
+// retry then encodes it again with an alphabet reshuffled for the attempt.
+func (s *hashidAliasStrategy) alias(id int64, attempt int) string {
+	alphabet := s.alphabet
+	if attempt > 0 {
+		alphabet = shuffleAlphabet(alphabet, fmt.Sprintf("%s#%d", s.salt, attempt))
+	}
+	base := uint64(len(alphabet))
+
+	// Find the length whose keyspace holds id, counting from the shortest.
+	// Keyspaces too large for 64 bits hold every id and are not permuted.
+	n := uint64(id)
+	length := s.minLength
+	for {
+		space, ok := power(base, length)
+		if !ok {
+			break
+		}
+		if n < space {
+			n = s.permute(n, space, base)
+			break
+		}
+		n -= space
+		length++
+	}
+
+	digits := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		digits[i] = alphabet[n%base]
+		n /= base
+	}
+	return string(digits)
+}
+
+// observe is a no-op; encoded ids do not collide with each other
+func (s *hashidAliasStrategy) observe(collided bool) {}
+
+// permute maps n to another number below space, one to one. The multiplier
+// is coprime with the base and therefore with space, so the map is a bijection.
+func (s *hashidAliasStrategy) permute(n, space, base uint64) uint64 {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", s.salt, space)))
+	multiplier := binary.BigEndian.Uint64(sum[0:8]) % space
+	offset := binary.BigEndian.Uint64(sum[8:16]) % space
+	for multiplier == 0 || gcd(multiplier, base) != 1 {
+		multiplier = (multiplier + 1) % space
+	}
+
+	hi, lo := bits.Mul64(n, multiplier)
+	_, product := bits.Div64(hi, lo, space)
+	return (product + offset) % space
+}
+
+// shuffleAlphabet deterministically shuffles an alphabet by a salt
+func shuffleAlphabet(alphabet, salt string) string {
+	shuffled := []byte(alphabet)
+	rng := rand.New(rand.NewChaCha8(sha256.Sum256([]byte(salt))))
+	rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return string(shuffled)
+}
+
+// power returns base^exp and false when the result overflows 63 bits
+func power(base uint64, exp int) (uint64, bool) {
+	result := uint64(1)
+	for i := 0; i < exp; i++ {
+		hi, lo := bits.Mul64(result, base)
+		if hi != 0 || lo > 1<<63 {
+			return 0, false
+		}
+		result = lo
+	}
+	return result, true
+}
+
+// gcd returns the greatest common divisor of a and b
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// hexAlphabet is the smallest alphabet validateAliasAlphabet accepts
+const hexAlphabet = "0123456789abcdef"
+
+func newTestHashidStrategy(salt string, minLength int) *hashidAliasStrategy {
+	config := &Config{AliasSalt: salt, AliasAlphabet: hexAlphabet, AliasLength: minLength}
+	return newHashidAliasStrategy(config).(*hashidAliasStrategy)
+}
+
+func TestHashidAliasesUniqueAndDeterministic(t *testing.T) {
+	strategy := newTestHashidStrategy("secret", 2)
+	again := newTestHashidStrategy("secret", 2)
+	otherSalt := newTestHashidStrategy("other", 2)
+
+	// 16^2 two-character aliases, then 16^3 three-character ones
+	const ids = 256 + 4096 + 100
+	seen := make(map[string]int64, ids)
+	differs := false
+	for id := int64(0); id < ids; id++ {
+		alias := strategy.alias(id, 0)
+		if previous, found := seen[alias]; found {
+			t.Fatalf("ids %d and %d both encode to %q", previous, id, alias)
+		}
+		seen[alias] = id
+
+		if repeat := again.alias(id, 0); repeat != alias {
+			t.Fatalf("id %d encodes to %q and %q with the same salt", id, alias, repeat)
+		}
+		if otherSalt.alias(id, 0) != alias {
+			differs = true
+		}
+		if strings.Trim(alias, hexAlphabet) != "" {
+			t.Fatalf("alias %q uses characters outside the alphabet", alias)
+		}
+	}
+	if !differs {
+		t.Error("a different salt produced the same aliases")
+	}
+}
+
+func TestHashidAliasesGrowWhenSpaceIsUsedUp(t *testing.T) {
+	strategy := newTestHashidStrategy("secret", 2)
+
+	tests := []struct {
+		id         int64
+		wantLength int
+	}{
+		{0, 2},
+		{255, 2},
+		{256, 3},
+		{256 + 4095, 3},
+		{256 + 4096, 4},
+		{1 << 40, 10},
+		{1<<63 - 1, 16},
+	}
+	for _, tt := range tests {
+		if alias := strategy.alias(tt.id, 0); len(alias) != tt.wantLength {
+			t.Errorf("alias(%d) = %q, want %d characters", tt.id, alias, tt.wantLength)
+		}
+	}
+}
+
+func TestHashidAliasRetriesDiffer(t *testing.T) {
+	strategy := newTestHashidStrategy("secret", 4)
+	first := strategy.alias(42, 0)
+	for attempt := 1; attempt < 5; attempt++ {
+		retry := strategy.alias(42, attempt)
+		if retry == first || len(retry) != len(first) {
+			t.Errorf("attempt %d = %q, want another alias of the length of %q", attempt, retry, first)
+		}
+	}
+}
+
+func TestRandomAliasGrowth(t *testing.T) {
+	tests := []struct {
+		name       string
+		threshold  float64
+		length     int
+		collisions int // Per window of aliasGrowthWindow candidates
+		windows    int
+		wantLength int
+	}{
+		{"below threshold", 0.5, 6, 50, 3, 6},
+		{"above threshold", 0.5, 6, 51, 1, 7},
+		{"grows every window", 0.5, 6, 90, 3, 9},
+		{"partial window", 0.5, 6, 99, 0, 6},
+		{"disabled", 0, 6, 100, 3, 6},
+		{"capped", 0.5, maxGeneratedAliasLength, 100, 2, maxGeneratedAliasLength},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discardLogs(t)
+			strategy := newRandomAliasStrategy(&Config{
+				AliasAlphabet:        defaultAliasAlphabet,
+				AliasLength:          tt.length,
+				AliasGrowthThreshold: tt.threshold,
+			}).(*randomAliasStrategy)
+
+			candidates := tt.windows * aliasGrowthWindow
+			if tt.windows == 0 {
+				candidates = aliasGrowthWindow - 1
+			}
+			for i := 0; i < candidates; i++ {
+				strategy.observe(i%aliasGrowthWindow < tt.collisions)
+			}
+
+			if alias := strategy.alias(0, 0); len(alias) != tt.wantLength {
+				t.Errorf("alias %q has %d characters, want %d", alias, len(alias), tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestCollisionTrackerWindows(t *testing.T) {
+	tracker := collisionTracker{threshold: 0.25}
+	for i := 0; i < aliasGrowthWindow-1; i++ {
+		if _, grow := tracker.record(true); grow {
+			t.Fatalf("grew after %d candidates, before the window ended", i+1)
+		}
+	}
+	if rate, grow := tracker.record(false); !grow || rate != 0.99 {
+		t.Errorf("end of window = %.2f, %t, want 0.99, true", rate, grow)
+	}
+
+	// The next window starts from zero
+	for i := 0; i < aliasGrowthWindow; i++ {
+		rate, grow := tracker.record(i < 25)
+		if grow {
+			t.Errorf("grew at a rate of %.2f, not above the threshold", rate)
+		}
+	}
+}
+
+func TestShuffleAlphabet(t *testing.T) {
+	shuffled := shuffleAlphabet(defaultAliasAlphabet, "secret")
+	if shuffled != shuffleAlphabet(defaultAliasAlphabet, "secret") {
+		t.Error("the same salt shuffled differently")
+	}
+	if shuffled == defaultAliasAlphabet || shuffled == shuffleAlphabet(defaultAliasAlphabet, "other") {
+		t.Error("the shuffle does not depend on the salt")
+	}
+
+	got, want := []byte(shuffled), []byte(defaultAliasAlphabet)
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("shuffled alphabet %q is not a permutation of the alphabet", shuffled)
+	}
+}
+
+func TestPowerAndGCD(t *testing.T) {
+	powers := []struct {
+		base   uint64
+		exp    int
+		want   uint64
+		wantOK bool
+	}{
+		{16, 0, 1, true},
+		{16, 3, 4096, true},
+		{2, 63, 1 << 63, true},
+		{2, 64, 0, false},
+		{62, 10, 839299365868340224, true},
+		{62, 11, 0, false},
+	}
+	for _, tt := range powers {
+		if got, ok := power(tt.base, tt.exp); got != tt.want || ok != tt.wantOK {
+			t.Errorf("power(%d, %d) = %d, %t, want %d, %t", tt.base, tt.exp, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	gcds := []struct{ a, b, want uint64 }{
+		{12, 18, 6},
+		{17, 62, 1},
+		{62, 0, 62},
+		{0, 16, 16},
+	}
+	for _, tt := range gcds {
+		if got := gcd(tt.a, tt.b); got != tt.want {
+			t.Errorf("gcd(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestValidateAliasAlphabet(t *testing.T) {
+	tests := []struct {
+		name     string
+		alphabet string
+		valid    bool
+	}{
+		{"default", defaultAliasAlphabet, true},
+		{"hex", hexAlphabet, true},
+		{"with hyphen and underscore", "abcdefghijklmn-_", true},
+		{"too short", "abcdefghijklmno", false},
+		{"duplicate", "0123456789abcdeff", false},
+		{"duplicates hide shortness", "0123456789abcdeeeeee", false},
+		{"slash", hexAlphabet + "/", false},
+		{"dot", hexAlphabet + ".", false},
+		{"space", hexAlphabet + " ", false},
+		{"tilde", hexAlphabet + "~", false},
+		{"non-ASCII", hexAlphabet + "é", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		if err := validateAliasAlphabet(tt.alphabet); (err == nil) != tt.valid {
+			t.Errorf("%s: validateAliasAlphabet(%q) = %v, want valid %t", tt.name, tt.alphabet, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiV1Prefix is the path of the current API version
+const apiV1Prefix = "/api/v1"
+
+// eventsPath serves the event stream outside /api/v1 as well. It is where
+// the stream was first announced, and unlike the legacy routes it is not
+// deprecated.
+const eventsPath = "/api/events"
+
+// legacyAPIDeprecatedAt is when the unversioned /api routes were
+// deprecated in favour of /api/v1, sent in their Deprecation header
+var legacyAPIDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// legacyAPIRoute is an unversioned /api route from before /api/v1. It is
+// served by the handler of its /api/v1 successor, with headers announcing
+// the deprecation.
+type legacyAPIRoute struct {
+	method     string
+	path       string
+	successor  string
+	handler    func(*Config) gin.HandlerFunc
+	idempotent bool // Accepts an Idempotency-Key header
+}
+
+var legacyAPIRoutes = []legacyAPIRoute{
+	{http.MethodPost, "/api/shorten", apiV1Prefix + "/shorten", shortenHandler, true},
+	{http.MethodGet, "/api/stats", apiV1Prefix + "/stats", statsHandler, false},
+	{http.MethodGet, "/api/urls", apiV1Prefix + "/urls", listURLsHandler, false},
+	{http.MethodGet, "/api/info/:alias", apiV1Prefix + "/urls/:alias", urlInfoHandler, false},
+	{http.MethodDelete, "/api/urls/:alias", apiV1Prefix + "/urls/:alias", deleteURLHandler, false},
+	{http.MethodPut, "/api/urls/:alias/rules", apiV1Prefix + "/urls/:alias/rules", updateRulesHandler, false},
+	{http.MethodPost, "/api/cleanup", apiV1Prefix + "/cleanup", cleanupHandler, false},
+}
+
+// registerAPIRoutes adds the /api/v1 routes and their deprecated
+// unversioned aliases to router
+func registerAPIRoutes(router *gin.Engine, config *Config) {
+	requireAPIKey := apiKeyMiddleware(config)
+	idempotent := idempotencyMiddleware(config)
+
+	v1 := router.Group(apiV1Prefix)
+	v1.Use(requireAPIKey)
+	{
+		v1.POST("/shorten", idempotent, shortenHandler(config))
+		v1.GET("/stats", statsHandler(config))
+		v1.GET("/urls", listURLsHandler(config))
+		v1.GET("/urls/:alias", urlInfoHandler(config))
+		v1.DELETE("/urls/:alias", deleteURLHandler(config))
+		v1.PUT("/urls/:alias/rules", updateRulesHandler(config))
+		v1.POST("/cleanup", cleanupHandler(config))
+		v1.GET("/events", eventsHandler(config))
+	}
+	router.GET(eventsPath, requireAPIKey, eventsHandler(config))
+
+	// Headers are set before the key check so rejected requests see them too
+	for _, route := range legacyAPIRoutes {
+		handlers := []gin.HandlerFunc{deprecatedRoute(route.successor), requireAPIKey}
+		if route.idempotent {
+			handlers = append(handlers, idempotent)
+		}
+		router.Handle(route.method, route.path, append(handlers, route.handler(config))...)
+	}
+}
+
+// deprecatedRoute marks responses of a legacy route as deprecated (RFC
+// 9745) and links to the route replacing it
+func deprecatedRoute(successor string) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", legacyAPIDeprecatedAt.Unix())
+
+	return func(c *gin.Context) {
+		link := successor
+		for _, param := range c.Params {
+			link = strings.Replace(link, ":"+param.Key, url.PathEscape(param.Value), 1)
+		}
+
+		c.Header("Deprecation", deprecation)
+		c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", link))
+		log.Printf("Deprecated route %s %s used by %s, use %s instead", c.Request.Method, c.FullPath(), c.ClientIP(), successor)
+		c.Next()
+	}
+}
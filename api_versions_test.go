@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLegacyRoutesDeprecated(t *testing.T) {
+	discardLogs(t)
+	config := newTestConfig(t)
+	router := gin.New()
+	registerAPIRoutes(router, config)
+
+	deprecation := fmt.Sprintf("@%d", legacyAPIDeprecatedAt.Unix())
+
+	tests := []struct {
+		method   string
+		path     string
+		wantLink string // Successor in the Link header, "" for current routes
+	}{
+		{http.MethodPost, "/api/shorten", "/api/v1/shorten"},
+		{http.MethodGet, "/api/stats", "/api/v1/stats"},
+		{http.MethodGet, "/api/urls", "/api/v1/urls"},
+		{http.MethodGet, "/api/info/my-link", "/api/v1/urls/my-link"},
+		{http.MethodGet, "/api/info/a%20b", "/api/v1/urls/a%20b"},
+		{http.MethodDelete, "/api/urls/my-link", "/api/v1/urls/my-link"},
+		{http.MethodPut, "/api/urls/my-link/rules", "/api/v1/urls/my-link/rules"},
+		{http.MethodPost, "/api/cleanup", "/api/v1/cleanup"},
+
+		{http.MethodPost, "/api/v1/shorten", ""},
+		{http.MethodGet, "/api/v1/stats", ""},
+		{http.MethodGet, "/api/v1/urls", ""},
+		{http.MethodGet, "/api/v1/urls/my-link", ""},
+		{http.MethodDelete, "/api/v1/urls/my-link", ""},
+		{http.MethodPut, "/api/v1/urls/my-link/rules", ""},
+		{http.MethodPost, "/api/v1/cleanup", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			wantDeprecation, wantLink := "", ""
+			if tt.wantLink != "" {
+				wantDeprecation = deprecation
+				wantLink = fmt.Sprintf("<%s>; rel=\"successor-version\"", tt.wantLink)
+			}
+			if got := w.Header().Get("Deprecation"); got != wantDeprecation {
+				t.Errorf("Deprecation = %q, want %q", got, wantDeprecation)
+			}
+			if got := w.Header().Get("Link"); got != wantLink {
+				t.Errorf("Link = %q, want %q", got, wantLink)
+			}
+		})
+	}
+}
+
+func TestLegacyRoutesDeprecatedWhenRejected(t *testing.T) {
+	discardLogs(t)
+	t.Setenv("REQUIRE_API_KEY", "true")
+	config := newTestConfig(t)
+	router := gin.New()
+	registerAPIRoutes(router, config)
+
+	// Clients still learn about the successor when the key check fails
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w.Header().Get("Deprecation") == "" || w.Header().Get("Link") == "" {
+		t.Errorf("rejected legacy request is missing Deprecation or Link: %v", w.Header())
+	}
+}
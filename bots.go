@@ -0,0 +1,202 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Per-link settings for how bots are served
+const (
+	botResponseRedirect = "redirect" // Redirect bots like any visitor
+	botResponseMetadata = "metadata" // Serve bots a page describing the link instead
+)
+
+// defaultBotSignatures is the signature list built into the binary
+//
+//go:embed bot_signatures.txt
+var defaultBotSignatures string
+
+// botLevel is how sure the classifier is that a request comes from a bot
+type botLevel int
+
+const (
+	botNone botLevel = iota
+	// botLikely hits look automated but may come from a person using an HTTP
+	// client. They are recorded as bot hits and still use up clicks.
+	botLikely
+	// botKnown hits are HEAD requests and crawler signatures. They do not
+	// use up clicks while SKIP_BOT_CLICKS is on.
+	botKnown
+)
+
+// botSignature is a User-Agent substring. Client signatures name HTTP
+// clients people also use by hand and only make a hit botLikely.
+type botSignature struct {
+	text   string
+	client bool
+}
+
+// botSignatures holds the active signature list. It starts as the built-in
+// list and is replaced by watchBotSignatures.
+var botSignatures atomic.Pointer[[]botSignature]
+
+func init() {
+	signatures, _ := parseBotSignatures(strings.NewReader(defaultBotSignatures))
+	botSignatures.Store(&signatures)
+}
+
+// parseBotSignatures reads a signature list. Lines starting with ~ are
+// client signatures.
+func parseBotSignatures(r io.Reader) ([]botSignature, error) {
+	words, err := parseWordList(r)
+	if err != nil {
+		return nil, err
+	}
+
+	signatures := make([]botSignature, 0, len(words))
+	for _, word := range words {
+		text, client := strings.CutPrefix(word, "~")
+		if text = strings.TrimSpace(text); text != "" {
+			signatures = append(signatures, botSignature{text: text, client: client})
+		}
+	}
+	return signatures, nil
+}
+
+// loadBotSignatures reads a signature file, rejecting files without any
+// signatures
+func loadBotSignatures(path string) ([]botSignature, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	signatures, err := parseBotSignatures(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if len(signatures) == 0 {
+		return nil, fmt.Errorf("%s contains no signatures", path)
+	}
+
+	return signatures, nil
+}
+
+// watchBotSignatures loads BOT_SIGNATURES_FILE and re-reads it whenever the
+// configuration is reloaded, so the list can be updated without a rebuild
+func watchBotSignatures(config *Config) {
+	refreshBotSignatures(config)
+	config.OnReload(func() {
+		refreshBotSignatures(config)
+	})
+}
+
+// refreshBotSignatures swaps in the configured signature list, keeping the
+// current list if the file cannot be read
+func refreshBotSignatures(config *Config) {
+	path := config.GetBotSignaturesFile()
+	if path == "" {
+		signatures, _ := parseBotSignatures(strings.NewReader(defaultBotSignatures))
+		botSignatures.Store(&signatures)
+		return
+	}
+
+	signatures, err := loadBotSignatures(path)
+	if err != nil {
+		log.Printf("⚠️  Keeping current bot signatures: %v", err)
+		return
+	}
+
+	botSignatures.Store(&signatures)
+	log.Printf("🤖 Loaded %d bot signatures from %s", len(signatures), path)
+}
+
+// matchBotSignature returns the first signature found in a User-Agent
+func matchBotSignature(userAgent string) (botSignature, bool) {
+	userAgent = strings.ToLower(userAgent)
+	for _, signature := range *botSignatures.Load() {
+		if strings.Contains(userAgent, signature.text) {
+			return signature, true
+		}
+	}
+	return botSignature{}, false
+}
+
+// classifyBot decides whether a request comes from a bot. HEAD requests and
+// crawler signatures are known bots. Client signatures and requests that
+// browsers never send, with an empty User-Agent or no Accept-Language
+// header, are only likely bots: a person running curl looks the same. The
+// returned reason is used for logging.
+func classifyBot(r *http.Request) (botLevel, string) {
+	if r.Method == http.MethodHead {
+		return botKnown, "head_request"
+	}
+
+	userAgent := strings.TrimSpace(r.UserAgent())
+	if userAgent == "" {
+		return botLikely, "empty_user_agent"
+	}
+
+	if signature, found := matchBotSignature(userAgent); found {
+		if signature.client {
+			return botLikely, "client:" + signature.text
+		}
+		return botKnown, "signature:" + signature.text
+	}
+
+	if r.Header.Get("Accept-Language") == "" {
+		return botLikely, "no_accept_language"
+	}
+
+	return botNone, ""
+}
+
+// serveBotHit serves a bot hit on an active URL that does not use up a
+// click. The hit is recorded under the link's bot_hits.
+func serveBotHit(c *gin.Context, config *Config, urlData *URLData, event ClickEvent, reason string) {
+	log.Printf("Bot hit: %s (%s, bot_response=%s)", event.Alias, reason, urlData.BotResponse)
+
+	// Record the bot hit (non-fatal)
+	event.Counted = false
+	event.Bot = true
+	destination := botDestination(c, urlData, &event)
+	if err := RecordClickEvent(config, event); err != nil {
+		log.Printf("Warning: failed to record bot hit for %s: %v", event.Alias, err)
+	}
+
+	sendBot(c, urlData, destination)
+}
+
+// botDestination resolves where a bot is redirected, or returns "" when
+// the link serves bots its metadata page instead
+func botDestination(c *gin.Context, urlData *URLData, event *ClickEvent) string {
+	if urlData.BotResponse == botResponseMetadata {
+		return ""
+	}
+	return resolveDestination(c, urlData, event)
+}
+
+// sendBot answers a bot with the link's bot_response: the metadata page,
+// or a redirect to destination
+func sendBot(c *gin.Context, urlData *URLData, destination string) {
+	if urlData.BotResponse == botResponseMetadata {
+		c.Header("X-Robots-Tag", "noindex, nofollow")
+		c.HTML(http.StatusOK, "bot.html", gin.H{
+			"alias":     urlData.Alias,
+			"shortURL":  urlData.ShortURL,
+			"createdAt": urlData.CreatedAt,
+		})
+		return
+	}
+
+	redirectTo(c, urlData.Alias, destination)
+}
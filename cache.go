@@ -0,0 +1,127 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// aliasCache is an LRU cache of URLs by alias with a time-to-live, used on
+// the redirect path. Cached URLs are shared and must not be modified; use
+// setClicks to update the click count. A nil cache caches nothing.
+type aliasCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List // Most recently used first
+	entries  map[string]*list.Element
+}
+
+// aliasCacheEntry is a cached URL and when it stops being valid
+type aliasCacheEntry struct {
+	alias     string
+	urlData   *URLData
+	expiresAt time.Time
+}
+
+// newAliasCache creates a cache holding up to capacity URLs, or returns nil
+// when capacity is zero
+func newAliasCache(capacity int, ttl time.Duration) *aliasCache {
+	if capacity <= 0 {
+		return nil
+	}
+
+	return &aliasCache{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element, capacity),
+	}
+}
+
+// get returns a cached URL that has not expired
+func (c *aliasCache) get(alias string) (*URLData, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, found := c.entries[alias]
+	if !found {
+		return nil, false
+	}
+
+	entry := element.Value.(*aliasCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(element)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.urlData, true
+}
+
+// put caches a URL, evicting the least recently used one when full
+func (c *aliasCache) put(urlData *URLData) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &aliasCacheEntry{alias: urlData.Alias, urlData: urlData, expiresAt: time.Now().Add(c.ttl)}
+	if element, found := c.entries[urlData.Alias]; found {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[urlData.Alias] = c.order.PushFront(entry)
+	if c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// setClicks updates the click count of a cached URL without extending its
+// lifetime. Clicks only change through the redirect path, so this keeps
+// expiry checks on cached URLs exact within a single process.
+func (c *aliasCache) setClicks(alias string, clicks int) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, found := c.entries[alias]; found {
+		entry := element.Value.(*aliasCacheEntry)
+		updated := *entry.urlData
+		updated.Clicks = clicks
+		element.Value = &aliasCacheEntry{alias: alias, urlData: &updated, expiresAt: entry.expiresAt}
+	}
+}
+
+// invalidate removes URLs from the cache
+func (c *aliasCache) invalidate(aliases ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, alias := range aliases {
+		if element, found := c.entries[alias]; found {
+			c.removeElement(element)
+		}
+	}
+}
+
+// removeElement drops an entry; the caller must hold mu
+func (c *aliasCache) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*aliasCacheEntry).alias)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAliasCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newAliasCache(2, time.Minute)
+	cache.put(&URLData{Alias: "first"})
+	cache.put(&URLData{Alias: "second"})
+	cache.get("first")
+	cache.put(&URLData{Alias: "third"})
+
+	if _, found := cache.get("second"); found {
+		t.Error("least recently used entry was not evicted")
+	}
+	for _, alias := range []string{"first", "third"} {
+		if _, found := cache.get(alias); !found {
+			t.Errorf("%s was evicted", alias)
+		}
+	}
+}
+
+func TestAliasCacheExpiresEntries(t *testing.T) {
+	cache := newAliasCache(2, 10*time.Millisecond)
+	cache.put(&URLData{Alias: "short"})
+	time.Sleep(20 * time.Millisecond)
+
+	if _, found := cache.get("short"); found {
+		t.Error("expired entry was returned")
+	}
+}
+
+func TestAliasCacheSetClicksCopies(t *testing.T) {
+	cache := newAliasCache(2, time.Minute)
+	original := &URLData{Alias: "shared", Clicks: 1}
+	cache.put(original)
+	cache.setClicks("shared", 2)
+
+	cached, _ := cache.get("shared")
+	if cached.Clicks != 2 {
+		t.Errorf("cached clicks = %d, want 2", cached.Clicks)
+	}
+	if original.Clicks != 1 {
+		t.Error("setClicks modified a URL handed out earlier")
+	}
+}
+
+func BenchmarkAliasCacheGet(b *testing.B) {
+	cache := newAliasCache(1000, time.Hour)
+	for i := 0; i < 1000; i++ {
+		cache.put(&URLData{Alias: "alias-" + strconv.Itoa(i)})
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			cache.get("alias-" + strconv.Itoa(i%1000))
+			i++
+		}
+	})
+}
+
+func BenchmarkAliasCachePutEvicting(b *testing.B) {
+	cache := newAliasCache(1000, time.Hour)
+	aliases := make([]string, 4096)
+	for i := range aliases {
+		aliases[i] = "alias-" + strconv.Itoa(i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.put(&URLData{Alias: aliases[i%len(aliases)]})
+	}
+}
+
+func BenchmarkAliasCacheSetClicks(b *testing.B) {
+	cache := newAliasCache(1000, time.Hour)
+	cache.put(&URLData{Alias: "busy"})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.setClicks("busy", i)
+	}
+}
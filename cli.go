@@ -0,0 +1,673 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const cliUsage = `Usage: url-shortener <command> [options]
+
+Commands:
+  serve                                        Start the HTTP server (default)
+  shorten <url> [--alias A] [--alias-style S] [--max-clicks N] [--bot-response R]
+          [--og-title T] [--og-description D] [--og-image URL] [--rules FILE]
+          [--destination [WEIGHT=]URL]... [--ios-url U] [--android-url U] [--fallback-url U]
+                                               Create a short URL
+  list [--status active|expired] [--search Q] [--health ok|broken|unchecked]
+                                               List short URLs
+  info <alias>                                 Show details for a short URL
+  delete <alias>                               Delete a short URL
+  cleanup                                      Remove URLs that reached their click limit
+  stats                                        Show global statistics
+  apikey create <name> [--alias-prefix P]      Create an API key (local database only)
+  apikey list                                  List API keys (local database only)
+  apikey revoke <id>                           Revoke an API key (local database only)
+  config check [--file PATH]                   Validate configuration without starting
+  openapi [--out FILE]                         Print the OpenAPI spec of the API
+
+Client options:
+  --remote URL    Use a running instance's /api/v1 instead of the local database
+                  (defaults to $URL_SHORTENER_REMOTE)
+  --api-key KEY   API key sent to the remote instance
+                  (defaults to $URL_SHORTENER_API_KEY)
+  --json          Print JSON instead of a table
+`
+
+// cliOptions holds the flags shared by all client commands
+type cliOptions struct {
+	remote string
+	apiKey string
+	json   bool
+}
+
+// runCLI dispatches command-line arguments to a subcommand.
+// Running without arguments starts the server.
+func runCLI(args []string) error {
+	if len(args) == 0 {
+		runServer()
+		return nil
+	}
+
+	command, rest := args[0], args[1:]
+	switch command {
+	case "serve":
+		runServer()
+		return nil
+	case "shorten":
+		return cliShorten(rest)
+	case "list":
+		return cliList(rest)
+	case "info":
+		return cliInfo(rest)
+	case "delete":
+		return cliDelete(rest)
+	case "cleanup":
+		return cliCleanup(rest)
+	case "stats":
+		return cliStats(rest)
+	case "apikey":
+		return cliAPIKey(rest)
+	case "config":
+		return cliConfig(rest)
+	case "openapi":
+		return cliOpenAPI(rest)
+	case "help", "-h", "--help":
+		fmt.Print(cliUsage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, cliUsage)
+	}
+}
+
+// newCommandFlags creates a flag set with the shared client options registered
+func newCommandFlags(name string) (*flag.FlagSet, *cliOptions) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	opts := &cliOptions{}
+	fs.StringVar(&opts.remote, "remote", os.Getenv("URL_SHORTENER_REMOTE"), "base URL of a running instance")
+	fs.StringVar(&opts.apiKey, "api-key", os.Getenv("URL_SHORTENER_API_KEY"), "API key for the remote instance")
+	fs.BoolVar(&opts.json, "json", false, "print JSON output")
+	return fs, opts
+}
+
+// parseCommandFlags parses flags that may appear before or after positional
+// arguments and returns the positional arguments
+func parseCommandFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// expectArgs checks the number of positional arguments
+func expectArgs(command string, args []string, count int, usage string) error {
+	if len(args) != count {
+		return fmt.Errorf("usage: url-shortener %s %s", command, usage)
+	}
+	return nil
+}
+
+func cliShorten(args []string) error {
+	fs, opts := newCommandFlags("shorten")
+	alias := fs.String("alias", "", "custom alias")
+	aliasStyle := fs.String("alias-style", "", "style of a generated alias: random, words, or hashid")
+	maxClicks := fs.Int("max-clicks", 0, "maximum number of clicks")
+	botResponse := fs.String("bot-response", "", "how bots are served: redirect or metadata")
+	ogTitle := fs.String("og-title", "", "title shown in social previews")
+	ogDescription := fs.String("og-description", "", "description shown in social previews")
+	ogImage := fs.String("og-image", "", "image URL shown in social previews")
+	rulesFile := fs.String("rules", "", "JSON file with an array of redirect rules")
+	iosURL := fs.String("ios-url", "", "app deep link opened on iOS")
+	androidURL := fs.String("android-url", "", "app deep link or intent URL opened on Android")
+	fallbackURL := fs.String("fallback-url", "", "web page used when the app does not open")
+	var destinations []Destination
+	fs.Func("destination", "A/B destination as URL or WEIGHT=URL (repeatable)", func(value string) error {
+		destinations = append(destinations, parseDestinationFlag(value))
+		return nil
+	})
+	positional, err := parseCommandFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs("shorten", positional, 1, "<url> [--alias A] [--max-clicks N] [--bot-response R]"); err != nil {
+		return err
+	}
+
+	backend, closeBackend, err := newCLIBackend(opts)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	req := ShortenRequest{
+		URL:           positional[0],
+		Alias:         *alias,
+		AliasStyle:    *aliasStyle,
+		BotResponse:   *botResponse,
+		OGTitle:       *ogTitle,
+		OGDescription: *ogDescription,
+		OGImage:       *ogImage,
+	}
+	req.Destinations = destinations
+	if *iosURL != "" || *androidURL != "" || *fallbackURL != "" {
+		req.DeepLink = &DeepLink{IOSURL: *iosURL, AndroidURL: *androidURL, FallbackURL: *fallbackURL}
+	}
+	if *rulesFile != "" {
+		if req.Rules, err = readRulesFile(*rulesFile); err != nil {
+			return err
+		}
+	}
+	if *maxClicks > 0 {
+		req.MaxClicks = maxClicks
+	}
+
+	result, err := backend.Shorten(req)
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return printJSON(result)
+	}
+
+	return printTable([]string{"ALIAS", "SHORT URL", "ORIGINAL URL", "MAX CLICKS"}, [][]string{
+		{result.Alias, result.ShortURL, displayURL(result.OriginalURL), strconv.Itoa(result.MaxClicks)},
+	})
+}
+
+// parseDestinationFlag parses an A/B destination given as URL or WEIGHT=URL
+func parseDestinationFlag(value string) Destination {
+	if weight, url, found := strings.Cut(value, "="); found {
+		if n, err := strconv.Atoi(weight); err == nil {
+			return Destination{URL: url, Weight: n}
+		}
+	}
+	return Destination{URL: value}
+}
+
+// formatDeepLink summarizes a link's app deep links
+func formatDeepLink(deepLink *DeepLink) string {
+	if deepLink == nil {
+		return "none"
+	}
+
+	var parts []string
+	if deepLink.IOSURL != "" {
+		parts = append(parts, "ios="+deepLink.IOSURL)
+	}
+	if deepLink.AndroidURL != "" {
+		parts = append(parts, "android="+deepLink.AndroidURL)
+	}
+	if deepLink.FallbackURL != "" {
+		parts = append(parts, "fallback="+deepLink.FallbackURL)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// readRulesFile reads redirect rules from a JSON file
+func readRulesFile(path string) ([]RedirectRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []RedirectRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %v", path, err)
+	}
+	return rules, nil
+}
+
+// formatRuleHits summarizes a link's rules and the clicks each one received
+func formatRuleHits(info *URLInfoResponse) string {
+	if len(info.Rules) == 0 {
+		return "none"
+	}
+
+	parts := make([]string, 0, len(info.Rules)+1)
+	for i, rule := range info.Rules {
+		name := ruleName(rule, i)
+		parts = append(parts, fmt.Sprintf("%s=%d", name, info.RuleHits[name]))
+	}
+	parts = append(parts, fmt.Sprintf("%s=%d", defaultRuleName, info.RuleHits[defaultRuleName]))
+	return strings.Join(parts, ", ")
+}
+
+// formatHealth describes the last destination check of a link
+func formatHealth(info *URLInfoResponse) string {
+	if info.HealthCheckedAt == nil {
+		return info.Health
+	}
+
+	result := info.HealthError
+	if result == "" {
+		result = fmt.Sprintf("HTTP %d", info.HealthStatus)
+	}
+	return fmt.Sprintf("%s (%s, checked %s)", info.Health, result, info.HealthCheckedAt.Format(time.RFC3339))
+}
+
+func cliList(args []string) error {
+	fs, opts := newCommandFlags("list")
+	status := fs.String("status", "", "filter by status: active or expired")
+	search := fs.String("search", "", "search aliases and destinations")
+	health := fs.String("health", "", "filter by destination health: ok, broken or unchecked")
+	positional, err := parseCommandFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs("list", positional, 0, "[--status active|expired] [--search Q] [--health ok|broken|unchecked]"); err != nil {
+		return err
+	}
+
+	backend, closeBackend, err := newCLIBackend(opts)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	urls, err := backend.List(*status, *search, *health)
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		if urls == nil {
+			urls = []URLData{}
+		}
+		return printJSON(urls)
+	}
+
+	rows := make([][]string, 0, len(urls))
+	for _, url := range urls {
+		rows = append(rows, []string{
+			url.Alias,
+			fmt.Sprintf("%d/%d", url.Clicks, url.MaxClicks),
+			urlStatus(url.Clicks, url.MaxClicks),
+			url.Health,
+			displayURL(url.URL),
+		})
+	}
+
+	return printTable([]string{"ALIAS", "CLICKS", "STATUS", "HEALTH", "ORIGINAL URL"}, rows)
+}
+
+func cliInfo(args []string) error {
+	fs, opts := newCommandFlags("info")
+	positional, err := parseCommandFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs("info", positional, 1, "<alias>"); err != nil {
+		return err
+	}
+
+	backend, closeBackend, err := newCLIBackend(opts)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	info, err := backend.Info(positional[0])
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return printJSON(info)
+	}
+
+	rows := [][]string{
+		{"Alias", info.Alias},
+		{"Short URL", info.ShortURL},
+		{"Original URL", displayURL(info.OriginalURL)},
+		{"Final URL", displayURL(info.FinalURL)},
+		{"Title", info.Title},
+		{"Description", info.Description},
+		{"Clicks", fmt.Sprintf("%d/%d", info.Clicks, info.MaxClicks)},
+		{"Remaining", strconv.Itoa(info.RemainingClicks)},
+		{"Hits", strconv.Itoa(info.Hits)},
+		{"Bot hits", strconv.Itoa(info.BotHits)},
+		{"Bot response", info.BotResponse},
+		{"OG title", info.OGTitle},
+		{"OG description", info.OGDescription},
+		{"OG image", info.OGImage},
+		{"Rules", formatRuleHits(info)},
+		{"Deep link", formatDeepLink(info.DeepLink)},
+		{"Status", urlStatus(info.Clicks, info.MaxClicks)},
+		{"Health", formatHealth(info)},
+		{"Created", info.CreatedAt.Format(time.RFC3339)},
+	}
+	for _, destination := range info.Destinations {
+		rows = append(rows, []string{
+			fmt.Sprintf("Variant %d", destination.Variant),
+			fmt.Sprintf("%s (weight %d = %.0f%%, %d clicks = %.1f%%)", destination.URL, destination.Weight,
+				destination.WeightShare, destination.Clicks, destination.ClickShare),
+		})
+	}
+
+	return printTable([]string{"FIELD", "VALUE"}, rows)
+}
+
+func cliDelete(args []string) error {
+	fs, opts := newCommandFlags("delete")
+	positional, err := parseCommandFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs("delete", positional, 1, "<alias>"); err != nil {
+		return err
+	}
+
+	backend, closeBackend, err := newCLIBackend(opts)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	if err := backend.Delete(positional[0]); err != nil {
+		return err
+	}
+
+	if opts.json {
+		return printJSON(map[string]interface{}{"deleted": positional[0]})
+	}
+
+	fmt.Printf("Deleted %s\n", positional[0])
+	return nil
+}
+
+func cliCleanup(args []string) error {
+	fs, opts := newCommandFlags("cleanup")
+	positional, err := parseCommandFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs("cleanup", positional, 0, ""); err != nil {
+		return err
+	}
+
+	backend, closeBackend, err := newCLIBackend(opts)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	deletedCount, err := backend.Cleanup()
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return printJSON(map[string]interface{}{"deleted_count": deletedCount})
+	}
+
+	fmt.Printf("Removed %d expired URLs\n", deletedCount)
+	return nil
+}
+
+func cliStats(args []string) error {
+	fs, opts := newCommandFlags("stats")
+	positional, err := parseCommandFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs("stats", positional, 0, ""); err != nil {
+		return err
+	}
+
+	backend, closeBackend, err := newCLIBackend(opts)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	stats, err := backend.Stats()
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return printJSON(stats)
+	}
+
+	return printTable([]string{"TOTAL URLS", "ACTIVE", "EXPIRED", "TOTAL CLICKS", "TOTAL HITS", "BOT HITS"}, [][]string{{
+		strconv.Itoa(stats.TotalURLs),
+		strconv.Itoa(stats.ActiveURLs),
+		strconv.Itoa(stats.ExpiredURLs),
+		strconv.Itoa(stats.TotalClicks),
+		strconv.Itoa(stats.TotalHits),
+		strconv.Itoa(stats.TotalBotHits),
+	}})
+}
+
+// cliAPIKey manages API keys. Keys are only ever managed against the local
+// database so that creating a key never requires an existing key.
+func cliAPIKey(args []string) error {
+	const usage = "usage: url-shortener apikey create <name> [--alias-prefix P] | list | revoke <id>"
+	if len(args) == 0 {
+		return fmt.Errorf(usage)
+	}
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		fmt.Println(usage)
+		return nil
+	}
+
+	fs := flag.NewFlagSet("apikey "+args[0], flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print JSON output")
+	var aliasPrefix *string
+	if args[0] == "create" {
+		aliasPrefix = fs.String("alias-prefix", "", "aliases created with the key must start with this prefix")
+	}
+	positional, err := parseCommandFlags(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		if err := expectArgs("apikey create", positional, 1, "<name> [--alias-prefix P]"); err != nil {
+			return err
+		}
+
+		config := LoadConfig()
+		defer config.CloseDB()
+
+		key, apiKey, err := createAPIKey(config, positional[0], *aliasPrefix)
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			return printJSON(map[string]interface{}{"key": key, "api_key": apiKey})
+		}
+
+		fmt.Printf("Created API key %q (id %d)\n\n    %s\n\nStore it now; it cannot be shown again.\n", apiKey.Name, apiKey.ID, key)
+		return nil
+
+	case "list":
+		if err := expectArgs("apikey list", positional, 0, ""); err != nil {
+			return err
+		}
+
+		config := LoadConfig()
+		defer config.CloseDB()
+
+		keys, err := GetAllAPIKeys(config)
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			if keys == nil {
+				keys = []APIKey{}
+			}
+			return printJSON(keys)
+		}
+
+		rows := make([][]string, 0, len(keys))
+		for _, apiKey := range keys {
+			lastUsed := "never"
+			if apiKey.LastUsedAt != nil {
+				lastUsed = apiKey.LastUsedAt.Format(time.RFC3339)
+			}
+			rows = append(rows, []string{
+				strconv.FormatInt(apiKey.ID, 10),
+				apiKey.Name,
+				apiKey.Prefix + "…",
+				apiKey.AliasPrefix,
+				apiKey.CreatedAt.Format(time.RFC3339),
+				lastUsed,
+			})
+		}
+
+		return printTable([]string{"ID", "NAME", "KEY", "ALIAS PREFIX", "CREATED", "LAST USED"}, rows)
+
+	case "revoke":
+		if err := expectArgs("apikey revoke", positional, 1, "<id>"); err != nil {
+			return err
+		}
+
+		id, err := strconv.ParseInt(positional[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid API key id %q", positional[0])
+		}
+
+		config := LoadConfig()
+		defer config.CloseDB()
+
+		found, err := DeleteAPIKey(config, id)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("no API key with id %d", id)
+		}
+
+		if *asJSON {
+			return printJSON(map[string]interface{}{"revoked": id})
+		}
+
+		fmt.Printf("Revoked API key %d\n", id)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown apikey command %q", args[0])
+	}
+}
+
+// cliConfig validates configuration the same way the server does at startup
+func cliConfig(args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return fmt.Errorf("usage: url-shortener config check [--file PATH]")
+	}
+
+	fs := flag.NewFlagSet("config check", flag.ContinueOnError)
+	file := fs.String("file", "", "config file to check (defaults to $CONFIG_FILE or ./config.{yaml,yml,toml})")
+	positional, err := parseCommandFlags(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if err := expectArgs("config check", positional, 0, "[--file PATH]"); err != nil {
+		return err
+	}
+
+	if *file != "" {
+		os.Setenv("CONFIG_FILE", *file)
+	}
+
+	config, err := ReadConfig()
+	if err != nil {
+		fmt.Println("Configuration is invalid:")
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Printf("  ✗ %s\n", line)
+		}
+		return fmt.Errorf("configuration check failed")
+	}
+
+	fmt.Printf("✓ Configuration is valid (config file: %s)\n", config.ConfigFile())
+	return nil
+}
+
+// urlStatus describes whether a URL can still be used
+func urlStatus(clicks, maxClicks int) string {
+	if clicks >= maxClicks {
+		return "expired"
+	}
+	return "active"
+}
+
+// printJSON writes a value to stdout as indented JSON
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printTable writes rows to stdout as aligned columns
+func printTable(headers []string, rows [][]string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	writeRow := func(cells []string) {
+		for i, cell := range cells {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, cell)
+		}
+		fmt.Fprintln(w)
+	}
+
+	writeRow(headers)
+	for _, row := range rows {
+		writeRow(row)
+	}
+
+	return w.Flush()
+}
+
+// cliOpenAPI writes the OpenAPI spec served at /api/openapi.json, for
+// generating clients without a running server
+func cliOpenAPI(args []string) error {
+	fs := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	out := fs.String("out", "", "file to write the spec to (defaults to standard output)")
+	positional, err := parseCommandFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs("openapi", positional, 0, "[--out FILE]"); err != nil {
+		return err
+	}
+
+	config, err := ReadConfig()
+	if err != nil {
+		return err
+	}
+
+	spec, err := json.MarshalIndent(buildOpenAPISpec(config), "", "  ")
+	if err != nil {
+		return err
+	}
+	spec = append(spec, '\n')
+
+	if *out == "" {
+		_, err = os.Stdout.Write(spec)
+		return err
+	}
+	return os.WriteFile(*out, spec, 0644)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cliBackend is the set of operations available to command-line clients,
+// implemented against either the local database or a remote instance
+type cliBackend interface {
+	Shorten(req ShortenRequest) (*ShortenResponse, error)
+	List(status, search, health string) ([]URLData, error)
+	Info(alias string) (*URLInfoResponse, error)
+	Delete(alias string) error
+	Cleanup() (int, error)
+	Stats() (*StatsResponse, error)
+}
+
+// newCLIBackend returns a remote backend when --remote is set, otherwise a
+// backend operating directly on the configured database. The returned
+// function releases any resources held by the backend.
+func newCLIBackend(opts *cliOptions) (cliBackend, func(), error) {
+	if opts.remote != "" {
+		baseURL, err := url.Parse(opts.remote)
+		if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+			return nil, nil, fmt.Errorf("invalid remote URL %q", opts.remote)
+		}
+
+		return &remoteBackend{
+			baseURL: strings.TrimRight(opts.remote, "/"),
+			apiKey:  opts.apiKey,
+			client:  &http.Client{Timeout: 15 * time.Second},
+		}, func() {}, nil
+	}
+
+	config := LoadConfig()
+	reserveServerRoutes(config)
+	return &localBackend{config: config}, func() { config.CloseDB() }, nil
+}
+
+// localBackend operates directly on the database through the db.go functions
+type localBackend struct {
+	config *Config
+}
+
+func (b *localBackend) Shorten(req ShortenRequest) (*ShortenResponse, error) {
+	response, _, errResp := createShortURL(b.config, req, nil)
+	if errResp != nil {
+		return nil, errorResponseError(errResp)
+	}
+	return response, nil
+}
+
+func (b *localBackend) List(status, search, health string) ([]URLData, error) {
+	var urls []URLData
+	for page := 1; ; page++ {
+		list, err := queryURLs(b.config, page, 100, status, search, health)
+		if err != nil {
+			return nil, err
+		}
+
+		urls = append(urls, list.URLs...)
+		if !list.HasNext {
+			return urls, nil
+		}
+	}
+}
+
+func (b *localBackend) Info(alias string) (*URLInfoResponse, error) {
+	urlData, err := GetURLByAlias(b.config, alias)
+	if err != nil {
+		return nil, err
+	}
+	if urlData == nil {
+		return nil, fmt.Errorf("no URL found for alias: %s", alias)
+	}
+
+	info := buildURLInfo(urlData)
+	if len(urlData.Rules) > 0 {
+		if info.RuleHits, err = GetRuleHits(b.config, alias); err != nil {
+			return nil, err
+		}
+	}
+	return &info, nil
+}
+
+func (b *localBackend) Delete(alias string) error {
+	found, err := DeleteURL(b.config, alias)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("no URL found for alias: %s", alias)
+	}
+	return nil
+}
+
+func (b *localBackend) Cleanup() (int, error) {
+	return CleanupExpiredURLs(b.config)
+}
+
+func (b *localBackend) Stats() (*StatsResponse, error) {
+	return GetStats(b.config)
+}
+
+// remoteBackend talks to a running instance's /api/v1 endpoints
+type remoteBackend struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func (b *remoteBackend) Shorten(req ShortenRequest) (*ShortenResponse, error) {
+	var response ShortenResponse
+	if err := b.do(http.MethodPost, apiV1Prefix+"/shorten", req, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (b *remoteBackend) List(status, search, health string) ([]URLData, error) {
+	var urls []URLData
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", "100")
+		if status != "" {
+			query.Set("status", status)
+		}
+		if search != "" {
+			query.Set("q", search)
+		}
+		if health != "" {
+			query.Set("health", health)
+		}
+
+		var list ListURLsResponse
+		if err := b.do(http.MethodGet, apiV1Prefix+"/urls?"+query.Encode(), nil, &list); err != nil {
+			return nil, err
+		}
+
+		urls = append(urls, list.URLs...)
+		if !list.HasNext {
+			return urls, nil
+		}
+	}
+}
+
+func (b *remoteBackend) Info(alias string) (*URLInfoResponse, error) {
+	var info URLInfoResponse
+	if err := b.do(http.MethodGet, apiV1Prefix+"/urls/"+url.PathEscape(alias), nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (b *remoteBackend) Delete(alias string) error {
+	return b.do(http.MethodDelete, apiV1Prefix+"/urls/"+url.PathEscape(alias), nil, nil)
+}
+
+func (b *remoteBackend) Cleanup() (int, error) {
+	var response CleanupResponse
+	if err := b.do(http.MethodPost, apiV1Prefix+"/cleanup", nil, &response); err != nil {
+		return 0, err
+	}
+	return response.DeletedCount, nil
+}
+
+func (b *remoteBackend) Stats() (*StatsResponse, error) {
+	var stats StatsResponse
+	if err := b.do(http.MethodGet, apiV1Prefix+"/stats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// do sends a JSON request and decodes the JSON response into out.
+// Error responses are decoded into an ErrorResponse and returned as an error.
+func (b *remoteBackend) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, b.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.apiKey != "" {
+		req.Header.Set("X-API-Key", b.apiKey)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %v", b.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var errResp ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+			return fmt.Errorf("%s %s failed with status %d", method, path, resp.StatusCode)
+		}
+		return errorResponseError(&errResp)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	return nil
+}
+
+// errorResponseError converts an API error response into a Go error
+func errorResponseError(errResp *ErrorResponse) error {
+	if errResp.Message != "" {
+		return fmt.Errorf("%s: %s", errResp.Error, errResp.Message)
+	}
+	return fmt.Errorf("%s", errResp.Error)
+}
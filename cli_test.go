@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// discardOutput silences the usage and results the CLI prints
+func discardOutput(t *testing.T) {
+	t.Helper()
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = devNull, devNull
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		devNull.Close()
+	})
+}
+
+func TestCLIAPIKeyFlags(t *testing.T) {
+	discardOutput(t)
+
+	tests := []struct {
+		args    []string
+		wantErr string // "" for success, "help" for flag.ErrHelp
+	}{
+		{[]string{"apikey", "-h"}, ""},
+		{[]string{"apikey", "--help"}, ""},
+		{[]string{"apikey", "create", "-h"}, "help"},
+		{[]string{"apikey", "list", "-h"}, "help"},
+		{[]string{"apikey", "list", "--alias-prefix", "team-"}, "flag provided but not defined"},
+		{[]string{"apikey", "revoke", "1", "--alias-prefix", "team-"}, "flag provided but not defined"},
+		{[]string{"apikey", "rotate"}, "unknown apikey command"},
+		{[]string{"apikey"}, "usage"},
+	}
+
+	for _, tt := range tests {
+		err := runCLI(tt.args)
+		switch {
+		case tt.wantErr == "":
+			if err != nil {
+				t.Errorf("%v: %v, want success", tt.args, err)
+			}
+		case tt.wantErr == "help":
+			if !errors.Is(err, flag.ErrHelp) {
+				t.Errorf("%v: %v, want %v", tt.args, err, flag.ErrHelp)
+			}
+		case err == nil || !strings.Contains(err.Error(), tt.wantErr):
+			t.Errorf("%v: %v, want an error containing %q", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCLIAPIKeyRevokeJSON(t *testing.T) {
+	discardLogs(t)
+	config := newTestConfig(t)
+	apiKey, err := SaveAPIKey(config, "ci", "abcd1234", "hash", "")
+	if err != nil {
+		t.Fatalf("SaveAPIKey: %v", err)
+	}
+	config.CloseDB()
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	id := strconv.FormatInt(apiKey.ID, 10)
+	err = runCLI([]string{"apikey", "revoke", "--json", id})
+	os.Stdout = stdout
+	writer.Close()
+	if err != nil {
+		t.Fatalf("apikey revoke: %v", err)
+	}
+
+	output, _ := io.ReadAll(reader)
+	if want := `"revoked": ` + id; !strings.Contains(string(output), want) {
+		t.Errorf("output = %q, want JSON with %s", output, want)
+	}
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Click write modes
+const (
+	clickWritesStrict   = "strict"   // Every counted click is written before redirecting
+	clickWritesBuffered = "buffered" // Clicks are counted in memory and flushed in batches
+)
+
+// maxPendingClickEvents caps the hits queued while flushes keep failing.
+// Beyond it the oldest hits are dropped; click counts are kept either way.
+const maxPendingClickEvents = 100_000
+
+// clickBuffer enforces click limits against in-memory counters and writes
+// the increments to the urls table in batches, together with the hits
+// recorded since the previous batch. The database stays the source of
+// truth between restarts: counters are loaded from it on first use and
+// dropped again once they have been idle for a flush interval. A nil
+// buffer means clicks are written directly.
+type clickBuffer struct {
+	config *Config
+
+	// mu is held for reading while counting clicks and for writing while
+	// counters are added, removed, or drained by a flush
+	mu       sync.RWMutex
+	counters map[string]*clickCounter
+
+	// generation changes whenever counters are forgotten, so loads that
+	// raced with a forget discard what they read
+	generation uint64
+
+	// eventsMu guards events, the hits not yet written, visits, when each
+	// visitor with a counted hit among them was counted, and dropped, the
+	// hits discarded at maxPendingClickEvents since the last warning
+	eventsMu sync.Mutex
+	events   []ClickEvent
+	visits   map[visitKey]time.Time
+	dropped  int
+
+	// flushMu serializes flushes so batches reach the database in order
+	flushMu sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// visitKey identifies a visitor of an alias
+type visitKey struct {
+	alias       string
+	visitorHash string
+}
+
+// clickBatch is what one transaction of the buffer writes
+type clickBatch struct {
+	clicks map[string]int64 // Pending clicks per URL
+	events []ClickEvent     // Hits, oldest first
+}
+
+// clickCounter tracks the clicks of one URL
+type clickCounter struct {
+	clicks    atomic.Int64 // Total clicks, including ones not yet written
+	pending   atomic.Int64 // Clicks not yet written to the database
+	maxClicks int64
+}
+
+// startClickBuffer switches the server to buffered click counting when
+// configured, flushing every interval until stopClickBuffer is called
+func startClickBuffer(config *Config) {
+	if config.ClickWrites != clickWritesBuffered {
+		return
+	}
+
+	buffer := &clickBuffer{
+		config:   config,
+		counters: make(map[string]*clickCounter),
+		visits:   make(map[visitKey]time.Time),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	config.clickBuffer = buffer
+
+	go func() {
+		defer close(buffer.done)
+
+		ticker := time.NewTicker(config.ClickFlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				buffer.flush()
+			case <-buffer.stop:
+				return
+			}
+		}
+	}()
+
+	log.Printf("🧮 Buffered click counting enabled, flushing every %v", config.ClickFlushInterval)
+}
+
+// stopClickBuffer stops the periodic flush and writes all pending clicks
+func stopClickBuffer(config *Config) {
+	buffer := config.clickBuffer
+	if buffer == nil {
+		return
+	}
+
+	close(buffer.stop)
+	<-buffer.done
+
+	urls, hits := buffer.flush()
+	log.Printf("🧮 Click buffer flushed on shutdown (%d URLs, %d hits)", urls, hits)
+}
+
+// increment counts a click if the URL is under its limit and returns the
+// new count. Errors match those of IncrementURLClicks.
+func (b *clickBuffer) increment(alias string) (int, error) {
+	for {
+		b.mu.RLock()
+		counter, found := b.counters[alias]
+		if found {
+			clicks, ok := counter.tryIncrement()
+			b.mu.RUnlock()
+			if !ok {
+				return int(clicks), fmt.Errorf("URL has already reached maximum clicks (%d/%d)", clicks, counter.maxClicks)
+			}
+			return int(clicks), nil
+		}
+		b.mu.RUnlock()
+
+		if err := b.load(alias); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// tryIncrement adds a click unless the limit has been reached. It returns
+// the resulting count and whether the click was counted.
+func (c *clickCounter) tryIncrement() (int64, bool) {
+	for {
+		clicks := c.clicks.Load()
+		if clicks >= c.maxClicks {
+			return clicks, false
+		}
+		if c.clicks.CompareAndSwap(clicks, clicks+1) {
+			c.pending.Add(1)
+			return clicks + 1, true
+		}
+	}
+}
+
+// load creates the counter of a URL from its stored click count
+func (b *clickBuffer) load(alias string) error {
+	db := b.config.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not available")
+	}
+
+	b.mu.RLock()
+	generation := b.generation
+	b.mu.RUnlock()
+
+	var clicks, maxClicks int64
+	err := db.QueryRow("SELECT clicks, max_clicks FROM urls WHERE alias = ?", alias).Scan(&clicks, &maxClicks)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("URL with alias %s not found", alias)
+		}
+		return fmt.Errorf("failed to get current clicks: %v", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Another request may have loaded it meanwhile, or a forget may have
+	// written clicks after the read; the caller retries in that case
+	if _, found := b.counters[alias]; !found && b.generation == generation {
+		counter := &clickCounter{maxClicks: maxClicks}
+		counter.clicks.Store(clicks)
+		b.counters[alias] = counter
+	}
+	return nil
+}
+
+// record queues a hit for the next flush
+func (b *clickBuffer) record(event ClickEvent) {
+	event.ClickedAt = time.Now()
+
+	b.eventsMu.Lock()
+	defer b.eventsMu.Unlock()
+
+	b.events = append(b.events, event)
+	if event.Counted {
+		b.visits[visitKey{event.Alias, event.VisitorHash}] = event.ClickedAt
+	}
+	b.trimEvents()
+}
+
+// trimEvents drops the oldest queued hits beyond maxPendingClickEvents.
+// eventsMu must be held.
+func (b *clickBuffer) trimEvents() {
+	excess := len(b.events) - maxPendingClickEvents
+	if excess <= 0 {
+		return
+	}
+
+	b.forgetVisits(b.events[:excess])
+	b.events = b.events[excess:]
+	b.dropped += excess
+}
+
+// hasPendingVisit reports whether a visitor has a counted hit on an alias
+// within the window that is not written yet
+func (b *clickBuffer) hasPendingVisit(alias, visitorHash string, window time.Duration) bool {
+	if b == nil {
+		return false
+	}
+
+	b.eventsMu.Lock()
+	defer b.eventsMu.Unlock()
+
+	countedAt, found := b.visits[visitKey{alias, visitorHash}]
+	return found && time.Since(countedAt) < window
+}
+
+// written forgets the pending visits of hits that reached the database,
+// unless the visitor was counted again since
+func (b *clickBuffer) written(events []ClickEvent) {
+	b.eventsMu.Lock()
+	defer b.eventsMu.Unlock()
+
+	b.forgetVisits(events)
+}
+
+// forgetVisits removes the pending visits of hits that left the queue.
+// eventsMu must be held.
+func (b *clickBuffer) forgetVisits(events []ClickEvent) {
+	for _, event := range events {
+		key := visitKey{event.Alias, event.VisitorHash}
+		if event.Counted && b.visits[key].Equal(event.ClickedAt) {
+			delete(b.visits, key)
+		}
+	}
+}
+
+// clicks returns the live click count of a URL, if it has a counter
+func (b *clickBuffer) clicks(alias string) (int, bool) {
+	if b == nil {
+		return 0, false
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	counter, found := b.counters[alias]
+	if !found {
+		return 0, false
+	}
+	return int(counter.clicks.Load()), true
+}
+
+// overlay replaces the stored click count of a freshly loaded URL with its
+// live count
+func (b *clickBuffer) overlay(urlData *URLData) {
+	if clicks, found := b.clicks(urlData.Alias); found {
+		urlData.Clicks = clicks
+	}
+}
+
+// flush writes pending clicks and hits to the database in one transaction
+// and drops counters that saw no clicks since the previous flush. It
+// returns the number of URLs with clicks and of hits written.
+func (b *clickBuffer) flush() (int, int) {
+	if b == nil {
+		return 0, 0
+	}
+
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	// Drain pending clicks; nothing can increment while the lock is held
+	batch := clickBatch{clicks: make(map[string]int64)}
+	b.mu.Lock()
+	for alias, counter := range b.counters {
+		if pending := counter.pending.Swap(0); pending > 0 {
+			batch.clicks[alias] = pending
+		} else {
+			delete(b.counters, alias)
+		}
+	}
+	b.mu.Unlock()
+
+	b.eventsMu.Lock()
+	batch.events, b.events = b.events, nil
+	dropped := b.dropped
+	b.dropped = 0
+	b.eventsMu.Unlock()
+
+	if dropped > 0 {
+		log.Printf("Warning: dropped the %d oldest buffered hits, more than %d were waiting to be written", dropped, maxPendingClickEvents)
+	}
+
+	if len(batch.clicks) == 0 && len(batch.events) == 0 {
+		return 0, 0
+	}
+
+	if err := b.write(batch); err != nil {
+		log.Printf("Warning: failed to flush %d buffered click counts and %d hits, retrying next interval: %v",
+			len(batch.clicks), len(batch.events), err)
+		b.restore(batch)
+		return 0, 0
+	}
+	b.written(batch.events)
+
+	return len(batch.clicks), len(batch.events)
+}
+
+// write adds a batch of clicks to the urls table and stores its hits
+func (b *clickBuffer) write(batch clickBatch) error {
+	db := b.config.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not available")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("UPDATE urls SET clicks = MIN(clicks + ?, max_clicks) WHERE alias = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare click update: %v", err)
+	}
+	defer stmt.Close()
+
+	for alias, pending := range batch.clicks {
+		if _, err := stmt.Exec(pending, alias); err != nil {
+			return fmt.Errorf("failed to update clicks for %s: %v", alias, err)
+		}
+	}
+
+	if err := writeClickEvents(tx, batch.events); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// restore puts back clicks and hits from a batch that could not be written
+func (b *clickBuffer) restore(batch clickBatch) {
+	b.mu.RLock()
+	for alias, pending := range batch.clicks {
+		if counter, found := b.counters[alias]; found {
+			counter.pending.Add(pending)
+		} else {
+			log.Printf("Warning: dropped %d buffered clicks for %s", pending, alias)
+		}
+	}
+	b.mu.RUnlock()
+
+	b.eventsMu.Lock()
+	b.events = append(batch.events, b.events...)
+	b.trimEvents()
+	b.eventsMu.Unlock()
+}
+
+// forget writes the pending clicks and hits of URLs and drops their
+// counters, so the next click reloads them. Call it after changing or
+// deleting a URL.
+func (b *clickBuffer) forget(aliases ...string) {
+	if b == nil || len(aliases) == 0 {
+		return
+	}
+
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	// Hold the lock while writing so no request reloads a stale count
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.generation++
+
+	batch := clickBatch{clicks: make(map[string]int64)}
+	forgotten := make(map[string]bool)
+	for _, alias := range aliases {
+		forgotten[alias] = true
+		if counter, found := b.counters[alias]; found {
+			if pending := counter.pending.Load(); pending > 0 {
+				batch.clicks[alias] = pending
+			}
+			delete(b.counters, alias)
+		}
+	}
+
+	b.eventsMu.Lock()
+	kept := b.events[:0]
+	for _, event := range b.events {
+		if forgotten[event.Alias] {
+			batch.events = append(batch.events, event)
+		} else {
+			kept = append(kept, event)
+		}
+	}
+	b.events = kept
+	b.eventsMu.Unlock()
+
+	if len(batch.clicks) == 0 && len(batch.events) == 0 {
+		return
+	}
+
+	if err := b.write(batch); err != nil {
+		log.Printf("Warning: failed to write buffered clicks for %v: %v", aliases, err)
+		return
+	}
+	b.written(batch.events)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetAllURLsIncludesBufferedClicks(t *testing.T) {
+	discardLogs(t)
+	t.Setenv("CLICK_WRITES", clickWritesBuffered)
+	t.Setenv("CLICK_FLUSH_INTERVAL", "1h")
+	config := newTestConfig(t)
+	startClickBuffer(config)
+	t.Cleanup(func() { stopClickBuffer(config) })
+
+	saveTestURL(t, config, "listed", "https://example.com/")
+	for i := 0; i < 3; i++ {
+		if _, err := IncrementURLClicks(config, "listed"); err != nil {
+			t.Fatalf("IncrementURLClicks: %v", err)
+		}
+	}
+
+	urls, err := GetAllURLs(config)
+	if err != nil {
+		t.Fatalf("GetAllURLs: %v", err)
+	}
+	if len(urls) != 1 || urls[0].Clicks != 3 {
+		t.Fatalf("GetAllURLs = %+v, want one URL with 3 clicks", urls)
+	}
+}
+
+func TestClickBufferCapsPendingEvents(t *testing.T) {
+	discardLogs(t)
+	t.Setenv("CLICK_WRITES", clickWritesBuffered)
+	t.Setenv("CLICK_FLUSH_INTERVAL", "1h")
+	config := newTestConfig(t)
+	startClickBuffer(config)
+	buffer := config.clickBuffer
+	t.Cleanup(func() { stopClickBuffer(config) })
+
+	// Keep flushes failing while hits come in
+	db := config.db
+	config.db = nil
+	t.Cleanup(func() { config.db = db })
+
+	const extra = 10
+	for i := 0; i < maxPendingClickEvents+extra; i++ {
+		buffer.record(ClickEvent{Alias: "busy", VisitorHash: fmt.Sprint(i), Counted: true})
+	}
+	buffer.flush()
+	buffer.record(ClickEvent{Alias: "busy", VisitorHash: "last", Counted: true})
+	buffer.flush()
+
+	buffer.eventsMu.Lock()
+	defer buffer.eventsMu.Unlock()
+
+	if len(buffer.events) != maxPendingClickEvents {
+		t.Fatalf("pending hits = %d, want %d", len(buffer.events), maxPendingClickEvents)
+	}
+	if first := buffer.events[0].VisitorHash; first != fmt.Sprint(extra+1) {
+		t.Errorf("oldest pending hit = %s, want %d", first, extra+1)
+	}
+	if last := buffer.events[len(buffer.events)-1].VisitorHash; last != "last" {
+		t.Errorf("newest pending hit = %s, want last", last)
+	}
+	if len(buffer.visits) != maxPendingClickEvents {
+		t.Errorf("pending visits = %d, want %d", len(buffer.visits), maxPendingClickEvents)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// visitorHash identifies a visitor of an alias without storing their IP.
+// The salt keeps hashes from being reversed by enumerating IP addresses.
+func visitorHash(config *Config, alias, clientIP, userAgent string) string {
+	sum := sha256.Sum256([]byte(config.VisitorHashSalt + "\x00" + alias + "\x00" + clientIP + "\x00" + userAgent))
+	return hex.EncodeToString(sum[:])
+}
+
+// shouldCountClick applies the click policy to a hit. It returns false and
+// a reason when the hit should not count toward max_clicks.
+func shouldCountClick(config *Config, event ClickEvent) (bool, string) {
+	policy := config.GetClickPolicy()
+
+	if policy.UniqueOnly {
+		// Counted hits still in the click buffer are not in the database yet
+		if config.clickBuffer.hasPendingVisit(event.Alias, event.VisitorHash, policy.UniqueWindow) {
+			return false, "repeat_visitor"
+		}
+
+		seen, err := HasRecentCountedVisit(config, event.Alias, event.VisitorHash, policy.UniqueWindow)
+		if err != nil {
+			// Fall back to counting so a database hiccup never grants free clicks
+			log.Printf("Warning: unique visitor check failed for %s: %v", event.Alias, err)
+			return true, ""
+		}
+		if seen {
+			return false, "repeat_visitor"
+		}
+	}
+
+	return true, ""
+}
+
+// serveUncountedHit redirects a hit on an active URL that does not count
+// toward max_clicks
+func serveUncountedHit(c *gin.Context, config *Config, urlData *URLData, event ClickEvent, reason string) {
+	if event.Bot {
+		serveBotHit(c, config, urlData, event, reason)
+		return
+	}
+
+	log.Printf("Hit not counted: %s (%s, %d/%d clicks)", event.Alias, reason, urlData.Clicks, urlData.MaxClicks)
+
+	// Record the raw hit (non-fatal)
+	event.Counted = false
+	destination := resolveDestination(c, urlData, &event)
+	if err := RecordClickEvent(config, event); err != nil {
+		log.Printf("Warning: failed to record click event for %s: %v", event.Alias, err)
+	}
+
+	sendVisitor(c, urlData, destination)
+}
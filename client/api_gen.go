@@ -0,0 +1,343 @@
+// Code generated by clientgen from openapi.json. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// CleanupResponse is the CleanupResponse schema of the API.
+type CleanupResponse struct {
+	Message      string    `json:"message"`
+	DeletedCount int       `json:"deleted_count"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// DeepLink is the DeepLink schema of the API.
+type DeepLink struct {
+	IOSURL      string `json:"ios_url,omitempty"`
+	AndroidURL  string `json:"android_url,omitempty"`
+	FallbackURL string `json:"fallback_url,omitempty"`
+}
+
+// Destination is the Destination schema of the API.
+type Destination struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight,omitempty"`
+	Clicks int    `json:"clicks"`
+}
+
+// DestinationInfo is the DestinationInfo schema of the API.
+type DestinationInfo struct {
+	Variant     int     `json:"variant"`
+	URL         string  `json:"url"`
+	Weight      int     `json:"weight"`
+	WeightShare float64 `json:"weight_share"`
+	Clicks      int     `json:"clicks"`
+	ClickShare  float64 `json:"click_share"`
+}
+
+// ErrorResponse is the ErrorResponse schema of the API.
+type ErrorResponse struct {
+	Error     string      `json:"error"`
+	Message   string      `json:"message,omitempty"`
+	Code      string      `json:"code"`
+	Details   interface{} `json:"details,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// HealthResponse is the HealthResponse schema of the API.
+type HealthResponse struct {
+	Status    string       `json:"status"`
+	Timestamp time.Time    `json:"timestamp"`
+	Version   string       `json:"version"`
+	Database  string       `json:"database"`
+	Error     string       `json:"error,omitempty"`
+	Stats     *HealthStats `json:"stats,omitempty"`
+}
+
+// HealthStats is the HealthStats schema of the API.
+type HealthStats struct {
+	TotalURLs   int `json:"total_urls"`
+	TotalClicks int `json:"total_clicks"`
+	ActiveURLs  int `json:"active_urls"`
+	ExpiredURLs int `json:"expired_urls"`
+}
+
+// LinkEvent is the LinkEvent schema of the API.
+type LinkEvent struct {
+	ID          int64     `json:"id"`
+	Type        string    `json:"type"`
+	Alias       string    `json:"alias"`
+	ShortURL    string    `json:"short_url"`
+	Destination string    `json:"destination"`
+	Clicks      int       `json:"clicks"`
+	MaxClicks   int       `json:"max_clicks"`
+	Owner       int64     `json:"owner,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// ListURLsResponse is the ListURLsResponse schema of the API.
+type ListURLsResponse struct {
+	URLs       []URLData `json:"urls"`
+	Count      int       `json:"count"`
+	Page       int       `json:"page"`
+	Limit      int       `json:"limit"`
+	TotalPages int       `json:"total_pages"`
+	HasNext    bool      `json:"has_next"`
+	HasPrev    bool      `json:"has_prev"`
+}
+
+// RedirectRule is the RedirectRule schema of the API.
+type RedirectRule struct {
+	Name        string          `json:"name,omitempty"`
+	Destination string          `json:"destination"`
+	Countries   []string        `json:"countries,omitempty"`
+	Devices     []string        `json:"devices,omitempty"`
+	OS          []string        `json:"os,omitempty"`
+	Languages   []string        `json:"languages,omitempty"`
+	TimeWindow  *RuleTimeWindow `json:"time_window,omitempty"`
+}
+
+// RuleTimeWindow is the RuleTimeWindow schema of the API.
+type RuleTimeWindow struct {
+	From     *time.Time `json:"from,omitempty"`
+	Until    *time.Time `json:"until,omitempty"`
+	Days     []string   `json:"days,omitempty"`
+	Start    string     `json:"start,omitempty"`
+	End      string     `json:"end,omitempty"`
+	Timezone string     `json:"timezone,omitempty"`
+}
+
+// ShortenRequest is the ShortenRequest schema of the API.
+type ShortenRequest struct {
+	URL           string         `json:"url"`
+	Alias         string         `json:"alias,omitempty"`
+	AliasStyle    string         `json:"alias_style,omitempty"`
+	MaxClicks     int            `json:"max_clicks,omitempty"`
+	BotResponse   string         `json:"bot_response,omitempty"`
+	OGTitle       string         `json:"og_title,omitempty"`
+	OGDescription string         `json:"og_description,omitempty"`
+	OGImage       string         `json:"og_image,omitempty"`
+	Rules         []RedirectRule `json:"rules,omitempty"`
+	Destinations  []Destination  `json:"destinations,omitempty"`
+	DeepLink      *DeepLink      `json:"deep_link,omitempty"`
+}
+
+// ShortenResponse is the ShortenResponse schema of the API.
+type ShortenResponse struct {
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url"`
+	DisplayURL  string    `json:"display_url"`
+	FinalURL    string    `json:"final_url,omitempty"`
+	Alias       string    `json:"alias"`
+	CreatedAt   time.Time `json:"created_at"`
+	MaxClicks   int       `json:"max_clicks"`
+	Clicks      int       `json:"clicks"`
+}
+
+// StatsResponse is the StatsResponse schema of the API.
+type StatsResponse struct {
+	TotalURLs    int `json:"total_urls"`
+	TotalClicks  int `json:"total_clicks"`
+	TotalHits    int `json:"total_hits"`
+	TotalBotHits int `json:"total_bot_hits"`
+	ActiveURLs   int `json:"active_urls"`
+	ExpiredURLs  int `json:"expired_urls"`
+}
+
+// URLData is the URLData schema of the API.
+type URLData struct {
+	Alias             string         `json:"alias"`
+	URL               string         `json:"url"`
+	OriginalURL       string         `json:"original_url"`
+	FinalURL          string         `json:"final_url,omitempty"`
+	ShortURL          string         `json:"short_url"`
+	Clicks            int            `json:"clicks"`
+	Hits              int            `json:"hits"`
+	BotHits           int            `json:"bot_hits"`
+	BotResponse       string         `json:"bot_response"`
+	MaxClicks         int            `json:"max_clicks"`
+	CreatedAt         time.Time      `json:"created_at"`
+	OGTitle           string         `json:"og_title,omitempty"`
+	OGDescription     string         `json:"og_description,omitempty"`
+	OGImage           string         `json:"og_image,omitempty"`
+	Rules             []RedirectRule `json:"rules,omitempty"`
+	Destinations      []Destination  `json:"destinations,omitempty"`
+	DeepLink          *DeepLink      `json:"deep_link,omitempty"`
+	Health            string         `json:"health"`
+	HealthStatus      int            `json:"health_status,omitempty"`
+	HealthError       string         `json:"health_error,omitempty"`
+	HealthCheckedAt   *time.Time     `json:"health_checked_at,omitempty"`
+	HealthFailures    int            `json:"health_failures,omitempty"`
+	Title             string         `json:"title,omitempty"`
+	Description       string         `json:"description,omitempty"`
+	FaviconURL        string         `json:"favicon_url,omitempty"`
+	MetadataError     string         `json:"metadata_error,omitempty"`
+	MetadataFetchedAt *time.Time     `json:"metadata_fetched_at,omitempty"`
+}
+
+// URLInfoResponse is the URLInfoResponse schema of the API.
+type URLInfoResponse struct {
+	Alias           string            `json:"alias"`
+	OriginalURL     string            `json:"original_url"`
+	DisplayURL      string            `json:"display_url"`
+	FinalURL        string            `json:"final_url,omitempty"`
+	ShortURL        string            `json:"short_url"`
+	Clicks          int               `json:"clicks"`
+	Hits            int               `json:"hits"`
+	BotHits         int               `json:"bot_hits"`
+	BotResponse     string            `json:"bot_response"`
+	MaxClicks       int               `json:"max_clicks"`
+	RemainingClicks int               `json:"remaining_clicks"`
+	CreatedAt       time.Time         `json:"created_at"`
+	IsExpired       bool              `json:"is_expired"`
+	UsagePercentage float64           `json:"usage_percentage"`
+	OGTitle         string            `json:"og_title,omitempty"`
+	OGDescription   string            `json:"og_description,omitempty"`
+	OGImage         string            `json:"og_image,omitempty"`
+	Rules           []RedirectRule    `json:"rules,omitempty"`
+	RuleHits        map[string]int    `json:"rule_hits,omitempty"`
+	Destinations    []DestinationInfo `json:"destinations,omitempty"`
+	DeepLink        *DeepLink         `json:"deep_link,omitempty"`
+	Health          string            `json:"health"`
+	HealthStatus    int               `json:"health_status,omitempty"`
+	HealthError     string            `json:"health_error,omitempty"`
+	HealthCheckedAt *time.Time        `json:"health_checked_at,omitempty"`
+	Title           string            `json:"title,omitempty"`
+	Description     string            `json:"description,omitempty"`
+	FaviconURL      string            `json:"favicon_url,omitempty"`
+}
+
+// UpdateRulesRequest is the UpdateRulesRequest schema of the API.
+type UpdateRulesRequest struct {
+	Rules []RedirectRule `json:"rules"`
+}
+
+// CleanupExpiredURLs calls POST /api/v1/cleanup. Delete URLs that reached their click limit.
+func (c *Client) CleanupExpiredURLs(ctx context.Context) (*CleanupResponse, error) {
+	var out CleanupResponse
+	if err := c.do(ctx, "POST", "/api/v1/cleanup", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateShortURL calls POST /api/v1/shorten. Create a short URL.
+//
+// Creates a short URL for a destination, with an optional custom alias, click limit, redirect rules, A/B destinations and deep links.
+func (c *Client) CreateShortURL(ctx context.Context, body ShortenRequest) (*ShortenResponse, error) {
+	var out ShortenResponse
+	if err := c.do(ctx, "POST", "/api/v1/shorten", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateShortURLPublic calls POST /shorten. Create a short URL without an API key.
+//
+// Same as /api/v1/shorten, without API key checks. Used by the web form.
+func (c *Client) CreateShortURLPublic(ctx context.Context, body ShortenRequest) (*ShortenResponse, error) {
+	var out ShortenResponse
+	if err := c.do(ctx, "POST", "/shorten", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteURL calls DELETE /api/v1/urls/{alias}. Delete a short URL.
+//
+// Needs the API key that created the short URL, also without REQUIRE_API_KEY.
+func (c *Client) DeleteURL(ctx context.Context, alias string) error {
+	return c.do(ctx, "DELETE", "/api/v1/urls/"+url.PathEscape(alias), nil, nil, nil)
+}
+
+// GetHealth calls GET /health. Check server health.
+func (c *Client) GetHealth(ctx context.Context) (*HealthResponse, error) {
+	var out HealthResponse
+	if err := c.do(ctx, "GET", "/health", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStats calls GET /api/v1/stats. Get global statistics.
+func (c *Client) GetStats(ctx context.Context) (*StatsResponse, error) {
+	var out StatsResponse
+	if err := c.do(ctx, "GET", "/api/v1/stats", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetURLInfo calls GET /api/v1/urls/{alias}. Get details of a short URL.
+//
+// Returns a short URL with its click counts, rule hits, A/B destination shares, destination health and metadata.
+func (c *Client) GetURLInfo(ctx context.Context, alias string) (*URLInfoResponse, error) {
+	var out URLInfoResponse
+	if err := c.do(ctx, "GET", "/api/v1/urls/"+url.PathEscape(alias), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListURLsParams holds the optional query parameters of ListURLs.
+type ListURLsParams struct {
+	// Page number, starting at 1
+	Page int
+	// URLs per page, at most 100
+	Limit int
+	// Only active or expired URLs
+	Status string
+	// Search aliases and destinations
+	Q string
+	// Only URLs whose destination has this health
+	Health string
+}
+
+func (p *ListURLsParams) values() url.Values {
+	query := url.Values{}
+	if p.Page != 0 {
+		query.Set("page", strconv.Itoa(p.Page))
+	}
+	if p.Limit != 0 {
+		query.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Status != "" {
+		query.Set("status", p.Status)
+	}
+	if p.Q != "" {
+		query.Set("q", p.Q)
+	}
+	if p.Health != "" {
+		query.Set("health", p.Health)
+	}
+	return query
+}
+
+// ListURLs calls GET /api/v1/urls. List short URLs.
+func (c *Client) ListURLs(ctx context.Context, params *ListURLsParams) (*ListURLsResponse, error) {
+	var query url.Values
+	if params != nil {
+		query = params.values()
+	}
+	var out ListURLsResponse
+	if err := c.do(ctx, "GET", "/api/v1/urls", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateURLRules calls PUT /api/v1/urls/{alias}/rules. Replace the redirect rules of a short URL.
+//
+// Replaces all redirect rules. An empty list removes them. Needs the API key that created the short URL, also without REQUIRE_API_KEY.
+func (c *Client) UpdateURLRules(ctx context.Context, alias string, body UpdateRulesRequest) (*UpdateRulesRequest, error) {
+	var out UpdateRulesRequest
+	if err := c.do(ctx, "PUT", "/api/v1/urls/"+url.PathEscape(alias)+"/rules", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,4 @@
+package client
+
+//go:generate go run .. openapi --out openapi.json
+//go:generate go run ../tools/clientgen -spec openapi.json -out api_gen.go -package client
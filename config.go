@@ -1,82 +1,341 @@
 package main
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/joho/godotenv"
 	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/grpc"
 )
 
 // Config holds all configuration for the application
 type Config struct {
 	// Server Configuration
-	Port    string
-	GinMode string
+	Port     string
+	GinMode  string
+	GRPCPort string // Empty disables the gRPC API
 
 	// Database Configuration
 	DBPath           string
 	DBBackupInterval time.Duration
 
-	// Application Configuration
+	// Application Configuration (MaxClicks is hot-reloadable, use GetMaxClicks)
 	MaxClicks int
 	BaseURL   string
 
-	// Development Settings
+	// URL Normalization Configuration (hot-reloadable, use GetURLNormalization)
+	URLDefaultScheme string
+	URLStripFragment bool
+	URLStripTracking string
+	DeduplicateURLs  bool
+
+	// Click Counting Configuration (hot-reloadable except the salt, use GetClickPolicy)
+	ClickCounting       string
+	UniqueVisitorWindow time.Duration
+	SkipBotClicks       bool
+	BotSignaturesFile   string
+	VisitorHashSalt     string
+
+	// Click Write Configuration (strict writes each click, buffered batches them)
+	ClickWrites        string
+	ClickFlushInterval time.Duration
+
+	// Redirect Rules Configuration
+	GeoIPDBPath string
+
+	// App Links Configuration (hot-reloadable, use GetAppLinks)
+	AppleAppIDs             []string
+	AndroidAppPackage       string
+	AndroidCertFingerprints []string
+
+	// Development Settings (EnableCORS is hot-reloadable, use CORSEnabled)
 	LogLevel   string
 	EnableCORS bool
 
+	// CORS Configuration (hot-reloadable, read under mu)
+	CORSAllowedOrigins      []string
+	CORSAPIAllowedOrigins   []string
+	CORSAPIAllowCredentials bool
+	CORSAdminAllowedOrigins []string
+	CORSMaxAge              time.Duration
+
 	// Health Check Configuration
 	HealthCheckInterval time.Duration
 	CleanupInterval     time.Duration
 
+	// API Key Configuration
+	RequireAPIKey bool
+
+	// Idempotency Configuration
+	IdempotencyTTL time.Duration
+
+	// Event Stream Configuration
+	EventsHeartbeatInterval time.Duration
+	EventsReplayBuffer      int
+
+	// Link Check Configuration (LINK_CHECK_INTERVAL=0 disables the checker)
+	LinkCheckInterval         time.Duration
+	LinkCheckConcurrency      int
+	LinkCheckTimeout          time.Duration
+	LinkCheckHostDelay        time.Duration
+	LinkCheckFailureThreshold int
+	LinkCheckBlockDead        bool
+
+	// Redirect Resolution Configuration
+	ResolveRedirects       bool
+	RedirectMaxHops        int
+	RedirectResolveTimeout time.Duration
+
+	// Metadata Fetch Configuration
+	MetadataFetch    bool
+	MetadataWorkers  int
+	MetadataTimeout  time.Duration
+	MetadataMaxBytes int
+
+	// Outbound Request Configuration
+	OutboundAllowPrivate bool
+
+	// Alias Generation Configuration (AliasSalt is required for hashid)
+	AliasStrategy        string
+	AliasLength          int
+	AliasAlphabet        string
+	AliasSalt            string
+	AliasGrowthThreshold float64
+
+	// Alias Policy Configuration
+	ReservedAliasesFile  string
+	AliasProfanityFilter bool
+	AliasBlocklistFiles  []string
+	AliasCaseInsensitive bool
+
+	// Alias Cache Configuration (ALIAS_CACHE_SIZE=0 disables the cache)
+	AliasCacheSize int
+	AliasCacheTTL  time.Duration
+
+	// Admin Dashboard Configuration
+	AdminUsername      string
+	AdminPassword      string
+	AdminSessionSecret string
+	AdminSessionTTL    time.Duration
+
+	// Path of the config file the values were read from (private)
+	configFile string
+
+	// Guards settings that can be hot-reloaded (private)
+	mu          sync.RWMutex
+	reloadHooks []func()
+
 	// Database connection (private)
 	db *sql.DB
+
+	// Cache of URLs on the redirect path (private, nil when disabled)
+	cache *aliasCache
+
+	// Generators for aliases of links created without one, by name (private)
+	aliases map[string]aliasStrategy
+
+	// Reserved and blocked aliases (private)
+	aliasPolicy *aliasPolicy
+
+	// Buffered click counter (private, nil in strict mode and in the CLI)
+	clickBuffer *clickBuffer
+
+	// Destination checker (private, nil when disabled and in the CLI)
+	linkChecker *linkChecker
+
+	// Destination metadata fetcher (private, nil when disabled and in the CLI)
+	metadataFetcher *metadataFetcher
+
+	// gRPC API server (private, nil when GRPC_PORT is empty and in the CLI)
+	grpcServer *grpc.Server
+
+	// Link event hub behind /api/v1/events (private, nil in the CLI)
+	events *eventHub
 }
 
-// LoadConfig loads configuration from environment variables and .env file
+// LoadConfig loads configuration from the config file, environment variables
+// and .env file, then opens the database
 func LoadConfig() *Config {
-	// Load .env file if it exists (ignore error in production)
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Warning: .env file not found or could not be loaded: %v", err)
+	config, err := ReadConfig()
+	if err != nil {
+		log.Fatalf("Configuration validation failed: %v", err)
+	}
+
+	// Generate a session secret if the admin dashboard is enabled without one
+	if config.AdminEnabled() && config.AdminSessionSecret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("Failed to generate admin session secret: %v", err)
+		}
+		log.Printf("Warning: ADMIN_SESSION_SECRET not set, admin sessions will not survive a restart")
+		config.AdminSessionSecret = hex.EncodeToString(secret)
+	}
+
+	// Generate a visitor hash salt if none is configured; unique visitor
+	// detection then restarts from scratch after a restart
+	if config.VisitorHashSalt == "" {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			log.Fatalf("Failed to generate visitor hash salt: %v", err)
+		}
+		config.VisitorHashSalt = hex.EncodeToString(salt)
+	}
+
+	// Initialize database
+	if err := config.InitDB(); err != nil {
+		log.Fatalf("Database initialization failed: %v", err)
+	}
+
+	config.cache = newAliasCache(config.AliasCacheSize, config.AliasCacheTTL)
+	config.aliases = newAliasStrategies(config)
+	if config.aliasPolicy, err = newAliasPolicy(config); err != nil {
+		log.Fatalf("Failed to load alias policy: %v", err)
+	}
+
+	return config
+}
+
+// ReadConfig reads and validates configuration without opening the database.
+// Values are layered as defaults < config file < .env < environment.
+// All parse and validation problems are returned together.
+func ReadConfig() (*Config, error) {
+	dotenv := readDotEnv()
+	src, err := newConfigSource(configFilePath(dotenv), dotenv)
+	if err != nil {
+		return nil, err
 	}
 
 	config := &Config{
 		// Server Configuration with defaults
-		Port:    getEnv("PORT", "8080"),
-		GinMode: getEnv("GIN_MODE", "debug"),
+		Port:     src.getEnv("PORT", "8080"),
+		GinMode:  src.getEnv("GIN_MODE", "debug"),
+		GRPCPort: src.getEnv("GRPC_PORT", ""),
 
 		// Database Configuration with defaults
-		DBPath:           getEnv("DB_PATH", "./data/urls.db"),
-		DBBackupInterval: getEnvAsDuration("DB_BACKUP_INTERVAL", 1*time.Hour),
+		DBPath:           src.getEnv("DB_PATH", "./data/urls.db"),
+		DBBackupInterval: src.getEnvAsDuration("DB_BACKUP_INTERVAL", 1*time.Hour),
 
 		// Application Configuration with defaults
-		MaxClicks: getEnvAsInt("MAX_CLICKS", 5),
-		BaseURL:   getEnv("BASE_URL", "http://localhost:8080"),
+		MaxClicks: src.getEnvAsInt("MAX_CLICKS", 5),
+		BaseURL:   src.getEnv("BASE_URL", "http://localhost:8080"),
+
+		// URL Normalization Configuration with defaults
+		URLDefaultScheme: src.getEnv("URL_DEFAULT_SCHEME", "http"),
+		URLStripFragment: src.getEnvAsBool("URL_STRIP_FRAGMENT", false),
+		URLStripTracking: src.getEnv("URL_STRIP_TRACKING", stripTrackingNone),
+		DeduplicateURLs:  src.getEnvAsBool("DEDUPLICATE_URLS", false),
+
+		// Click Counting Configuration with defaults
+		ClickCounting:       src.getEnv("CLICK_COUNTING", "all"),
+		UniqueVisitorWindow: src.getEnvAsDuration("UNIQUE_VISITOR_WINDOW", 24*time.Hour),
+		SkipBotClicks:       src.getEnvAsBool("SKIP_BOT_CLICKS", true),
+		BotSignaturesFile:   src.getEnv("BOT_SIGNATURES_FILE", ""),
+		VisitorHashSalt:     src.getEnv("VISITOR_HASH_SALT", ""),
+
+		// Click Write Configuration with defaults
+		ClickWrites:        src.getEnv("CLICK_WRITES", clickWritesStrict),
+		ClickFlushInterval: src.getEnvAsDuration("CLICK_FLUSH_INTERVAL", 1*time.Second),
+
+		// Redirect Rules Configuration with defaults
+		GeoIPDBPath: src.getEnv("GEOIP_DB_PATH", ""),
+
+		// App Links Configuration with defaults
+		AppleAppIDs:             src.getEnvAsList("APPLE_APP_IDS", nil),
+		AndroidAppPackage:       src.getEnv("ANDROID_APP_PACKAGE", ""),
+		AndroidCertFingerprints: src.getEnvAsList("ANDROID_CERT_FINGERPRINTS", nil),
 
 		// Development Settings with defaults
-		LogLevel:   getEnv("LOG_LEVEL", "info"),
-		EnableCORS: getEnvAsBool("ENABLE_CORS", true),
+		LogLevel:   src.getEnv("LOG_LEVEL", "info"),
+		EnableCORS: src.getEnvAsBool("ENABLE_CORS", true),
 
-		// Health Check Configuration with defaults
-		HealthCheckInterval: getEnvAsDuration("HEALTH_CHECK_INTERVAL", 30*time.Second),
-		CleanupInterval:     getEnvAsDuration("CLEANUP_INTERVAL", 5*time.Minute),
-	}
+		// CORS Configuration with defaults
+		CORSAllowedOrigins:      src.getEnvAsList("CORS_ALLOWED_ORIGINS", []string{"*"}),
+		CORSAPIAllowedOrigins:   src.getEnvAsList("CORS_API_ALLOWED_ORIGINS", nil),
+		CORSAPIAllowCredentials: src.getEnvAsBool("CORS_API_ALLOW_CREDENTIALS", false),
+		CORSAdminAllowedOrigins: src.getEnvAsList("CORS_ADMIN_ALLOWED_ORIGINS", nil),
+		CORSMaxAge:              src.getEnvAsDuration("CORS_MAX_AGE", 12*time.Hour),
 
-	// Validate configuration
-	if err := config.Validate(); err != nil {
-		log.Fatalf("Configuration validation failed: %v", err)
+		// Health Check Configuration with defaults
+		HealthCheckInterval: src.getEnvAsDuration("HEALTH_CHECK_INTERVAL", 30*time.Second),
+		CleanupInterval:     src.getEnvAsDuration("CLEANUP_INTERVAL", 5*time.Minute),
+
+		// API Key Configuration with defaults
+		RequireAPIKey: src.getEnvAsBool("REQUIRE_API_KEY", false),
+
+		// Idempotency Configuration with defaults
+		IdempotencyTTL: src.getEnvAsDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		// Event Stream Configuration with defaults
+		EventsHeartbeatInterval: src.getEnvAsDuration("EVENTS_HEARTBEAT_INTERVAL", 15*time.Second),
+		EventsReplayBuffer:      src.getEnvAsInt("EVENTS_REPLAY_BUFFER", 1000),
+
+		// Link Check Configuration with defaults
+		LinkCheckInterval:         src.getEnvAsDuration("LINK_CHECK_INTERVAL", 0),
+		LinkCheckConcurrency:      src.getEnvAsInt("LINK_CHECK_CONCURRENCY", 4),
+		LinkCheckTimeout:          src.getEnvAsDuration("LINK_CHECK_TIMEOUT", 10*time.Second),
+		LinkCheckHostDelay:        src.getEnvAsDuration("LINK_CHECK_HOST_DELAY", 1*time.Second),
+		LinkCheckFailureThreshold: src.getEnvAsInt("LINK_CHECK_FAILURE_THRESHOLD", 3),
+		LinkCheckBlockDead:        src.getEnvAsBool("LINK_CHECK_BLOCK_DEAD", false),
+
+		// Redirect Resolution Configuration with defaults
+		ResolveRedirects:       src.getEnvAsBool("RESOLVE_REDIRECTS", false),
+		RedirectMaxHops:        src.getEnvAsInt("REDIRECT_MAX_HOPS", 5),
+		RedirectResolveTimeout: src.getEnvAsDuration("REDIRECT_RESOLVE_TIMEOUT", 5*time.Second),
+
+		// Metadata Fetch Configuration with defaults
+		MetadataFetch:    src.getEnvAsBool("METADATA_FETCH", false),
+		MetadataWorkers:  src.getEnvAsInt("METADATA_WORKERS", 2),
+		MetadataTimeout:  src.getEnvAsDuration("METADATA_TIMEOUT", 5*time.Second),
+		MetadataMaxBytes: src.getEnvAsInt("METADATA_MAX_BYTES", 512*1024),
+
+		// Outbound Request Configuration with defaults
+		OutboundAllowPrivate: src.getEnvAsBool("OUTBOUND_ALLOW_PRIVATE", false),
+
+		// Alias Generation Configuration with defaults
+		AliasStrategy:        src.getEnv("ALIAS_STRATEGY", "random"),
+		AliasLength:          src.getEnvAsInt("ALIAS_LENGTH", 6),
+		AliasAlphabet:        src.getEnv("ALIAS_ALPHABET", defaultAliasAlphabet),
+		AliasSalt:            src.getEnv("ALIAS_SALT", ""),
+		AliasGrowthThreshold: src.getEnvAsFloat("ALIAS_GROWTH_THRESHOLD", 0.1),
+
+		// Alias Policy Configuration with defaults
+		ReservedAliasesFile:  src.getEnv("RESERVED_ALIASES_FILE", ""),
+		AliasProfanityFilter: src.getEnvAsBool("ALIAS_PROFANITY_FILTER", false),
+		AliasBlocklistFiles:  src.getEnvAsList("ALIAS_BLOCKLIST_FILES", nil),
+		AliasCaseInsensitive: src.getEnvAsBool("ALIAS_CASE_INSENSITIVE", false),
+
+		// Alias Cache Configuration with defaults
+		AliasCacheSize: src.getEnvAsInt("ALIAS_CACHE_SIZE", 10000),
+		AliasCacheTTL:  src.getEnvAsDuration("ALIAS_CACHE_TTL", 30*time.Second),
+
+		// Admin Dashboard Configuration with defaults
+		AdminUsername:      src.getEnv("ADMIN_USERNAME", "admin"),
+		AdminPassword:      src.getEnv("ADMIN_PASSWORD", ""),
+		AdminSessionSecret: src.getEnv("ADMIN_SESSION_SECRET", ""),
+		AdminSessionTTL:    src.getEnvAsDuration("ADMIN_SESSION_TTL", 12*time.Hour),
+
+		configFile: src.path,
 	}
 
-	// Initialize database
-	if err := config.InitDB(); err != nil {
-		log.Fatalf("Database initialization failed: %v", err)
+	// Validate configuration, reporting parse errors alongside validation errors
+	errs := append(src.errs, src.unknownKeys()...)
+	errs = append(errs, config.Validate())
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
 	}
 
-	return config
+	return config, nil
 }
 
 // InitDB initializes the database connection
@@ -131,7 +390,10 @@ func (c *Config) createOrMigrateTables() error {
 
 	if len(tableInfo) == 0 {
 		// Table doesn't exist, create it
-		return c.createTables()
+		if err := c.createTables(); err != nil {
+			return err
+		}
+		return c.upgradeSchema()
 	}
 
 	// Table exists, check if we need to migrate
@@ -146,10 +408,215 @@ func (c *Config) createOrMigrateTables() error {
 	if !hasAlias {
 		// Need to migrate old table
 		log.Println("🔄 Migrating database schema...")
-		return c.migrateTables()
+		if err := c.migrateTables(); err != nil {
+			return err
+		}
+	}
+
+	// Table is up to date, apply later additions
+	return c.upgradeSchema()
+}
+
+// schemaColumns lists columns added after their table was first released.
+// They are added to existing databases with ALTER TABLE and must therefore
+// be nullable or have a default.
+var schemaColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"urls", "hits", "INTEGER DEFAULT 0"},
+	{"click_events", "visitor_hash", "TEXT"},
+	{"click_events", "counted", "BOOLEAN DEFAULT 1"},
+	{"urls", "bot_hits", "INTEGER DEFAULT 0"},
+	{"urls", "bot_response", "TEXT DEFAULT 'redirect'"},
+	{"click_events", "is_bot", "BOOLEAN DEFAULT 0"},
+	{"urls", "og_title", "TEXT"},
+	{"urls", "og_description", "TEXT"},
+	{"urls", "og_image", "TEXT"},
+	{"urls", "rules", "TEXT"},
+	{"click_events", "rule", "TEXT"},
+	{"urls", "app_ios_url", "TEXT"},
+	{"urls", "app_android_url", "TEXT"},
+	{"urls", "app_fallback_url", "TEXT"},
+	{"api_keys", "alias_prefix", "TEXT"},
+	{"urls", "alias_skeleton", "TEXT"},
+	{"urls", "health_status", "INTEGER"},
+	{"urls", "health_error", "TEXT"},
+	{"urls", "health_checked_at", "DATETIME"},
+	{"urls", "health_failures", "INTEGER DEFAULT 0"},
+	{"urls", "meta_title", "TEXT"},
+	{"urls", "meta_description", "TEXT"},
+	{"urls", "favicon_url", "TEXT"},
+	{"urls", "metadata_error", "TEXT"},
+	{"urls", "metadata_fetched_at", "DATETIME"},
+	{"urls", "final_url", "TEXT"},
+	{"urls", "api_key_id", "INTEGER"},
+}
+
+// upgradeSchema creates auxiliary tables and adds missing columns
+func (c *Config) upgradeSchema() error {
+	if err := c.createAuxiliaryTables(); err != nil {
+		return err
+	}
+
+	existing := make(map[string]map[string]bool)
+	for _, col := range schemaColumns {
+		if existing[col.table] == nil {
+			columns, err := c.getTableInfo(col.table)
+			if err != nil {
+				return err
+			}
+			existing[col.table] = make(map[string]bool)
+			for _, name := range columns {
+				existing[col.table][name] = true
+			}
+		}
+
+		if existing[col.table][col.column] {
+			continue
+		}
+
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", col.table, col.column, col.definition)
+		if _, err := c.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %v", col.table, col.column, err)
+		}
+		existing[col.table][col.column] = true
+		log.Printf("🔄 Added column %s.%s", col.table, col.column)
+	}
+
+	// Indexes added after the tables were first released
+	query := `
+	CREATE INDEX IF NOT EXISTS idx_click_events_visitor ON click_events(alias, visitor_hash, clicked_at);
+	CREATE INDEX IF NOT EXISTS idx_urls_alias_skeleton ON urls(alias_skeleton);
+	CREATE INDEX IF NOT EXISTS idx_urls_original_url ON urls(original_url);
+	`
+	if _, err := c.db.Exec(query); err != nil {
+		return err
+	}
+
+	if err := c.backfillAliasSkeletons(); err != nil {
+		return err
+	}
+
+	return c.applyAliasCaseSensitivity()
+}
+
+// backfillAliasSkeletons stores the skeleton of aliases created before
+// skeletons were tracked, so new aliases are compared against them
+func (c *Config) backfillAliasSkeletons() error {
+	rows, err := c.db.Query("SELECT alias FROM urls WHERE alias_skeleton IS NULL")
+	if err != nil {
+		return fmt.Errorf("failed to read aliases: %v", err)
+	}
+	var aliases []string
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read aliases: %v", err)
+		}
+		aliases = append(aliases, alias)
+	}
+	rows.Close()
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, alias := range aliases {
+		if _, err := tx.Exec("UPDATE urls SET alias_skeleton = ? WHERE alias = ?", aliasSkeleton(alias, true), alias); err != nil {
+			return fmt.Errorf("failed to store skeleton of %s: %v", alias, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to store alias skeletons: %v", err)
+	}
+
+	log.Printf("🔄 Stored skeletons of %d aliases", len(aliases))
+	return nil
+}
+
+// applyAliasCaseSensitivity adds or drops the unique index that keeps
+// aliases from differing only in case
+func (c *Config) applyAliasCaseSensitivity() error {
+	if !c.AliasCaseInsensitive {
+		_, err := c.db.Exec("DROP INDEX IF EXISTS idx_alias_nocase")
+		return err
+	}
+
+	if _, err := c.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_alias_nocase ON urls(alias COLLATE NOCASE)"); err != nil {
+		var duplicates []string
+		rows, queryErr := c.db.Query("SELECT LOWER(alias) FROM urls GROUP BY LOWER(alias) HAVING COUNT(*) > 1 LIMIT 10")
+		if queryErr == nil {
+			defer rows.Close()
+			for rows.Next() {
+				var alias string
+				if rows.Scan(&alias) == nil {
+					duplicates = append(duplicates, alias)
+				}
+			}
+		}
+		return fmt.Errorf("ALIAS_CASE_INSENSITIVE: existing aliases differ only in case (%s): %v",
+			strings.Join(duplicates, ", "), err)
+	}
+	return nil
+}
+
+// createAuxiliaryTables creates tables that hang off the urls table
+func (c *Config) createAuxiliaryTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS click_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		alias TEXT NOT NULL REFERENCES urls(alias) ON DELETE CASCADE,
+		user_agent TEXT,
+		referrer TEXT,
+		clicked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_click_events_alias ON click_events(alias, clicked_at);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		key_hash TEXT UNIQUE NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_used_at DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		scope TEXT NOT NULL,
+		key TEXT NOT NULL,
+		request_hash TEXT NOT NULL,
+		status INTEGER,
+		content_type TEXT,
+		body BLOB,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (scope, key)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+
+	CREATE TABLE IF NOT EXISTS url_destinations (
+		alias TEXT NOT NULL REFERENCES urls(alias) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		url TEXT NOT NULL,
+		weight INTEGER NOT NULL DEFAULT 1,
+		clicks INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (alias, position)
+	);
+	`
+
+	if _, err := c.db.Exec(query); err != nil {
+		return err
 	}
 
-	// Table is up to date
 	return nil
 }
 
@@ -315,85 +782,236 @@ func (c *Config) createTables() error {
 	return nil
 }
 
-// Validate checks if the configuration is valid
+// Validate checks if the configuration is valid and returns every problem found
 func (c *Config) Validate() error {
+	var errs []error
+
 	// Validate GIN_MODE
 	if c.GinMode != "debug" && c.GinMode != "release" && c.GinMode != "test" {
-		log.Printf("Warning: Invalid GIN_MODE '%s', using 'debug'. Valid values: debug, release, test", c.GinMode)
-		c.GinMode = "debug"
+		errs = append(errs, fmt.Errorf("GIN_MODE: invalid value %q (valid values: debug, release, test)", c.GinMode))
+	}
+
+	// Validate Port
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT: %q is not a valid port number (1-65535)", c.Port))
+	}
+
+	// Validate GRPCPort (optional)
+	if c.GRPCPort != "" {
+		if port, err := strconv.Atoi(c.GRPCPort); err != nil || port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("GRPC_PORT: %q is not a valid port number (1-65535)", c.GRPCPort))
+		} else if c.GRPCPort == c.Port {
+			errs = append(errs, fmt.Errorf("GRPC_PORT: must differ from PORT (%s)", c.Port))
+		}
+	}
+
+	// Validate BaseURL
+	if baseURL, err := url.Parse(c.BaseURL); err != nil ||
+		(baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		errs = append(errs, fmt.Errorf("BASE_URL: %q must be an absolute http(s) URL", c.BaseURL))
+	} else if strings.HasSuffix(c.BaseURL, "/") {
+		errs = append(errs, fmt.Errorf("BASE_URL: %q must not end with a slash", c.BaseURL))
+	}
+
+	// Validate DBPath
+	if c.DBPath == "" {
+		errs = append(errs, fmt.Errorf("DB_PATH: must not be empty"))
 	}
 
 	// Validate MaxClicks
 	if c.MaxClicks <= 0 {
-		log.Printf("Warning: Invalid MAX_CLICKS '%d', using default value 5", c.MaxClicks)
-		c.MaxClicks = 5
+		errs = append(errs, fmt.Errorf("MAX_CLICKS: must be positive, got %d", c.MaxClicks))
 	}
 
-	// Validate Port
-	if c.Port == "" {
-		log.Printf("Warning: Empty PORT, using default value 8080")
-		c.Port = "8080"
+	// Validate alias generation
+	if _, found := aliasStrategies[c.AliasStrategy]; !found {
+		errs = append(errs, fmt.Errorf("ALIAS_STRATEGY: invalid value %q (valid values: random, hashid, words)", c.AliasStrategy))
+	}
+	if c.AliasLength < 4 || c.AliasLength > maxGeneratedAliasLength {
+		errs = append(errs, fmt.Errorf("ALIAS_LENGTH: must be between 4 and %d, got %d", maxGeneratedAliasLength, c.AliasLength))
+	}
+	if err := validateAliasAlphabet(c.AliasAlphabet); err != nil {
+		errs = append(errs, fmt.Errorf("ALIAS_ALPHABET: %v", err))
+	}
+	if c.AliasStrategy == "hashid" && c.AliasSalt == "" {
+		errs = append(errs, fmt.Errorf("ALIAS_SALT: required when ALIAS_STRATEGY is hashid"))
+	}
+	if _, fits := power(uint64(len(c.AliasAlphabet)), c.AliasLength); c.AliasStrategy == "hashid" && !fits {
+		errs = append(errs, fmt.Errorf("ALIAS_LENGTH: %d characters of a %d character alphabet exceed 63 bits, too long for hashid",
+			c.AliasLength, len(c.AliasAlphabet)))
+	}
+	if c.AliasGrowthThreshold < 0 || c.AliasGrowthThreshold > 1 {
+		errs = append(errs, fmt.Errorf("ALIAS_GROWTH_THRESHOLD: must be between 0 (never grow) and 1, got %v", c.AliasGrowthThreshold))
 	}
 
-	return nil
-}
+	// Validate alias policy files
+	if _, err := newAliasPolicy(c); err != nil {
+		errs = append(errs, err)
+	}
 
-// IsDevelopment returns true if running in development mode
-func (c *Config) IsDevelopment() bool {
-	return c.GinMode == "debug"
-}
+	// Validate EventsReplayBuffer
+	if c.EventsReplayBuffer < 0 {
+		errs = append(errs, fmt.Errorf("EVENTS_REPLAY_BUFFER: must be zero (no replay) or positive, got %d", c.EventsReplayBuffer))
+	}
 
-// IsProduction returns true if running in production mode
-func (c *Config) IsProduction() bool {
-	return c.GinMode == "release"
-}
+	// Validate AliasCacheSize
+	if c.AliasCacheSize < 0 {
+		errs = append(errs, fmt.Errorf("ALIAS_CACHE_SIZE: must be zero (disabled) or positive, got %d", c.AliasCacheSize))
+	}
 
-// IsTest returns true if running in test mode
-func (c *Config) IsTest() bool {
-	return c.GinMode == "test"
-}
+	// Validate link checking
+	if c.LinkCheckInterval < 0 {
+		errs = append(errs, fmt.Errorf("LINK_CHECK_INTERVAL: must be zero (disabled) or positive, got %v", c.LinkCheckInterval))
+	}
+	if c.LinkCheckConcurrency < 1 {
+		errs = append(errs, fmt.Errorf("LINK_CHECK_CONCURRENCY: must be at least 1, got %d", c.LinkCheckConcurrency))
+	}
+	if c.LinkCheckHostDelay < 0 {
+		errs = append(errs, fmt.Errorf("LINK_CHECK_HOST_DELAY: must not be negative, got %v", c.LinkCheckHostDelay))
+	}
+	if c.LinkCheckFailureThreshold < 1 {
+		errs = append(errs, fmt.Errorf("LINK_CHECK_FAILURE_THRESHOLD: must be at least 1, got %d", c.LinkCheckFailureThreshold))
+	}
 
-// Helper functions to read environment variables
+	// Validate redirect resolution
+	if c.RedirectMaxHops < 1 || c.RedirectMaxHops > 20 {
+		errs = append(errs, fmt.Errorf("REDIRECT_MAX_HOPS: must be between 1 and 20, got %d", c.RedirectMaxHops))
+	}
 
-// getEnv gets an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	// Validate metadata fetching
+	if c.MetadataWorkers < 1 {
+		errs = append(errs, fmt.Errorf("METADATA_WORKERS: must be at least 1, got %d", c.MetadataWorkers))
+	}
+	if c.MetadataMaxBytes < 1024 {
+		errs = append(errs, fmt.Errorf("METADATA_MAX_BYTES: must be at least 1024, got %d", c.MetadataMaxBytes))
+	}
+
+	// Validate ClickCounting
+	if c.ClickCounting != "all" && c.ClickCounting != "unique" {
+		errs = append(errs, fmt.Errorf("CLICK_COUNTING: invalid value %q (valid values: all, unique)", c.ClickCounting))
+	}
+
+	// Validate URL normalization
+	if c.URLDefaultScheme != "http" && c.URLDefaultScheme != "https" {
+		errs = append(errs, fmt.Errorf("URL_DEFAULT_SCHEME: invalid value %q (valid values: http, https)", c.URLDefaultScheme))
+	}
+	if c.URLStripTracking != stripTrackingNone && c.URLStripTracking != stripTrackingClickIDs && c.URLStripTracking != stripTrackingAll {
+		errs = append(errs, fmt.Errorf("URL_STRIP_TRACKING: invalid value %q (valid values: %s, %s, %s)",
+			c.URLStripTracking, stripTrackingNone, stripTrackingClickIDs, stripTrackingAll))
+	}
+
+	// Validate ClickWrites
+	if c.ClickWrites != clickWritesStrict && c.ClickWrites != clickWritesBuffered {
+		errs = append(errs, fmt.Errorf("CLICK_WRITES: invalid value %q (valid values: strict, buffered)", c.ClickWrites))
 	}
-	return defaultValue
-}
 
-// getEnvAsInt gets an environment variable as integer or returns a default value
-func getEnvAsInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+	// Validate BotSignaturesFile
+	if c.BotSignaturesFile != "" {
+		if _, err := loadBotSignatures(c.BotSignaturesFile); err != nil {
+			errs = append(errs, fmt.Errorf("BOT_SIGNATURES_FILE: %v", err))
 		}
-		log.Printf("Warning: Invalid integer value for %s: %s, using default: %d", key, value, defaultValue)
 	}
-	return defaultValue
-}
 
-// getEnvAsBool gets an environment variable as boolean or returns a default value
-func getEnvAsBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		if boolValue, err := strconv.ParseBool(value); err == nil {
-			return boolValue
+	// Validate GeoIPDBPath
+	if c.GeoIPDBPath != "" {
+		if _, err := os.Stat(c.GeoIPDBPath); err != nil {
+			errs = append(errs, fmt.Errorf("GEOIP_DB_PATH: %v", err))
 		}
-		log.Printf("Warning: Invalid boolean value for %s: %s, using default: %t", key, value, defaultValue)
 	}
-	return defaultValue
-}
 
-// getEnvAsDuration gets an environment variable as duration or returns a default value
-func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
+	// Validate app links
+	for _, appID := range c.AppleAppIDs {
+		if !appleAppIDPattern.MatchString(appID) {
+			errs = append(errs, fmt.Errorf("APPLE_APP_IDS: %q is not of the form TEAMID.bundle.id", appID))
 		}
-		log.Printf("Warning: Invalid duration value for %s: %s, using default: %v", key, value, defaultValue)
 	}
-	return defaultValue
+	if c.AndroidAppPackage != "" && !androidPackagePattern.MatchString(c.AndroidAppPackage) {
+		errs = append(errs, fmt.Errorf("ANDROID_APP_PACKAGE: %q is not a valid package name", c.AndroidAppPackage))
+	}
+	if c.AndroidAppPackage != "" && len(c.AndroidCertFingerprints) == 0 {
+		errs = append(errs, fmt.Errorf("ANDROID_CERT_FINGERPRINTS: required when ANDROID_APP_PACKAGE is set"))
+	}
+	for _, fingerprint := range c.AndroidCertFingerprints {
+		if !certFingerprintPattern.MatchString(fingerprint) {
+			errs = append(errs, fmt.Errorf("ANDROID_CERT_FINGERPRINTS: %q is not a SHA-256 fingerprint (AA:BB:...)", fingerprint))
+		}
+	}
+
+	// Validate LogLevel
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL: invalid value %q (valid values: debug, info, warn, error)", c.LogLevel))
+	}
+
+	// Validate CORS origins
+	for _, origins := range []struct {
+		key    string
+		values []string
+	}{
+		{"CORS_ALLOWED_ORIGINS", c.CORSAllowedOrigins},
+		{"CORS_API_ALLOWED_ORIGINS", c.CORSAPIAllowedOrigins},
+		{"CORS_ADMIN_ALLOWED_ORIGINS", c.CORSAdminAllowedOrigins},
+	} {
+		for _, origin := range origins.values {
+			if err := validateCORSOrigin(origin, len(origins.values)); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", origins.key, err))
+			}
+		}
+	}
+	if c.CORSAPIAllowCredentials && len(c.CORSAPIAllowedOrigins) == 1 && c.CORSAPIAllowedOrigins[0] == "*" {
+		errs = append(errs, fmt.Errorf("CORS_API_ALLOW_CREDENTIALS: browsers reject credentials with a wildcard origin, list explicit CORS_API_ALLOWED_ORIGINS"))
+	}
+	if slices.Contains(c.CORSAdminAllowedOrigins, "*") {
+		errs = append(errs, fmt.Errorf("CORS_ADMIN_ALLOWED_ORIGINS: the admin dashboard cannot allow every origin, list explicit origins"))
+	}
+
+	// Validate durations
+	durations := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"DB_BACKUP_INTERVAL", c.DBBackupInterval},
+		{"HEALTH_CHECK_INTERVAL", c.HealthCheckInterval},
+		{"CLEANUP_INTERVAL", c.CleanupInterval},
+		{"ADMIN_SESSION_TTL", c.AdminSessionTTL},
+		{"CORS_MAX_AGE", c.CORSMaxAge},
+		{"UNIQUE_VISITOR_WINDOW", c.UniqueVisitorWindow},
+		{"ALIAS_CACHE_TTL", c.AliasCacheTTL},
+		{"CLICK_FLUSH_INTERVAL", c.ClickFlushInterval},
+		{"LINK_CHECK_TIMEOUT", c.LinkCheckTimeout},
+		{"REDIRECT_RESOLVE_TIMEOUT", c.RedirectResolveTimeout},
+		{"METADATA_TIMEOUT", c.MetadataTimeout},
+		{"IDEMPOTENCY_TTL", c.IdempotencyTTL},
+		{"EVENTS_HEARTBEAT_INTERVAL", c.EventsHeartbeatInterval},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s: must be a positive duration, got %v", d.key, d.value))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// AdminEnabled returns true if the admin dashboard has credentials configured
+func (c *Config) AdminEnabled() bool {
+	return c.AdminPassword != ""
+}
+
+// IsDevelopment returns true if running in development mode
+func (c *Config) IsDevelopment() bool {
+	return c.GinMode == "debug"
+}
+
+// IsProduction returns true if running in production mode
+func (c *Config) IsProduction() bool {
+	return c.GinMode == "release"
+}
+
+// IsTest returns true if running in test mode
+func (c *Config) IsTest() bool {
+	return c.GinMode == "test"
 }
 
 // PrintConfig prints the current configuration (for debugging)
@@ -401,14 +1019,43 @@ func (c *Config) PrintConfig() {
 	if c.IsDevelopment() {
 		log.Println("=== Application Configuration ===")
 		log.Printf("Port: %s", c.Port)
+		log.Printf("gRPC Port: %s", c.GRPCPort)
 		log.Printf("GIN Mode: %s", c.GinMode)
 		log.Printf("Database Path: %s", c.DBPath)
-		log.Printf("Max Clicks: %d", c.MaxClicks)
+		log.Printf("Config File: %s", c.ConfigFile())
+		log.Printf("Max Clicks: %d", c.GetMaxClicks())
 		log.Printf("Base URL: %s", c.BaseURL)
 		log.Printf("Log Level: %s", c.LogLevel)
-		log.Printf("Enable CORS: %t", c.EnableCORS)
+		log.Printf("Click Counting: %s (unique window %v, skip bots %t)", c.ClickCounting, c.UniqueVisitorWindow, c.SkipBotClicks)
+		log.Printf("Click Writes: %s (flush interval %v)", c.ClickWrites, c.ClickFlushInterval)
+		log.Printf("Link Check: interval %v, concurrency %d, timeout %v, host delay %v, block dead after %d failures: %t",
+			c.LinkCheckInterval, c.LinkCheckConcurrency, c.LinkCheckTimeout, c.LinkCheckHostDelay,
+			c.LinkCheckFailureThreshold, c.LinkCheckBlockDead)
+		log.Printf("Redirect Resolution: %t (max hops %d, timeout %v)",
+			c.ResolveRedirects, c.RedirectMaxHops, c.RedirectResolveTimeout)
+		log.Printf("Metadata Fetch: %t (workers %d, timeout %v, max bytes %d)",
+			c.MetadataFetch, c.MetadataWorkers, c.MetadataTimeout, c.MetadataMaxBytes)
+		log.Printf("Outbound Allow Private: %t", c.OutboundAllowPrivate)
+		log.Printf("URL Normalization: default scheme %s, strip fragment %t, strip tracking %s, deduplicate %t",
+			c.URLDefaultScheme, c.URLStripFragment, c.URLStripTracking, c.DeduplicateURLs)
+		log.Printf("Bot Signatures File: %s", c.BotSignaturesFile)
+		log.Printf("GeoIP Database: %s", c.GeoIPDBPath)
+		log.Printf("Apple App IDs: %v", c.AppleAppIDs)
+		log.Printf("Android App Package: %s", c.AndroidAppPackage)
+		log.Printf("Enable CORS: %t", c.CORSEnabled())
+		log.Printf("CORS Allowed Origins: %v", c.CORSAllowedOrigins)
+		log.Printf("CORS API Allowed Origins: %v", c.CORSAPIAllowedOrigins)
+		log.Printf("CORS Admin Allowed Origins: %v", c.CORSAdminAllowedOrigins)
 		log.Printf("Health Check Interval: %v", c.HealthCheckInterval)
 		log.Printf("Cleanup Interval: %v", c.CleanupInterval)
+		log.Printf("Require API Key: %t", c.RequireAPIKey)
+		log.Printf("Idempotency Key TTL: %v", c.IdempotencyTTL)
+		log.Printf("Event Stream: heartbeat %v, replay buffer %d", c.EventsHeartbeatInterval, c.EventsReplayBuffer)
+		log.Printf("Alias Strategy: %s (length %d, %d character alphabet)", c.AliasStrategy, c.AliasLength, len(c.AliasAlphabet))
+		log.Printf("Alias Policy: reserved file %q, profanity filter %t, blocklists %v, case-insensitive %t",
+			c.ReservedAliasesFile, c.AliasProfanityFilter, c.AliasBlocklistFiles, c.AliasCaseInsensitive)
+		log.Printf("Alias Cache: %d entries, TTL %v", c.AliasCacheSize, c.AliasCacheTTL)
+		log.Printf("Admin Dashboard Enabled: %t", c.AdminEnabled())
 		log.Println("=================================")
 	}
 }
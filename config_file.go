@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFiles are checked in order when CONFIG_FILE is not set
+var defaultConfigFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// configSource resolves configuration values from the environment, falling
+// back to .env and then to an optional config file. Parse errors are
+// collected rather than replaced with defaults so they can be reported
+// together.
+type configSource struct {
+	path   string
+	dotenv map[string]string // .env values, read without touching the environment
+	values map[string]string // config file values keyed by env var name
+	used   map[string]bool
+	errs   []error
+}
+
+// readDotEnv reads .env from the working directory. It is read on every
+// load instead of being copied into the environment, so edits are picked up
+// on reload and do not shadow the config file once the server has started.
+func readDotEnv() map[string]string {
+	values, err := godotenv.Read()
+	if err != nil {
+		log.Printf("Warning: .env file not found or could not be loaded: %v", err)
+		return map[string]string{}
+	}
+	return values
+}
+
+// configFilePath returns the config file to use, or "" if there is none
+func configFilePath(dotenv map[string]string) string {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		return path
+	}
+	if path := dotenv["CONFIG_FILE"]; path != "" {
+		return path
+	}
+
+	for _, path := range defaultConfigFiles {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// newConfigSource reads the config file at path, if any. YAML and TOML files
+// are supported; keys are the lowercase env var names, and nested tables are
+// joined with underscores (admin.username is ADMIN_USERNAME).
+func newConfigSource(path string, dotenv map[string]string) (*configSource, error) {
+	src := &configSource{
+		path:   path,
+		dotenv: dotenv,
+		values: make(map[string]string),
+		used:   make(map[string]bool),
+	}
+	if path == "" {
+		return src, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	raw := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q (use .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	flattenConfigValues("", raw, src.values)
+	return src, nil
+}
+
+// flattenConfigValues converts nested config file values into env-style keys
+func flattenConfigValues(prefix string, raw map[string]interface{}, out map[string]string) {
+	for key, value := range raw {
+		name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flattenConfigValues(name, v, out)
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			out[name] = strings.Join(items, ",")
+		case nil:
+			out[name] = ""
+		default:
+			out[name] = fmt.Sprint(v)
+		}
+	}
+}
+
+// lookup returns the raw value for key, preferring the environment, then
+// .env, then the config file
+func (s *configSource) lookup(key string) string {
+	s.used[key] = true
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	if value := s.dotenv[key]; value != "" {
+		return value
+	}
+	return s.values[key]
+}
+
+// unknownKeys reports config file keys that no setting reads
+func (s *configSource) unknownKeys() []error {
+	var unknown []string
+	for key := range s.values {
+		if !s.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	errs := make([]error, 0, len(unknown))
+	for _, key := range unknown {
+		errs = append(errs, fmt.Errorf("%s: unknown setting %q in config file", key, strings.ToLower(key)))
+	}
+	return errs
+}
+
+// getEnv gets a value or returns a default value
+func (s *configSource) getEnv(key, defaultValue string) string {
+	if value := s.lookup(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvAsInt gets a value as integer or returns a default value
+func (s *configSource) getEnvAsInt(key string, defaultValue int) int {
+	if value := s.lookup(key); value != "" {
+		intValue, err := strconv.Atoi(value)
+		if err != nil {
+			s.errs = append(s.errs, fmt.Errorf("%s: invalid integer value %q", key, value))
+			return defaultValue
+		}
+		return intValue
+	}
+	return defaultValue
+}
+
+// getEnvAsBool gets a value as boolean or returns a default value
+func (s *configSource) getEnvAsBool(key string, defaultValue bool) bool {
+	if value := s.lookup(key); value != "" {
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			s.errs = append(s.errs, fmt.Errorf("%s: invalid boolean value %q", key, value))
+			return defaultValue
+		}
+		return boolValue
+	}
+	return defaultValue
+}
+
+// getEnvAsFloat gets a value as floating point number or returns a default value
+func (s *configSource) getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := s.lookup(key); value != "" {
+		floatValue, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			s.errs = append(s.errs, fmt.Errorf("%s: invalid number value %q", key, value))
+			return defaultValue
+		}
+		return floatValue
+	}
+	return defaultValue
+}
+
+// getEnvAsDuration gets a value as duration or returns a default value
+func (s *configSource) getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := s.lookup(key); value != "" {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			s.errs = append(s.errs, fmt.Errorf("%s: invalid duration value %q", key, value))
+			return defaultValue
+		}
+		return duration
+	}
+	return defaultValue
+}
+
+// getEnvAsList gets a comma separated value as a list or returns a default value
+func (s *configSource) getEnvAsList(key string, defaultValue []string) []string {
+	if value := s.lookup(key); value != "" {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	return defaultValue
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// GetMaxClicks returns the default click limit for new URLs
+func (c *Config) GetMaxClicks() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.MaxClicks
+}
+
+// ClickPolicy controls which hits count toward a URL's max_clicks
+type ClickPolicy struct {
+	UniqueOnly    bool
+	UniqueWindow  time.Duration
+	SkipBotClicks bool
+}
+
+// GetClickPolicy returns the current click counting policy
+func (c *Config) GetClickPolicy() ClickPolicy {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return ClickPolicy{
+		UniqueOnly:    c.ClickCounting == "unique",
+		UniqueWindow:  c.UniqueVisitorWindow,
+		SkipBotClicks: c.SkipBotClicks,
+	}
+}
+
+// URLNormalization controls how destination URLs are canonicalized
+type URLNormalization struct {
+	DefaultScheme string // Scheme for URLs given without one
+	StripFragment bool
+	StripTracking string // URL_STRIP_TRACKING policy
+	Deduplicate   bool   // Reuse an identical active link for the same URL
+}
+
+// GetURLNormalization returns the current URL normalization policy
+func (c *Config) GetURLNormalization() URLNormalization {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return URLNormalization{
+		DefaultScheme: c.URLDefaultScheme,
+		StripFragment: c.URLStripFragment,
+		StripTracking: c.URLStripTracking,
+		Deduplicate:   c.DeduplicateURLs,
+	}
+}
+
+// CORSEnabled returns true if CORS headers should be sent
+func (c *Config) CORSEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.EnableCORS
+}
+
+// GetBotSignaturesFile returns the configured bot signature list, or "" for
+// the built-in list
+func (c *Config) GetBotSignaturesFile() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.BotSignaturesFile
+}
+
+// AppLinks is the app association served under /.well-known
+type AppLinks struct {
+	AppleAppIDs             []string
+	AndroidPackage          string
+	AndroidCertFingerprints []string
+}
+
+// GetAppLinks returns the current app association settings
+func (c *Config) GetAppLinks() AppLinks {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return AppLinks{
+		AppleAppIDs:             c.AppleAppIDs,
+		AndroidPackage:          c.AndroidAppPackage,
+		AndroidCertFingerprints: c.AndroidCertFingerprints,
+	}
+}
+
+// ConfigFile returns the config file path in use, or "(none)"
+func (c *Config) ConfigFile() string {
+	if c.configFile == "" {
+		return "(none)"
+	}
+	return c.configFile
+}
+
+// Reload re-reads configuration and applies settings that are safe to change
+// while running. Settings that need a restart are reported but left unchanged.
+// An invalid configuration is rejected as a whole and nothing is applied.
+func (c *Config) Reload() error {
+	next, err := ReadConfig()
+	if err != nil {
+		return err
+	}
+
+	c.applyReload(next)
+
+	// Let components rebuild state derived from reloadable settings
+	c.mu.RLock()
+	hooks := c.reloadHooks
+	c.mu.RUnlock()
+	for _, hook := range hooks {
+		hook()
+	}
+
+	return nil
+}
+
+// OnReload registers a function to run after settings are hot-reloaded
+func (c *Config) OnReload(hook func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reloadHooks = append(c.reloadHooks, hook)
+}
+
+// applyReload copies reloadable settings from next
+func (c *Config) applyReload(next *Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.MaxClicks != next.MaxClicks {
+		log.Printf("🔄 MAX_CLICKS changed: %d -> %d", c.MaxClicks, next.MaxClicks)
+		c.MaxClicks = next.MaxClicks
+	}
+
+	if c.EnableCORS != next.EnableCORS {
+		log.Printf("🔄 ENABLE_CORS changed: %t -> %t", c.EnableCORS, next.EnableCORS)
+		c.EnableCORS = next.EnableCORS
+	}
+
+	if c.ClickCounting != next.ClickCounting ||
+		c.UniqueVisitorWindow != next.UniqueVisitorWindow ||
+		c.SkipBotClicks != next.SkipBotClicks {
+		log.Printf("🔄 Click counting changed: mode=%s, window=%v, skip_bots=%t",
+			next.ClickCounting, next.UniqueVisitorWindow, next.SkipBotClicks)
+		c.ClickCounting = next.ClickCounting
+		c.UniqueVisitorWindow = next.UniqueVisitorWindow
+		c.SkipBotClicks = next.SkipBotClicks
+	}
+
+	if c.URLDefaultScheme != next.URLDefaultScheme ||
+		c.URLStripFragment != next.URLStripFragment ||
+		c.URLStripTracking != next.URLStripTracking ||
+		c.DeduplicateURLs != next.DeduplicateURLs {
+		log.Printf("🔄 URL normalization changed: default_scheme=%s, strip_fragment=%t, strip_tracking=%s, deduplicate=%t",
+			next.URLDefaultScheme, next.URLStripFragment, next.URLStripTracking, next.DeduplicateURLs)
+		c.URLDefaultScheme = next.URLDefaultScheme
+		c.URLStripFragment = next.URLStripFragment
+		c.URLStripTracking = next.URLStripTracking
+		c.DeduplicateURLs = next.DeduplicateURLs
+	}
+
+	if c.BotSignaturesFile != next.BotSignaturesFile {
+		log.Printf("🔄 BOT_SIGNATURES_FILE changed: %q -> %q", c.BotSignaturesFile, next.BotSignaturesFile)
+		c.BotSignaturesFile = next.BotSignaturesFile
+	}
+
+	if !slices.Equal(c.CORSAllowedOrigins, next.CORSAllowedOrigins) ||
+		!slices.Equal(c.CORSAPIAllowedOrigins, next.CORSAPIAllowedOrigins) ||
+		c.CORSAPIAllowCredentials != next.CORSAPIAllowCredentials ||
+		!slices.Equal(c.CORSAdminAllowedOrigins, next.CORSAdminAllowedOrigins) ||
+		c.CORSMaxAge != next.CORSMaxAge {
+		log.Printf("🔄 CORS policy changed: origins=%v, api_origins=%v, admin_origins=%v",
+			next.CORSAllowedOrigins, next.CORSAPIAllowedOrigins, next.CORSAdminAllowedOrigins)
+		c.CORSAllowedOrigins = next.CORSAllowedOrigins
+		c.CORSAPIAllowedOrigins = next.CORSAPIAllowedOrigins
+		c.CORSAPIAllowCredentials = next.CORSAPIAllowCredentials
+		c.CORSAdminAllowedOrigins = next.CORSAdminAllowedOrigins
+		c.CORSMaxAge = next.CORSMaxAge
+	}
+
+	if !slices.Equal(c.AppleAppIDs, next.AppleAppIDs) ||
+		c.AndroidAppPackage != next.AndroidAppPackage ||
+		!slices.Equal(c.AndroidCertFingerprints, next.AndroidCertFingerprints) {
+		log.Printf("🔄 App links changed: apple=%v, android=%s", next.AppleAppIDs, next.AndroidAppPackage)
+		c.AppleAppIDs = next.AppleAppIDs
+		c.AndroidAppPackage = next.AndroidAppPackage
+		c.AndroidCertFingerprints = next.AndroidCertFingerprints
+	}
+
+	restartOnly := map[string]bool{
+		"PORT":                         c.Port != next.Port,
+		"GIN_MODE":                     c.GinMode != next.GinMode,
+		"GRPC_PORT":                    c.GRPCPort != next.GRPCPort,
+		"DB_PATH":                      c.DBPath != next.DBPath,
+		"BASE_URL":                     c.BaseURL != next.BaseURL,
+		"LOG_LEVEL":                    c.LogLevel != next.LogLevel,
+		"GEOIP_DB_PATH":                c.GeoIPDBPath != next.GeoIPDBPath,
+		"DB_BACKUP_INTERVAL":           c.DBBackupInterval != next.DBBackupInterval,
+		"HEALTH_CHECK_INTERVAL":        c.HealthCheckInterval != next.HealthCheckInterval,
+		"CLEANUP_INTERVAL":             c.CleanupInterval != next.CleanupInterval,
+		"REQUIRE_API_KEY":              c.RequireAPIKey != next.RequireAPIKey,
+		"IDEMPOTENCY_TTL":              c.IdempotencyTTL != next.IdempotencyTTL,
+		"EVENTS_HEARTBEAT_INTERVAL":    c.EventsHeartbeatInterval != next.EventsHeartbeatInterval,
+		"EVENTS_REPLAY_BUFFER":         c.EventsReplayBuffer != next.EventsReplayBuffer,
+		"LINK_CHECK_INTERVAL":          c.LinkCheckInterval != next.LinkCheckInterval,
+		"LINK_CHECK_CONCURRENCY":       c.LinkCheckConcurrency != next.LinkCheckConcurrency,
+		"LINK_CHECK_TIMEOUT":           c.LinkCheckTimeout != next.LinkCheckTimeout,
+		"LINK_CHECK_HOST_DELAY":        c.LinkCheckHostDelay != next.LinkCheckHostDelay,
+		"LINK_CHECK_FAILURE_THRESHOLD": c.LinkCheckFailureThreshold != next.LinkCheckFailureThreshold,
+		"LINK_CHECK_BLOCK_DEAD":        c.LinkCheckBlockDead != next.LinkCheckBlockDead,
+		"RESOLVE_REDIRECTS":            c.ResolveRedirects != next.ResolveRedirects,
+		"REDIRECT_MAX_HOPS":            c.RedirectMaxHops != next.RedirectMaxHops,
+		"REDIRECT_RESOLVE_TIMEOUT":     c.RedirectResolveTimeout != next.RedirectResolveTimeout,
+		"METADATA_FETCH":               c.MetadataFetch != next.MetadataFetch,
+		"METADATA_WORKERS":             c.MetadataWorkers != next.MetadataWorkers,
+		"METADATA_TIMEOUT":             c.MetadataTimeout != next.MetadataTimeout,
+		"METADATA_MAX_BYTES":           c.MetadataMaxBytes != next.MetadataMaxBytes,
+		"OUTBOUND_ALLOW_PRIVATE":       c.OutboundAllowPrivate != next.OutboundAllowPrivate,
+		"ALIAS_STRATEGY":               c.AliasStrategy != next.AliasStrategy,
+		"ALIAS_LENGTH":                 c.AliasLength != next.AliasLength,
+		"ALIAS_ALPHABET":               c.AliasAlphabet != next.AliasAlphabet,
+		"ALIAS_SALT":                   c.AliasSalt != next.AliasSalt,
+		"ALIAS_GROWTH_THRESHOLD":       c.AliasGrowthThreshold != next.AliasGrowthThreshold,
+		"RESERVED_ALIASES_FILE":        c.ReservedAliasesFile != next.ReservedAliasesFile,
+		"ALIAS_PROFANITY_FILTER":       c.AliasProfanityFilter != next.AliasProfanityFilter,
+		"ALIAS_BLOCKLIST_FILES":        !slices.Equal(c.AliasBlocklistFiles, next.AliasBlocklistFiles),
+		"ALIAS_CASE_INSENSITIVE":       c.AliasCaseInsensitive != next.AliasCaseInsensitive,
+		"ALIAS_CACHE_SIZE":             c.AliasCacheSize != next.AliasCacheSize,
+		"ALIAS_CACHE_TTL":              c.AliasCacheTTL != next.AliasCacheTTL,
+		"CLICK_WRITES":                 c.ClickWrites != next.ClickWrites,
+		"CLICK_FLUSH_INTERVAL":         c.ClickFlushInterval != next.ClickFlushInterval,
+		"ADMIN_USERNAME":               c.AdminUsername != next.AdminUsername,
+		"ADMIN_PASSWORD":               c.AdminPassword != next.AdminPassword,
+		"ADMIN_SESSION_TTL":            c.AdminSessionTTL != next.AdminSessionTTL,
+		// Unset secrets are generated at startup, so only a configured
+		// value can differ from the one in use
+		"ADMIN_SESSION_SECRET": next.AdminSessionSecret != "" && c.AdminSessionSecret != next.AdminSessionSecret,
+		"VISITOR_HASH_SALT":    next.VisitorHashSalt != "" && c.VisitorHashSalt != next.VisitorHashSalt,
+	}
+	for key, changed := range restartOnly {
+		if changed {
+			log.Printf("Warning: %s changed but requires a restart to take effect", key)
+		}
+	}
+}
+
+// watchConfigReload reloads configuration whenever the process receives SIGHUP
+func watchConfigReload(config *Config) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			log.Printf("🔄 SIGHUP received, reloading configuration from %s", config.ConfigFile())
+			if err := config.Reload(); err != nil {
+				log.Printf("Configuration reload rejected, keeping current settings: %v", err)
+				continue
+			}
+			log.Println("✅ Configuration reloaded")
+		}
+	}()
+}
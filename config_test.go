@@ -0,0 +1,127 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chdirTemp runs the rest of a test in a fresh temporary directory, where
+// .env is read from
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReloadRereadsDotEnv checks that .env is layered over the config file
+// on every load without being copied into the environment, so both files
+// can change a setting on reload
+func TestReloadRereadsDotEnv(t *testing.T) {
+	discardLogs(t)
+	t.Setenv("MAX_CLICKS", "")
+	dir := chdirTemp(t)
+	writeTestFile(t, ".env", "MAX_CLICKS=7\n")
+
+	config := newTestConfig(t)
+	configFile := filepath.Join(dir, "config.yaml")
+	writeTestFile(t, configFile, "max_clicks: 3\n")
+	t.Setenv("CONFIG_FILE", configFile)
+
+	if got := config.GetMaxClicks(); got != 7 {
+		t.Fatalf("MaxClicks = %d, want 7 from .env", got)
+	}
+	if value := os.Getenv("MAX_CLICKS"); value != "" {
+		t.Fatalf("loading .env set MAX_CLICKS=%q in the environment", value)
+	}
+
+	steps := []struct {
+		name   string
+		dotenv string
+		want   int
+	}{
+		{"edited .env", "MAX_CLICKS=9\n", 9},
+		{".env over config file", "MAX_CLICKS=9\nLOG_LEVEL=info\n", 9},
+		{"removed from .env", "LOG_LEVEL=info\n", 3},
+	}
+	for _, step := range steps {
+		writeTestFile(t, ".env", step.dotenv)
+		if err := config.Reload(); err != nil {
+			t.Fatalf("%s: Reload: %v", step.name, err)
+		}
+		if got := config.GetMaxClicks(); got != step.want {
+			t.Errorf("%s: MaxClicks = %d, want %d", step.name, got, step.want)
+		}
+	}
+}
+
+func TestConfigFileFromDotEnv(t *testing.T) {
+	discardLogs(t)
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("MAX_CLICKS", "")
+	dir := chdirTemp(t)
+
+	configFile := filepath.Join(dir, "settings.toml")
+	writeTestFile(t, configFile, "max_clicks = 11\n")
+	writeTestFile(t, ".env", "CONFIG_FILE="+configFile+"\n")
+
+	dotenv := readDotEnv()
+	src, err := newConfigSource(configFilePath(dotenv), dotenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := src.getEnvAsInt("MAX_CLICKS", 5); got != 11 {
+		t.Errorf("MAX_CLICKS = %d, want 11 from the config file named in .env", got)
+	}
+}
+
+func TestReloadWarnsAboutSecrets(t *testing.T) {
+	discardLogs(t)
+	t.Setenv("ADMIN_PASSWORD", "secret")
+	t.Setenv("ADMIN_SESSION_SECRET", "")
+	t.Setenv("VISITOR_HASH_SALT", "")
+	config := newTestConfig(t)
+
+	var logs strings.Builder
+	log.SetOutput(&logs)
+
+	// Generated secrets are not reported as changed
+	if err := config.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if strings.Contains(logs.String(), "requires a restart") {
+		t.Errorf("reload without changes warned:\n%s", logs.String())
+	}
+
+	t.Setenv("ADMIN_SESSION_SECRET", "new-session-secret")
+	t.Setenv("VISITOR_HASH_SALT", "new-salt")
+	if err := config.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	for _, key := range []string{"ADMIN_SESSION_SECRET", "VISITOR_HASH_SALT"} {
+		if !strings.Contains(logs.String(), key+" changed but requires a restart") {
+			t.Errorf("no restart warning for %s:\n%s", key, logs.String())
+		}
+	}
+	if config.VisitorHashSalt == "new-salt" {
+		t.Error("VISITOR_HASH_SALT was applied without a restart")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// corsPolicies holds one CORS handler per route group
+type corsPolicies struct {
+	public gin.HandlerFunc // redirects, /shorten, /health and static files
+	api    gin.HandlerFunc // /api, limited to CORS_API_ALLOWED_ORIGINS
+	admin  gin.HandlerFunc // /admin, limited to BASE_URL and CORS_ADMIN_ALLOWED_ORIGINS
+}
+
+// handlerFor picks the policy for a request path. Policies are chosen by path
+// rather than attached to route groups so preflight OPTIONS requests are
+// answered before routing.
+func (p *corsPolicies) handlerFor(path string) gin.HandlerFunc {
+	switch {
+	case path == "/api" || strings.HasPrefix(path, "/api/"):
+		return p.api
+	case path == "/admin" || strings.HasPrefix(path, "/admin/"):
+		return p.admin
+	default:
+		return p.public
+	}
+}
+
+// corsMiddleware applies the configured CORS policy for each route group.
+// Allowed origins are echoed back with "Vary: Origin"; disallowed origins are
+// rejected with 403. Policies are rebuilt when configuration is reloaded.
+func corsMiddleware(config *Config) gin.HandlerFunc {
+	var policies atomic.Pointer[corsPolicies]
+	policies.Store(buildCORSPolicies(config))
+	config.OnReload(func() {
+		policies.Store(buildCORSPolicies(config))
+	})
+
+	return func(c *gin.Context) {
+		// Checked per request so ENABLE_CORS can be hot-reloaded
+		if !config.CORSEnabled() {
+			c.Next()
+			return
+		}
+
+		if c.Request.Method == "OPTIONS" {
+			log.Printf("CORS preflight request from origin: %s", c.GetHeader("Origin"))
+		}
+
+		policies.Load().handlerFor(c.Request.URL.Path)(c)
+	}
+}
+
+// buildCORSPolicies creates the CORS handlers from the current configuration
+func buildCORSPolicies(config *Config) *corsPolicies {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	return &corsPolicies{
+		public: newCORSHandler(cors.Config{
+			AllowOrigins:  config.CORSAllowedOrigins,
+			AllowMethods:  []string{"GET", "HEAD", "POST", "OPTIONS"},
+			AllowHeaders:  []string{"Origin", "Content-Type", "Accept", "Idempotency-Key"},
+			ExposeHeaders: []string{"Content-Length", "Idempotent-Replayed"},
+			MaxAge:        config.CORSMaxAge,
+		}),
+		api: newCORSHandler(cors.Config{
+			AllowOrigins:     config.CORSAPIAllowedOrigins,
+			AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Requested-With", "Idempotency-Key"},
+			ExposeHeaders:    []string{"Content-Length", "Idempotent-Replayed"},
+			AllowCredentials: config.CORSAPIAllowCredentials,
+			MaxAge:           config.CORSMaxAge,
+		}),
+		admin: newCORSHandler(cors.Config{
+			AllowOrigins:     adminCORSOrigins(config),
+			AllowMethods:     []string{"GET", "POST", "OPTIONS"},
+			AllowHeaders:     []string{"Origin", "Content-Type", "Accept"},
+			AllowCredentials: true,
+			MaxAge:           config.CORSMaxAge,
+		}),
+	}
+}
+
+// adminCORSOrigins returns the origins allowed on /admin: the origin of
+// BASE_URL, which differs from the Host header behind some proxies, and
+// CORS_ADMIN_ALLOWED_ORIGINS. The caller must hold config.mu.
+func adminCORSOrigins(config *Config) []string {
+	origins := slices.Clone(config.CORSAdminAllowedOrigins)
+	if baseURL, err := url.Parse(config.BaseURL); err == nil && baseURL.Host != "" {
+		origins = append(origins, baseURL.Scheme+"://"+baseURL.Host)
+	}
+	return origins
+}
+
+// newCORSHandler wraps cors.New, denying every cross-origin request when no
+// origins are configured
+func newCORSHandler(corsConfig cors.Config) gin.HandlerFunc {
+	corsConfig.AllowWildcard = true
+	if len(corsConfig.AllowOrigins) == 0 {
+		corsConfig.AllowOrigins = nil
+		corsConfig.AllowOriginFunc = func(string) bool { return false }
+	}
+	return cors.New(corsConfig)
+}
+
+// validateCORSOrigin checks a configured origin. Valid forms are "*" (only
+// on its own), "https://example.com[:port]" and "https://*.example.com".
+func validateCORSOrigin(origin string, total int) error {
+	if origin == "*" {
+		if total > 1 {
+			return fmt.Errorf("\"*\" cannot be combined with other origins")
+		}
+		return nil
+	}
+
+	scheme, host, found := strings.Cut(origin, "://")
+	if !found || (scheme != "http" && scheme != "https") {
+		return fmt.Errorf("%q must start with http:// or https://", origin)
+	}
+
+	if strings.Count(origin, "*") > 0 {
+		if !strings.HasPrefix(host, "*.") || strings.Count(host, "*") != 1 {
+			return fmt.Errorf("%q: wildcards are only allowed as a leading subdomain, e.g. https://*.example.com", origin)
+		}
+		host = "sub" + host[1:]
+	}
+
+	parsed, err := url.Parse(scheme + "://" + host)
+	if err != nil || parsed.Host == "" || parsed.Path != "" || parsed.RawQuery != "" || parsed.User != nil {
+		return fmt.Errorf("%q is not a valid origin (scheme://host[:port] without a path)", origin)
+	}
+
+	return nil
+}
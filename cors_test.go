@@ -0,0 +1,179 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newCORSTestRouter serves a route in each CORS group behind the CORS
+// middleware, as the server does
+func newCORSTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	discardLogs(t)
+
+	config := newTestConfig(t)
+	router := gin.New()
+	router.Use(corsMiddleware(config))
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/shorten", ok)
+	router.GET("/api/v1/urls", ok)
+	router.POST("/admin/links/:alias", ok)
+	return router
+}
+
+// corsRequest sends a request with an Origin header. Preflights ask for
+// the given method.
+func corsRequest(router *gin.Engine, preflight bool, method, path, origin string) *httptest.ResponseRecorder {
+	requestMethod := method
+	if preflight {
+		requestMethod = http.MethodOptions
+	}
+
+	req := httptest.NewRequest(requestMethod, "http://sho.rt.internal"+path, nil)
+	req.Header.Set("Origin", origin)
+	if preflight {
+		req.Header.Set("Access-Control-Request-Method", method)
+		req.Header.Set("Access-Control-Request-Headers", "content-type")
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSPolicies(t *testing.T) {
+	t.Setenv("BASE_URL", "https://sho.rt")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+	t.Setenv("CORS_API_ALLOWED_ORIGINS", "https://app.example.com,https://*.partner.example")
+	t.Setenv("CORS_ADMIN_ALLOWED_ORIGINS", "https://admin.example.com")
+	router := newCORSTestRouter(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		origin     string
+		wantStatus int
+		wantOrigin string // Access-Control-Allow-Origin, "" when refused
+	}{
+		{"public wildcard", http.MethodPost, "/shorten", "https://anywhere.example", http.StatusNoContent, "*"},
+
+		{"api exact origin", http.MethodGet, "/api/v1/urls", "https://app.example.com", http.StatusNoContent, "https://app.example.com"},
+		{"api wildcard subdomain", http.MethodGet, "/api/v1/urls", "https://eu.partner.example", http.StatusNoContent, "https://eu.partner.example"},
+		{"api wildcard apex refused", http.MethodGet, "/api/v1/urls", "https://partner.example", http.StatusForbidden, ""},
+		{"api other scheme refused", http.MethodGet, "/api/v1/urls", "http://app.example.com", http.StatusForbidden, ""},
+		{"api disallowed", http.MethodGet, "/api/v1/urls", "https://evil.example", http.StatusForbidden, ""},
+
+		{"admin base URL origin", http.MethodPost, "/admin/links/abc", "https://sho.rt", http.StatusNoContent, "https://sho.rt"},
+		{"admin configured origin", http.MethodPost, "/admin/links/abc", "https://admin.example.com", http.StatusNoContent, "https://admin.example.com"},
+		{"admin disallowed", http.MethodPost, "/admin/links/abc", "https://app.example.com", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := corsRequest(router, true, tt.method, tt.path, tt.origin)
+			if w.Code != tt.wantStatus {
+				t.Errorf("preflight status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if tt.wantOrigin != "" && tt.wantOrigin != "*" && w.Header().Get("Vary") == "" {
+				t.Error("echoed origin is missing Vary")
+			}
+
+			// The actual request is treated the same way
+			w = corsRequest(router, false, tt.method, tt.path, tt.origin)
+			wantStatus := http.StatusOK
+			if tt.wantOrigin == "" {
+				wantStatus = http.StatusForbidden
+			}
+			if w.Code != wantStatus {
+				t.Errorf("%s status = %d, want %d", tt.method, w.Code, wantStatus)
+			}
+		})
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	t.Setenv("BASE_URL", "https://sho.rt")
+	t.Setenv("CORS_API_ALLOWED_ORIGINS", "https://app.example.com")
+	t.Setenv("CORS_API_ALLOW_CREDENTIALS", "true")
+	router := newCORSTestRouter(t)
+
+	for _, tt := range []struct {
+		method, path, origin string
+		want                 string
+	}{
+		{http.MethodPost, "/shorten", "https://app.example.com", ""},
+		{http.MethodGet, "/api/v1/urls", "https://app.example.com", "true"},
+		{http.MethodPost, "/admin/links/abc", "https://sho.rt", "true"},
+	} {
+		w := corsRequest(router, true, tt.method, tt.path, tt.origin)
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.want {
+			t.Errorf("%s: Access-Control-Allow-Credentials = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCORSDefaults(t *testing.T) {
+	t.Setenv("BASE_URL", "https://sho.rt")
+	router := newCORSTestRouter(t)
+
+	// No API origins are allowed by default
+	if w := corsRequest(router, true, http.MethodGet, "/api/v1/urls", "https://app.example.com"); w.Code != http.StatusForbidden {
+		t.Errorf("api preflight status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	// The dashboard works from BASE_URL even when a proxy rewrites Host
+	if w := corsRequest(router, false, http.MethodPost, "/admin/links/abc", "https://sho.rt"); w.Code != http.StatusOK {
+		t.Errorf("admin post from BASE_URL status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	// Same-origin requests pass every policy
+	for _, path := range []string{"/api/v1/urls", "/admin/links/abc"} {
+		method := http.MethodGet
+		if path != "/api/v1/urls" {
+			method = http.MethodPost
+		}
+		if w := corsRequest(router, false, method, path, "http://sho.rt.internal"); w.Code != http.StatusOK {
+			t.Errorf("same-origin %s status = %d, want %d", path, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestCORSDisabled(t *testing.T) {
+	t.Setenv("ENABLE_CORS", "false")
+	router := newCORSTestRouter(t)
+
+	w := corsRequest(router, false, http.MethodGet, "/api/v1/urls", "https://evil.example")
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("with CORS disabled: status %d, Access-Control-Allow-Origin %q", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestValidateCORSOrigin(t *testing.T) {
+	valid := []string{"https://example.com", "http://localhost:3000", "https://*.example.com"}
+	for _, origin := range valid {
+		if err := validateCORSOrigin(origin, 1); err != nil {
+			t.Errorf("validateCORSOrigin(%q): %v", origin, err)
+		}
+	}
+	if err := validateCORSOrigin("*", 1); err != nil {
+		t.Errorf("validateCORSOrigin(\"*\") alone: %v", err)
+	}
+
+	invalid := []string{"example.com", "ftp://example.com", "https://example.com/path", "https://*example.com", "https://a.*.example.com"}
+	for _, origin := range invalid {
+		if err := validateCORSOrigin(origin, 1); err == nil {
+			t.Errorf("validateCORSOrigin(%q) accepted an invalid origin", origin)
+		}
+	}
+	if err := validateCORSOrigin("*", 2); err == nil {
+		t.Error("validateCORSOrigin accepted \"*\" combined with other origins")
+	}
+}
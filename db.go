@@ -2,29 +2,133 @@ package main
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 )
 
-// SaveURL saves a URL to the database
-func SaveURL(config *Config, urlData URLData) error {
+// parseDBTime parses a timestamp column scanned into a string. The driver
+// returns RFC 3339 for values it recognises as times and the raw SQLite
+// format otherwise.
+func parseDBTime(value string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05"} {
+		if parsedTime, err := time.Parse(layout, value); err == nil {
+			return parsedTime
+		}
+	}
+	return time.Time{}
+}
+
+// encodeRules serializes redirect rules for the rules column, storing NULL
+// for links without rules
+func encodeRules(rules []RedirectRule) (interface{}, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rules: %v", err)
+	}
+	return string(encoded), nil
+}
+
+// decodeRules parses the rules column
+func decodeRules(value string) ([]RedirectRule, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var rules []RedirectRule
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode rules: %v", err)
+	}
+
+	// A stored timezone this system cannot load leaves the window in UTC
+	for _, rule := range rules {
+		if rule.TimeWindow != nil {
+			rule.TimeWindow.loadLocation()
+		}
+	}
+	return rules, nil
+}
+
+// SaveURL saves a URL to the database. A URL without an alias gets one from
+// the configured alias strategy, which is set on urlData along with its
+// short URL.
+func SaveURL(config *Config, urlData *URLData) error {
 	if db := config.GetDB(); db != nil {
 		query := `
-		INSERT INTO urls (alias, original_url, short_url, clicks, max_clicks, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO urls (alias, alias_skeleton, original_url, short_url, clicks, max_clicks, created_at, bot_response,
+			og_title, og_description, og_image, rules, app_ios_url, app_android_url, app_fallback_url, final_url, api_key_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, 0))
 		`
 
-		_, err := db.Exec(query,
+		rules, err := encodeRules(urlData.Rules)
+		if err != nil {
+			return err
+		}
+
+		var deepLink DeepLink
+		if urlData.DeepLink != nil {
+			deepLink = *urlData.DeepLink
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		generated := urlData.Alias == ""
+		if generated {
+			urlData.Alias = pendingAlias()
+		}
+
+		result, err := tx.Exec(query,
 			urlData.Alias,
+			aliasSkeleton(urlData.Alias, true),
 			urlData.URL,
 			urlData.ShortURL,
 			urlData.Clicks,
 			urlData.MaxClicks,
 			urlData.CreatedAt,
+			urlData.BotResponse,
+			urlData.OGTitle,
+			urlData.OGDescription,
+			urlData.OGImage,
+			rules,
+			deepLink.IOSURL,
+			deepLink.AndroidURL,
+			deepLink.FallbackURL,
+			urlData.FinalURL,
+			urlData.apiKeyID,
 		)
 
 		if err != nil {
-			return fmt.Errorf("failed to save URL: %v", err)
+			return fmt.Errorf("failed to save URL: %w", err) // Wrapped so callers can detect a taken alias
+		}
+
+		if generated {
+			id, err := result.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("failed to get URL id: %v", err)
+			}
+			if err := assignGeneratedAlias(tx, config, urlData, id); err != nil {
+				return err
+			}
+		}
+
+		for i, destination := range urlData.Destinations {
+			_, err := tx.Exec("INSERT INTO url_destinations (alias, position, url, weight) VALUES (?, ?, ?, ?)",
+				urlData.Alias, i, destination.URL, destination.Weight)
+			if err != nil {
+				return fmt.Errorf("failed to save destination: %v", err)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit URL: %v", err)
 		}
 
 		return nil
@@ -32,27 +136,96 @@ func SaveURL(config *Config, urlData URLData) error {
 	return fmt.Errorf("database connection not available")
 }
 
-// GetURLByAlias retrieves a URL by its alias
-func GetURLByAlias(config *Config, alias string) (*URLData, error) {
-	if db := config.GetDB(); db != nil {
-		query := `
-		SELECT alias, original_url, short_url, clicks, max_clicks, created_at
-		FROM urls
-		WHERE alias = ?
-		`
+// urlColumns is the column list of the urls table read by scanURL
+const urlColumns = `
+	alias, original_url, short_url, clicks, COALESCE(hits, 0), COALESCE(bot_hits, 0),
+	COALESCE(bot_response, 'redirect'), max_clicks, created_at,
+	COALESCE(og_title, ''), COALESCE(og_description, ''), COALESCE(og_image, ''),
+	COALESCE(rules, ''),
+	COALESCE(app_ios_url, ''), COALESCE(app_android_url, ''), COALESCE(app_fallback_url, ''),
+	COALESCE(health_status, 0), COALESCE(health_error, ''), COALESCE(health_checked_at, ''),
+	COALESCE(health_failures, 0),
+	COALESCE(meta_title, ''), COALESCE(meta_description, ''), COALESCE(favicon_url, ''),
+	COALESCE(metadata_error, ''), COALESCE(metadata_fetched_at, ''),
+	COALESCE(final_url, ''), COALESCE(api_key_id, 0)`
 
-		var urlData URLData
-		var createdAt string
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-		err := db.QueryRow(query, alias).Scan(
-			&urlData.Alias,
-			&urlData.URL,
-			&urlData.ShortURL,
-			&urlData.Clicks,
-			&urlData.MaxClicks,
-			&createdAt,
-		)
+// scanURL reads a row selected with urlColumns. Scan errors, including
+// sql.ErrNoRows, are returned unwrapped.
+func scanURL(row rowScanner) (*URLData, error) {
+	var urlData URLData
+	var createdAt, rules, healthCheckedAt, metadataFetchedAt string
+	var deepLink DeepLink
 
+	err := row.Scan(
+		&urlData.Alias,
+		&urlData.URL,
+		&urlData.ShortURL,
+		&urlData.Clicks,
+		&urlData.Hits,
+		&urlData.BotHits,
+		&urlData.BotResponse,
+		&urlData.MaxClicks,
+		&createdAt,
+		&urlData.OGTitle,
+		&urlData.OGDescription,
+		&urlData.OGImage,
+		&rules,
+		&deepLink.IOSURL,
+		&deepLink.AndroidURL,
+		&deepLink.FallbackURL,
+		&urlData.HealthStatus,
+		&urlData.HealthError,
+		&healthCheckedAt,
+		&urlData.HealthFailures,
+		&urlData.Title,
+		&urlData.Description,
+		&urlData.FaviconURL,
+		&urlData.MetadataError,
+		&metadataFetchedAt,
+		&urlData.FinalURL,
+		&urlData.apiKeyID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse created_at timestamp
+	urlData.CreatedAt = parseDBTime(createdAt)
+
+	// Set OriginalURL for compatibility
+	urlData.OriginalURL = urlData.URL
+
+	if urlData.Rules, err = decodeRules(rules); err != nil {
+		return nil, err
+	}
+
+	if deepLink != (DeepLink{}) {
+		urlData.DeepLink = &deepLink
+	}
+
+	if healthCheckedAt != "" {
+		checkedAt := parseDBTime(healthCheckedAt)
+		urlData.HealthCheckedAt = &checkedAt
+	}
+	urlData.Health = linkHealth(&urlData)
+
+	if metadataFetchedAt != "" {
+		fetchedAt := parseDBTime(metadataFetchedAt)
+		urlData.MetadataFetchedAt = &fetchedAt
+	}
+
+	return &urlData, nil
+}
+
+// GetURLByAlias retrieves a URL by its alias
+func GetURLByAlias(config *Config, alias string) (*URLData, error) {
+	if db := config.GetDB(); db != nil {
+		urlData, err := scanURL(db.QueryRow("SELECT "+urlColumns+" FROM urls WHERE alias = ?", alias))
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return nil, nil // URL not found
@@ -60,101 +233,193 @@ func GetURLByAlias(config *Config, alias string) (*URLData, error) {
 			return nil, fmt.Errorf("failed to get URL: %v", err)
 		}
 
-		// Parse created_at timestamp
-		if parsedTime, err := time.Parse("2006-01-02 15:04:05", createdAt); err == nil {
-			urlData.CreatedAt = parsedTime
+		if urlData.Destinations, err = getDestinations(db, alias); err != nil {
+			return nil, err
 		}
 
-		// Set OriginalURL for compatibility
-		urlData.OriginalURL = urlData.URL
+		// Include clicks that are still buffered in memory
+		config.clickBuffer.overlay(urlData)
 
-		return &urlData, nil
+		return urlData, nil
 	}
 	return nil, fmt.Errorf("database connection not available")
 }
 
-// IncrementURLClicks increments the click count for a URL and returns the new count
-func IncrementURLClicks(config *Config, alias string) (int, error) {
-	if db := config.GetDB(); db == nil {
-		return 0, fmt.Errorf("database connection not available")
+// GetURLOwner returns the ID of the API key that created a URL, 0 for
+// links created without one. found is false if no URL exists for the alias.
+func GetURLOwner(config *Config, alias string) (apiKeyID int64, found bool, err error) {
+	if db := config.GetDB(); db != nil {
+		err := db.QueryRow("SELECT COALESCE(api_key_id, 0) FROM urls WHERE alias = ?", alias).Scan(&apiKeyID)
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to get URL owner: %v", err)
+		}
+		return apiKeyID, true, nil
 	}
+	return 0, false, fmt.Errorf("database connection not available")
+}
 
-	db := config.GetDB()
+// AliasTaken reports whether an alias is in use, ignoring case when
+// ALIAS_CASE_INSENSITIVE is set
+func AliasTaken(config *Config, alias string) (bool, error) {
+	if db := config.GetDB(); db != nil {
+		query := "SELECT 1 FROM urls WHERE alias = ?"
+		if config.AliasCaseInsensitive {
+			query += " COLLATE NOCASE"
+		}
 
-	// Use a transaction to ensure atomicity
-	tx, err := db.Begin()
-	if err != nil {
-		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+		var found int
+		err := db.QueryRow(query+" LIMIT 1", alias).Scan(&found)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to check alias: %v", err)
+		}
+		return true, nil
 	}
-	defer tx.Rollback()
+	return false, fmt.Errorf("database connection not available")
+}
 
-	// First, get current click count and max clicks
-	var currentClicks, maxClicks int
-	selectQuery := `SELECT clicks, max_clicks FROM urls WHERE alias = ?`
-	err = tx.QueryRow(selectQuery, alias).Scan(&currentClicks, &maxClicks)
-	if err != nil {
+// FindDuplicateURL returns an active link to originalURL with the given
+// click limit and no rules, destinations, deep link or Open Graph overrides,
+// or nil if there is none. With an alias prefix, only links whose alias
+// starts with it are considered.
+func FindDuplicateURL(config *Config, originalURL string, maxClicks int, aliasPrefix string) (*URLData, error) {
+	if db := config.GetDB(); db != nil {
+		query := `
+		SELECT alias FROM urls
+		WHERE original_url = ? AND max_clicks = ? AND clicks < max_clicks
+			AND substr(alias, 1, length(?)) = ?
+			AND COALESCE(bot_response, 'redirect') = 'redirect' AND rules IS NULL
+			AND COALESCE(og_title, '') = '' AND COALESCE(og_description, '') = '' AND COALESCE(og_image, '') = ''
+			AND app_ios_url IS NULL AND app_android_url IS NULL AND app_fallback_url IS NULL
+			AND NOT EXISTS (SELECT 1 FROM url_destinations WHERE url_destinations.alias = urls.alias)
+		ORDER BY created_at DESC
+		LIMIT 1
+		`
+
+		var alias string
+		err := db.QueryRow(query, originalURL, maxClicks, aliasPrefix, aliasPrefix).Scan(&alias)
 		if err == sql.ErrNoRows {
-			return 0, fmt.Errorf("URL with alias %s not found", alias)
+			return nil, nil
 		}
-		return 0, fmt.Errorf("failed to get current clicks: %v", err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find duplicate URL: %v", err)
+		}
+
+		// Buffered clicks may have used up the link since they were written
+		urlData, err := GetURLByAlias(config, alias)
+		if err != nil || urlData == nil || urlData.Clicks >= urlData.MaxClicks {
+			return nil, err
+		}
+		return urlData, nil
 	}
+	return nil, fmt.Errorf("database connection not available")
+}
 
-	// Check if already at max clicks
-	if currentClicks >= maxClicks {
-		return currentClicks, fmt.Errorf("URL has already reached maximum clicks (%d/%d)", currentClicks, maxClicks)
+// ConfusableAlias returns an existing alias that looks like alias, such as
+// "pаypal" with a Cyrillic "а" for "paypal", or "" if there is none
+func ConfusableAlias(config *Config, alias string) (string, error) {
+	if db := config.GetDB(); db != nil {
+		rows, err := db.Query("SELECT alias FROM urls WHERE alias_skeleton = ?", aliasSkeleton(alias, true))
+		if err != nil {
+			return "", fmt.Errorf("failed to check alias: %v", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var existing string
+			if err := rows.Scan(&existing); err != nil {
+				return "", fmt.Errorf("failed to check alias: %v", err)
+			}
+			if confusableAliases(config, alias, existing) {
+				return existing, nil
+			}
+		}
+		return "", rows.Err()
 	}
+	return "", fmt.Errorf("database connection not available")
+}
 
-	// Atomically increment click count only if under the limit
-	updateQuery := `UPDATE urls SET clicks = clicks + 1 WHERE alias = ? AND clicks < max_clicks`
-	result, err := tx.Exec(updateQuery, alias)
-	if err != nil {
-		return 0, fmt.Errorf("failed to update clicks: %v", err)
+// GetCachedURL retrieves a URL by its alias through the alias cache. The
+// returned URL may be shared and must not be modified.
+func GetCachedURL(config *Config, alias string) (*URLData, error) {
+	if urlData, found := config.cache.get(alias); found {
+		return urlData, nil
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	urlData, err := GetURLByAlias(config, alias)
+	if err != nil || urlData == nil {
+		return urlData, err // Missing aliases are not cached
+	}
+
+	config.cache.put(urlData)
+	return urlData, nil
+}
+
+// getDestinations loads the A/B destinations of a URL in order
+func getDestinations(db *sql.DB, alias string) ([]Destination, error) {
+	rows, err := db.Query("SELECT url, weight, clicks FROM url_destinations WHERE alias = ? ORDER BY position", alias)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get affected rows: %v", err)
+		return nil, fmt.Errorf("failed to get destinations: %v", err)
 	}
+	defer rows.Close()
 
-	if rowsAffected == 0 {
-		// Re-check current state
-		var recheckClicks, recheckMaxClicks int
-		recheckQuery := `SELECT clicks, max_clicks FROM urls WHERE alias = ?`
-		err = tx.QueryRow(recheckQuery, alias).Scan(&recheckClicks, &recheckMaxClicks)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				return 0, fmt.Errorf("URL with alias %s not found", alias)
-			}
-			return 0, fmt.Errorf("failed to recheck clicks: %v", err)
+	var destinations []Destination
+	for rows.Next() {
+		var destination Destination
+		if err := rows.Scan(&destination.URL, &destination.Weight, &destination.Clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan destination: %v", err)
 		}
+		destinations = append(destinations, destination)
+	}
 
-		if recheckClicks >= recheckMaxClicks {
-			return recheckClicks, fmt.Errorf("URL has already reached maximum clicks (%d/%d)", recheckClicks, recheckMaxClicks)
-		}
+	return destinations, rows.Err()
+}
 
-		return 0, fmt.Errorf("failed to increment clicks - no rows affected")
+// IncrementURLClicks increments the click count for a URL and returns the new
+// count. The limit check and the increment happen in a single statement, or
+// in memory when clicks are buffered.
+func IncrementURLClicks(config *Config, alias string) (int, error) {
+	if config.clickBuffer != nil {
+		return config.clickBuffer.increment(alias)
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	db := config.GetDB()
+	if db == nil {
+		return 0, fmt.Errorf("database connection not available")
+	}
+
+	var newClicks, maxClicks int
+	query := `UPDATE urls SET clicks = clicks + 1 WHERE alias = ? AND clicks < max_clicks RETURNING clicks, max_clicks`
+	err := db.QueryRow(query, alias).Scan(&newClicks, &maxClicks)
+	if err == nil {
+		return newClicks, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to update clicks: %v", err)
+	}
+
+	// Nothing was updated, find out why
+	var currentClicks int
+	err = db.QueryRow("SELECT clicks, max_clicks FROM urls WHERE alias = ?", alias).Scan(&currentClicks, &maxClicks)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("URL with alias %s not found", alias)
+		}
+		return 0, fmt.Errorf("failed to get current clicks: %v", err)
 	}
 
-	// Return new click count
-	newClickCount := currentClicks + 1
-	return newClickCount, nil
+	return currentClicks, fmt.Errorf("URL has already reached maximum clicks (%d/%d)", currentClicks, maxClicks)
 }
 
 // GetAllURLs returns all stored URLs
 func GetAllURLs(config *Config) ([]URLData, error) {
 	if db := config.GetDB(); db != nil {
-		query := `
-		SELECT alias, original_url, short_url, clicks, max_clicks, created_at
-		FROM urls
-		ORDER BY created_at DESC
-		`
-
-		rows, err := db.Query(query)
+		rows, err := db.Query("SELECT " + urlColumns + " FROM urls ORDER BY created_at DESC")
 		if err != nil {
 			return nil, fmt.Errorf("failed to get URLs: %v", err)
 		}
@@ -162,57 +427,165 @@ func GetAllURLs(config *Config) ([]URLData, error) {
 
 		var urls []URLData
 		for rows.Next() {
-			var urlData URLData
-			var createdAt string
-
-			err := rows.Scan(
-				&urlData.Alias,
-				&urlData.URL,
-				&urlData.ShortURL,
-				&urlData.Clicks,
-				&urlData.MaxClicks,
-				&createdAt,
-			)
-
+			urlData, err := scanURL(rows)
 			if err != nil {
 				return nil, fmt.Errorf("failed to scan URL: %v", err)
 			}
 
-			// Parse created_at timestamp
-			if parsedTime, err := time.Parse("2006-01-02 15:04:05", createdAt); err == nil {
-				urlData.CreatedAt = parsedTime
+			// Include clicks that are still buffered in memory
+			config.clickBuffer.overlay(urlData)
+
+			urls = append(urls, *urlData)
+		}
+
+		return urls, nil
+	}
+	return nil, fmt.Errorf("database connection not available")
+}
+
+// GetLinkCheckTargets returns the active links whose destination was not
+// checked within the given age, least recently checked first
+func GetLinkCheckTargets(config *Config, age time.Duration) ([]linkCheckTarget, error) {
+	if db := config.GetDB(); db != nil {
+		query := `
+		SELECT alias, original_url FROM urls
+		WHERE clicks < max_clicks
+		AND (health_checked_at IS NULL OR health_checked_at < datetime('now', ?))
+		ORDER BY health_checked_at IS NOT NULL, health_checked_at
+		`
+
+		modifier := fmt.Sprintf("-%d seconds", int64(age.Seconds()))
+		rows, err := db.Query(query, modifier)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get links to check: %v", err)
+		}
+		defer rows.Close()
+
+		var targets []linkCheckTarget
+		for rows.Next() {
+			var target linkCheckTarget
+			if err := rows.Scan(&target.alias, &target.url); err != nil {
+				return nil, fmt.Errorf("failed to scan link to check: %v", err)
 			}
+			targets = append(targets, target)
+		}
+		return targets, rows.Err()
+	}
+	return nil, fmt.Errorf("database connection not available")
+}
 
-			// Set OriginalURL for compatibility
-			urlData.OriginalURL = urlData.URL
+// GetMetadataTargets returns up to limit active links whose destination
+// metadata has not been fetched, oldest first
+func GetMetadataTargets(config *Config, limit int) ([]metadataTarget, error) {
+	if db := config.GetDB(); db != nil {
+		query := `
+		SELECT alias, original_url FROM urls
+		WHERE metadata_fetched_at IS NULL AND clicks < max_clicks
+		ORDER BY id
+		LIMIT ?
+		`
 
-			urls = append(urls, urlData)
+		rows, err := db.Query(query, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get links without metadata: %v", err)
 		}
+		defer rows.Close()
 
-		return urls, nil
+		var targets []metadataTarget
+		for rows.Next() {
+			var target metadataTarget
+			if err := rows.Scan(&target.alias, &target.url); err != nil {
+				return nil, fmt.Errorf("failed to scan link without metadata: %v", err)
+			}
+			targets = append(targets, target)
+		}
+		return targets, rows.Err()
 	}
 	return nil, fmt.Errorf("database connection not available")
 }
 
+// SaveMetadata stores the metadata fetched for a link. It is not stored
+// when the destination was changed in the meantime, so the new destination
+// is fetched instead.
+func SaveMetadata(config *Config, alias, originalURL string, meta pageMetadata) error {
+	if db := config.GetDB(); db != nil {
+		query := `
+		UPDATE urls SET
+			meta_title = NULLIF(?, ''),
+			meta_description = NULLIF(?, ''),
+			favicon_url = NULLIF(?, ''),
+			metadata_error = NULLIF(?, ''),
+			metadata_fetched_at = CURRENT_TIMESTAMP
+		WHERE alias = ? AND original_url = ?
+		`
+
+		if _, err := db.Exec(query, meta.title, meta.description, meta.favicon, meta.err, alias, originalURL); err != nil {
+			return fmt.Errorf("failed to save metadata: %v", err)
+		}
+
+		config.cache.invalidate(alias)
+		return nil
+	}
+	return fmt.Errorf("database connection not available")
+}
+
+// SaveLinkCheck stores the outcome of a destination check. Failed checks
+// in a row are counted, a successful check resets the count.
+func SaveLinkCheck(config *Config, alias string, result linkCheckResult) error {
+	if db := config.GetDB(); db != nil {
+		query := `
+		UPDATE urls SET
+			health_status = NULLIF(?, 0),
+			health_error = NULLIF(?, ''),
+			health_checked_at = CURRENT_TIMESTAMP,
+			health_failures = CASE WHEN ? THEN COALESCE(health_failures, 0) + 1 ELSE 0 END
+		WHERE alias = ?
+		`
+
+		if _, err := db.Exec(query, result.status, result.err, result.broken(), alias); err != nil {
+			return fmt.Errorf("failed to save link check: %v", err)
+		}
+
+		// Redirects read the link's health from the cache
+		config.cache.invalidate(alias)
+		return nil
+	}
+	return fmt.Errorf("database connection not available")
+}
+
 // CleanupExpiredURLs removes URLs that have exceeded their click limit
 func CleanupExpiredURLs(config *Config) (int, error) {
 	if db := config.GetDB(); db != nil {
+		// Write buffered clicks first so links that just expired are included
+		config.clickBuffer.flush()
+
 		query := `
 		DELETE FROM urls
 		WHERE clicks >= max_clicks
+		RETURNING alias
 		`
 
-		result, err := db.Exec(query)
+		rows, err := db.Query(query)
 		if err != nil {
 			return 0, fmt.Errorf("failed to cleanup expired URLs: %v", err)
 		}
+		defer rows.Close()
 
-		affected, err := result.RowsAffected()
-		if err != nil {
-			return 0, fmt.Errorf("failed to get affected rows: %v", err)
+		var deleted []string
+		for rows.Next() {
+			var alias string
+			if err := rows.Scan(&alias); err != nil {
+				return 0, fmt.Errorf("failed to scan deleted alias: %v", err)
+			}
+			deleted = append(deleted, alias)
+		}
+		if err := rows.Err(); err != nil {
+			return 0, fmt.Errorf("failed to cleanup expired URLs: %v", err)
 		}
 
-		return int(affected), nil
+		config.clickBuffer.forget(deleted...)
+		config.cache.invalidate(deleted...)
+		return len(deleted), nil
 	}
 	return 0, fmt.Errorf("database connection not available")
 }
@@ -238,6 +611,18 @@ func GetStats(config *Config) (*StatsResponse, error) {
 		return nil, fmt.Errorf("failed to get total clicks: %v", err)
 	}
 
+	// Get raw hits, including repeat visitors that were not counted
+	err = db.QueryRow("SELECT COALESCE(SUM(hits), 0) FROM urls").Scan(&stats.TotalHits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total hits: %v", err)
+	}
+
+	// Get bot hits, which are kept out of the totals
+	err = db.QueryRow("SELECT COALESCE(SUM(bot_hits), 0) FROM urls").Scan(&stats.TotalBotHits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total bot hits: %v", err)
+	}
+
 	// Get active URLs (clicks < max_clicks)
 	err = db.QueryRow("SELECT COUNT(*) FROM urls WHERE clicks < max_clicks").Scan(&stats.ActiveURLs)
 	if err != nil {
@@ -252,3 +637,443 @@ func GetStats(config *Config) (*StatsResponse, error) {
 
 	return stats, nil
 }
+
+// UpdateURL updates the destination and click limit of an existing URL.
+// An empty finalURL keeps the recorded final URL of an unchanged
+// destination. A new destination has its metadata fetched again. It
+// returns false if no URL exists for the alias.
+func UpdateURL(config *Config, alias, originalURL, finalURL string, maxClicks int) (bool, error) {
+	if db := config.GetDB(); db != nil {
+		query := `
+		UPDATE urls
+		SET original_url = ?, max_clicks = ?,
+			final_url = COALESCE(NULLIF(?, ''), CASE WHEN original_url = ? THEN final_url END),
+			metadata_fetched_at = CASE WHEN original_url = ? THEN metadata_fetched_at END
+		WHERE alias = ?
+		`
+
+		result, err := db.Exec(query, originalURL, maxClicks, finalURL, originalURL, originalURL, alias)
+		if err != nil {
+			return false, fmt.Errorf("failed to update URL: %v", err)
+		}
+		config.clickBuffer.forget(alias)
+		config.cache.invalidate(alias)
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to get affected rows: %v", err)
+		}
+
+		return affected > 0, nil
+	}
+	return false, fmt.Errorf("database connection not available")
+}
+
+// UpdateURLRules replaces the redirect rules of a URL.
+// It returns false if no URL exists for the alias.
+func UpdateURLRules(config *Config, alias string, rules []RedirectRule) (bool, error) {
+	if db := config.GetDB(); db != nil {
+		encoded, err := encodeRules(rules)
+		if err != nil {
+			return false, err
+		}
+
+		result, err := db.Exec("UPDATE urls SET rules = ? WHERE alias = ?", encoded, alias)
+		if err != nil {
+			return false, fmt.Errorf("failed to update rules: %v", err)
+		}
+		config.cache.invalidate(alias)
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to get affected rows: %v", err)
+		}
+
+		return affected > 0, nil
+	}
+	return false, fmt.Errorf("database connection not available")
+}
+
+// DeleteURL removes a URL and its click history.
+// It returns false if no URL exists for the alias.
+func DeleteURL(config *Config, alias string) (bool, error) {
+	if db := config.GetDB(); db != nil {
+		result, err := db.Exec("DELETE FROM urls WHERE alias = ?", alias)
+		if err != nil {
+			return false, fmt.Errorf("failed to delete URL: %v", err)
+		}
+		config.clickBuffer.forget(alias)
+		config.cache.invalidate(alias)
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to get affected rows: %v", err)
+		}
+
+		return affected > 0, nil
+	}
+	return false, fmt.Errorf("database connection not available")
+}
+
+// RecordClickEvent stores a single hit for analytics and bumps the URL's raw
+// hit counter, or its bot hit counter for bots, and the clicks of the chosen
+// A/B variant. Hits that did not count toward max_clicks are stored too. In
+// buffered mode the hit is written with the next flush.
+func RecordClickEvent(config *Config, event ClickEvent) error {
+	if config.clickBuffer != nil {
+		config.clickBuffer.record(event)
+		return nil
+	}
+
+	if db := config.GetDB(); db != nil {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		if err := writeClickEvents(tx, []ClickEvent{event}); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	return fmt.Errorf("database connection not available")
+}
+
+// writeClickEvents stores hits and updates the hit, bot hit and A/B
+// variant counters they add up to. Hits on links deleted in the meantime
+// are skipped.
+func writeClickEvents(tx *sql.Tx, events []ClickEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.Prepare(`
+	INSERT INTO click_events (alias, visitor_hash, user_agent, referrer, counted, is_bot, rule, clicked_at)
+	SELECT ?, ?, ?, ?, ?, ?, NULLIF(?, ''), COALESCE(?, CURRENT_TIMESTAMP)
+	WHERE EXISTS (SELECT 1 FROM urls WHERE alias = ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare click event insert: %v", err)
+	}
+	defer stmt.Close()
+
+	type variantKey struct {
+		alias    string
+		position int
+	}
+	hits := make(map[string]int)
+	botHits := make(map[string]int)
+	variantClicks := make(map[variantKey]int)
+
+	for _, event := range events {
+		var clickedAt interface{}
+		if !event.ClickedAt.IsZero() {
+			clickedAt = event.ClickedAt.UTC().Format("2006-01-02 15:04:05")
+		}
+		_, err := stmt.Exec(event.Alias, event.VisitorHash, event.UserAgent, event.Referrer,
+			event.Counted, event.Bot, event.Rule, clickedAt, event.Alias)
+		if err != nil {
+			return fmt.Errorf("failed to record click event: %v", err)
+		}
+
+		if event.Bot {
+			botHits[event.Alias]++
+		} else {
+			hits[event.Alias]++
+		}
+
+		// Credit the A/B variant that received a counted click
+		if event.Counted && event.Variant > 0 {
+			variantClicks[variantKey{event.Alias, event.Variant - 1}]++
+		}
+	}
+
+	for alias, count := range hits {
+		if _, err := tx.Exec("UPDATE urls SET hits = COALESCE(hits, 0) + ? WHERE alias = ?", count, alias); err != nil {
+			return fmt.Errorf("failed to update hits: %v", err)
+		}
+	}
+	for alias, count := range botHits {
+		if _, err := tx.Exec("UPDATE urls SET bot_hits = COALESCE(bot_hits, 0) + ? WHERE alias = ?", count, alias); err != nil {
+			return fmt.Errorf("failed to update bot hits: %v", err)
+		}
+	}
+	for key, count := range variantClicks {
+		_, err := tx.Exec("UPDATE url_destinations SET clicks = clicks + ? WHERE alias = ? AND position = ?",
+			count, key.alias, key.position)
+		if err != nil {
+			return fmt.Errorf("failed to update destination clicks: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// HasRecentCountedVisit reports whether a visitor already had a counted
+// click on an alias within the given window
+func HasRecentCountedVisit(config *Config, alias, visitorHash string, window time.Duration) (bool, error) {
+	if db := config.GetDB(); db != nil {
+		query := `
+		SELECT EXISTS (
+			SELECT 1 FROM click_events
+			WHERE alias = ? AND visitor_hash = ? AND counted = 1
+			AND clicked_at >= datetime('now', ?)
+		)
+		`
+
+		var exists bool
+		modifier := fmt.Sprintf("-%d seconds", int64(window.Seconds()))
+		if err := db.QueryRow(query, alias, visitorHash, modifier).Scan(&exists); err != nil {
+			return false, fmt.Errorf("failed to check recent visits: %v", err)
+		}
+
+		return exists, nil
+	}
+	return false, fmt.Errorf("database connection not available")
+}
+
+// GetRuleHits returns the number of counted clicks per matched redirect rule.
+// Clicks recorded before rules existed are not included.
+func GetRuleHits(config *Config, alias string) (map[string]int, error) {
+	if db := config.GetDB(); db != nil {
+		query := `
+		SELECT rule, COUNT(*)
+		FROM click_events
+		WHERE alias = ? AND counted = 1 AND rule IS NOT NULL
+		GROUP BY rule
+		`
+
+		rows, err := db.Query(query, alias)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rule hits: %v", err)
+		}
+		defer rows.Close()
+
+		hits := make(map[string]int)
+		for rows.Next() {
+			var rule string
+			var count int
+			if err := rows.Scan(&rule, &count); err != nil {
+				return nil, fmt.Errorf("failed to scan rule hits: %v", err)
+			}
+			hits[rule] = count
+		}
+
+		return hits, rows.Err()
+	}
+	return nil, fmt.Errorf("database connection not available")
+}
+
+// GetClickTimeline returns daily click counts for an alias over the last
+// number of days, oldest first, including days without clicks
+func GetClickTimeline(config *Config, alias string, days int) ([]ClickBucket, error) {
+	if db := config.GetDB(); db == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	db := config.GetDB()
+	query := `
+	SELECT date(clicked_at) AS day, COUNT(*)
+	FROM click_events
+	WHERE alias = ? AND COALESCE(counted, 1) = 1 AND clicked_at >= datetime('now', ?)
+	GROUP BY day
+	`
+
+	rows, err := db.Query(query, alias, fmt.Sprintf("-%d days", days))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click timeline: %v", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var day string
+		var count int
+		if err := rows.Scan(&day, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan click timeline: %v", err)
+		}
+		counts[day] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read click timeline: %v", err)
+	}
+
+	// Fill in every day of the window so charts have a continuous axis
+	timeline := make([]ClickBucket, 0, days)
+	today := time.Now().UTC()
+	for i := days - 1; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i).Format("2006-01-02")
+		timeline = append(timeline, ClickBucket{Day: day, Clicks: counts[day]})
+	}
+
+	return timeline, nil
+}
+
+// SaveAPIKey stores a new API key by its hash and returns the stored record
+func SaveAPIKey(config *Config, name, prefix, keyHash, aliasPrefix string) (*APIKey, error) {
+	if db := config.GetDB(); db != nil {
+		query := `
+		INSERT INTO api_keys (name, prefix, key_hash, created_at, alias_prefix)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''))
+		`
+
+		createdAt := time.Now().UTC()
+		result, err := db.Exec(query, name, prefix, keyHash, createdAt, aliasPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save API key: %v", err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get API key id: %v", err)
+		}
+
+		return &APIKey{ID: id, Name: name, Prefix: prefix, AliasPrefix: aliasPrefix, CreatedAt: createdAt}, nil
+	}
+	return nil, fmt.Errorf("database connection not available")
+}
+
+// GetAPIKeyByHash looks up an API key by its hash and records its use.
+// It returns nil if no key matches.
+func GetAPIKeyByHash(config *Config, keyHash string) (*APIKey, error) {
+	if db := config.GetDB(); db != nil {
+		var apiKey APIKey
+		err := db.QueryRow("SELECT id, name, prefix, COALESCE(alias_prefix, '') FROM api_keys WHERE key_hash = ?", keyHash).
+			Scan(&apiKey.ID, &apiKey.Name, &apiKey.Prefix, &apiKey.AliasPrefix)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, nil // Key not found
+			}
+			return nil, fmt.Errorf("failed to get API key: %v", err)
+		}
+
+		if _, err := db.Exec("UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP WHERE id = ?", apiKey.ID); err != nil {
+			return nil, fmt.Errorf("failed to update API key usage: %v", err)
+		}
+
+		return &apiKey, nil
+	}
+	return nil, fmt.Errorf("database connection not available")
+}
+
+// GetAllAPIKeys returns all stored API keys, newest first
+func GetAllAPIKeys(config *Config) ([]APIKey, error) {
+	if db := config.GetDB(); db != nil {
+		rows, err := db.Query("SELECT id, name, prefix, COALESCE(alias_prefix, ''), created_at, last_used_at FROM api_keys ORDER BY id DESC")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get API keys: %v", err)
+		}
+		defer rows.Close()
+
+		var keys []APIKey
+		for rows.Next() {
+			var apiKey APIKey
+			var lastUsedAt sql.NullTime
+
+			if err := rows.Scan(&apiKey.ID, &apiKey.Name, &apiKey.Prefix, &apiKey.AliasPrefix, &apiKey.CreatedAt, &lastUsedAt); err != nil {
+				return nil, fmt.Errorf("failed to scan API key: %v", err)
+			}
+
+			if lastUsedAt.Valid {
+				apiKey.LastUsedAt = &lastUsedAt.Time
+			}
+
+			keys = append(keys, apiKey)
+		}
+
+		return keys, nil
+	}
+	return nil, fmt.Errorf("database connection not available")
+}
+
+// DeleteAPIKey revokes an API key by id. It returns false if no key exists.
+func DeleteAPIKey(config *Config, id int64) (bool, error) {
+	if db := config.GetDB(); db != nil {
+		result, err := db.Exec("DELETE FROM api_keys WHERE id = ?", id)
+		if err != nil {
+			return false, fmt.Errorf("failed to delete API key: %v", err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to get affected rows: %v", err)
+		}
+
+		return affected > 0, nil
+	}
+	return false, fmt.Errorf("database connection not available")
+}
+
+// ClaimIdempotencyKey marks a request with an idempotency key as started,
+// after removing keys older than ttl. It returns nil when the key was free
+// and is now claimed, or what is stored for the key otherwise.
+func ClaimIdempotencyKey(config *Config, scope, key, requestHash string, ttl time.Duration) (*idempotentResponse, error) {
+	if db := config.GetDB(); db != nil {
+		expired := fmt.Sprintf("-%d seconds", int(ttl.Seconds()))
+		if _, err := db.Exec("DELETE FROM idempotency_keys WHERE created_at < datetime('now', ?)", expired); err != nil {
+			return nil, fmt.Errorf("failed to remove expired idempotency keys: %v", err)
+		}
+
+		result, err := db.Exec(`
+		INSERT INTO idempotency_keys (scope, key, request_hash) VALUES (?, ?, ?)
+		ON CONFLICT (scope, key) DO NOTHING
+		`, scope, key, requestHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim idempotency key: %v", err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get affected rows: %v", err)
+		}
+		if affected > 0 {
+			return nil, nil
+		}
+
+		var stored idempotentResponse
+		var status sql.NullInt64
+		var contentType sql.NullString
+		err = db.QueryRow("SELECT request_hash, status, content_type, body FROM idempotency_keys WHERE scope = ? AND key = ?", scope, key).
+			Scan(&stored.requestHash, &status, &contentType, &stored.body)
+		if err == sql.ErrNoRows {
+			// Released by a failed first request since the insert; the
+			// client retries as if that request were still running
+			return &idempotentResponse{requestHash: requestHash}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get idempotency key: %v", err)
+		}
+
+		stored.status = int(status.Int64)
+		stored.contentType = contentType.String
+		return &stored, nil
+	}
+	return nil, fmt.Errorf("database connection not available")
+}
+
+// SaveIdempotentResponse stores the response to the request that claimed
+// an idempotency key, for its retries to replay
+func SaveIdempotentResponse(config *Config, scope, key string, status int, contentType string, body []byte) error {
+	if db := config.GetDB(); db != nil {
+		_, err := db.Exec("UPDATE idempotency_keys SET status = ?, content_type = ?, body = ? WHERE scope = ? AND key = ?",
+			status, contentType, body, scope, key)
+		if err != nil {
+			return fmt.Errorf("failed to save idempotent response: %v", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("database connection not available")
+}
+
+// ReleaseIdempotencyKey frees a claimed idempotency key whose request
+// failed, so it can be retried
+func ReleaseIdempotencyKey(config *Config, scope, key string) error {
+	if db := config.GetDB(); db != nil {
+		if _, err := db.Exec("DELETE FROM idempotency_keys WHERE scope = ? AND key = ? AND status IS NULL", scope, key); err != nil {
+			return fmt.Errorf("failed to release idempotency key: %v", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("database connection not available")
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deepLinkFallbackDelay is how long the interstitial page waits for the app
+// to open before loading the fallback page, in milliseconds
+const deepLinkFallbackDelay = 1500
+
+// Formats of the app identifiers served under /.well-known
+var (
+	appleAppIDPattern      = regexp.MustCompile(`^[A-Z0-9]{10}\.[A-Za-z0-9.-]+$`)
+	androidPackagePattern  = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(\.[A-Za-z][A-Za-z0-9_]*)+$`)
+	certFingerprintPattern = regexp.MustCompile(`^([0-9A-Fa-f]{2}:){31}[0-9A-Fa-f]{2}$`)
+)
+
+// blockedAppSchemes can run code in the browser and are never valid app links
+var blockedAppSchemes = []string{"javascript", "data", "vbscript", "file", "about", "blob"}
+
+// validateDeepLink checks a link's deep link settings and returns them
+// trimmed, or nil when none are set
+func validateDeepLink(config *Config, deepLink *DeepLink) (*DeepLink, error) {
+	if deepLink == nil {
+		return nil, nil
+	}
+
+	normalized := DeepLink{
+		IOSURL:      strings.TrimSpace(deepLink.IOSURL),
+		AndroidURL:  strings.TrimSpace(deepLink.AndroidURL),
+		FallbackURL: strings.TrimSpace(deepLink.FallbackURL),
+	}
+	if normalized == (DeepLink{}) {
+		return nil, nil
+	}
+
+	if normalized.IOSURL == "" && normalized.AndroidURL == "" {
+		return nil, fmt.Errorf("deep_link needs an ios_url or android_url")
+	}
+
+	if err := validateAppURL(normalized.IOSURL); err != nil {
+		return nil, fmt.Errorf("ios_url: %v", err)
+	}
+	if err := validateAppURL(normalized.AndroidURL); err != nil {
+		return nil, fmt.Errorf("android_url: %v", err)
+	}
+
+	if normalized.FallbackURL != "" {
+		sanitized, err := sanitizeURL(config, normalized.FallbackURL)
+		if err != nil {
+			return nil, fmt.Errorf("fallback_url: %v", err)
+		}
+		normalized.FallbackURL = sanitized
+	}
+
+	return &normalized, nil
+}
+
+// validateAppURL checks a custom scheme or intent URI
+func validateAppURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	if len(raw) > 2048 {
+		return fmt.Errorf("must be less than 2048 characters")
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URI: %v", err)
+	}
+	if parsed.Scheme == "" {
+		return fmt.Errorf("%q needs a scheme such as myapp://", raw)
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	for _, blocked := range blockedAppSchemes {
+		if scheme == blocked {
+			return fmt.Errorf("the %s: scheme is not allowed", scheme)
+		}
+	}
+
+	return nil
+}
+
+// appURLFor returns the deep link for the visitor's platform, or "" when the
+// link has none for it
+func appURLFor(deepLink *DeepLink, userAgent string) string {
+	if deepLink == nil {
+		return ""
+	}
+
+	switch _, os := parseDevice(userAgent); os {
+	case "ios":
+		return deepLink.IOSURL
+	case "android":
+		return deepLink.AndroidURL
+	}
+	return ""
+}
+
+// sendVisitor takes a visitor to their destination. On iOS and Android, links
+// with a deep link for the platform get a page that opens the app and falls
+// back to the web; everyone else is redirected.
+func sendVisitor(c *gin.Context, urlData *URLData, destination string) {
+	appURL := appURLFor(urlData.DeepLink, c.Request.UserAgent())
+	if appURL == "" {
+		redirectTo(c, urlData.Alias, destination)
+		return
+	}
+
+	fallback := urlData.DeepLink.FallbackURL
+	if fallback == "" {
+		fallback = destination
+	}
+
+	log.Printf("Opening app for %s: %s (fallback %s)", urlData.Alias, appURL, fallback)
+
+	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+	c.Header("Pragma", "no-cache")
+	c.Header("Expires", "0")
+	c.HTML(http.StatusOK, "deeplink.html", gin.H{
+		// The scheme was validated on creation, so it is safe in an href
+		"appURL":   template.URL(appURL),
+		"fallback": fallback,
+		"delay":    deepLinkFallbackDelay,
+	})
+}
+
+// appleAppSiteAssociationHandler serves the file that lets iOS open short
+// links directly in the configured apps (Universal Links)
+func appleAppSiteAssociationHandler(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		appLinks := config.GetAppLinks()
+		if len(appLinks.AppleAppIDs) == 0 {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:     "Not configured",
+				Message:   "Set APPLE_APP_IDS to enable Universal Links",
+				Code:      errCodeAppLinksNotConfigured,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		// Short links live at the root, so everything except the app's own
+		// routes is claimed. Both the current and the legacy format are sent.
+		components := []gin.H{
+			{"/": "/api/*", "exclude": true},
+			{"/": "/admin*", "exclude": true},
+			{"/": "/static/*", "exclude": true},
+			{"/": "/health", "exclude": true},
+			{"/": "/shorten", "exclude": true},
+			{"/": "/.well-known/*", "exclude": true},
+			{"/": "/?*"},
+		}
+		paths := []string{"NOT /api/*", "NOT /admin*", "NOT /static/*", "NOT /health", "NOT /shorten", "NOT /.well-known/*", "/?*"}
+
+		details := make([]gin.H, 0, len(appLinks.AppleAppIDs))
+		for _, appID := range appLinks.AppleAppIDs {
+			details = append(details, gin.H{
+				"appID":      appID,
+				"appIDs":     []string{appID},
+				"paths":      paths,
+				"components": components,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"applinks": gin.H{
+				"apps":    []string{},
+				"details": details,
+			},
+		})
+	}
+}
+
+// assetLinksHandler serves the Digital Asset Links file that lets Android
+// open short links directly in the configured app (App Links)
+func assetLinksHandler(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		appLinks := config.GetAppLinks()
+		if appLinks.AndroidPackage == "" {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:     "Not configured",
+				Message:   "Set ANDROID_APP_PACKAGE to enable App Links",
+				Code:      errCodeAppLinksNotConfigured,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, []gin.H{{
+			"relation": []string{"delegate_permission/common.handle_all_urls"},
+			"target": gin.H{
+				"namespace":                "android_app",
+				"package_name":             appLinks.AndroidPackage,
+				"sha256_cert_fingerprints": appLinks.AndroidCertFingerprints,
+			},
+		}})
+	}
+}
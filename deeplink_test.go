@@ -0,0 +1,177 @@
+package main
+
+import (
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// User agents of common platforms
+const (
+	iPhoneUA   = "Mozilla/5.0 (iPhone; CPU iPhone OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Mobile/15E148 Safari/604.1"
+	iPadUA     = "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+	androidUA  = "Mozilla/5.0 (Linux; Android 15; Pixel 9) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Mobile Safari/537.36"
+	tabletUA   = "Mozilla/5.0 (Linux; Android 14; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"
+	macUA      = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15"
+	windowsUA  = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"
+	linuxUA    = "Mozilla/5.0 (X11; Linux x86_64; rv:140.0) Gecko/20100101 Firefox/140.0"
+	chromeOSUA = "Mozilla/5.0 (X11; CrOS x86_64 15633.69.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"
+)
+
+func TestParseDevice(t *testing.T) {
+	tests := []struct {
+		name       string
+		userAgent  string
+		wantDevice string
+		wantOS     string
+	}{
+		{"iPhone", iPhoneUA, "mobile", "ios"},
+		{"iPad", iPadUA, "tablet", "ios"},
+		{"Android phone", androidUA, "mobile", "android"},
+		{"Android tablet", tabletUA, "tablet", "android"},
+		{"Mac", macUA, "desktop", "macos"},
+		{"Windows", windowsUA, "desktop", "windows"},
+		{"Linux", linuxUA, "desktop", "linux"},
+		{"ChromeOS", chromeOSUA, "desktop", "chromeos"},
+		{"empty", "", "desktop", ""},
+		{"curl", "curl/8.5.0", "desktop", ""},
+	}
+
+	for _, tt := range tests {
+		device, os := parseDevice(tt.userAgent)
+		if device != tt.wantDevice || os != tt.wantOS {
+			t.Errorf("%s: parseDevice = %q, %q, want %q, %q", tt.name, device, os, tt.wantDevice, tt.wantOS)
+		}
+	}
+}
+
+func TestAppURLFor(t *testing.T) {
+	both := &DeepLink{IOSURL: "myapp://item/42", AndroidURL: "intent://item/42#Intent;scheme=myapp;package=com.example.app;end"}
+	iosOnly := &DeepLink{IOSURL: "myapp://item/42"}
+
+	tests := []struct {
+		name      string
+		deepLink  *DeepLink
+		userAgent string
+		want      string
+	}{
+		{"iPhone", both, iPhoneUA, both.IOSURL},
+		{"iPad", both, iPadUA, both.IOSURL},
+		{"Android", both, androidUA, both.AndroidURL},
+		{"Android tablet", both, tabletUA, both.AndroidURL},
+		{"Mac", both, macUA, ""},
+		{"Windows", both, windowsUA, ""},
+		{"Android without an Android URL", iosOnly, androidUA, ""},
+		{"no deep link", nil, iPhoneUA, ""},
+	}
+
+	for _, tt := range tests {
+		if got := appURLFor(tt.deepLink, tt.userAgent); got != tt.want {
+			t.Errorf("%s: appURLFor = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateDeepLink(t *testing.T) {
+	discardLogs(t)
+	config := newTestConfig(t)
+
+	tests := []struct {
+		name     string
+		deepLink *DeepLink
+		want     *DeepLink
+		wantErr  bool
+	}{
+		{"none", nil, nil, false},
+		{"empty", &DeepLink{IOSURL: " "}, nil, false},
+		{"trimmed", &DeepLink{IOSURL: " myapp://a "}, &DeepLink{IOSURL: "myapp://a"}, false},
+		{"intent URI", &DeepLink{AndroidURL: "intent://a#Intent;scheme=myapp;end"}, &DeepLink{AndroidURL: "intent://a#Intent;scheme=myapp;end"}, false},
+		{"fallback canonicalized", &DeepLink{IOSURL: "myapp://a", FallbackURL: "Example.com/app"}, &DeepLink{IOSURL: "myapp://a", FallbackURL: "http://example.com/app"}, false},
+		{"fallback only", &DeepLink{FallbackURL: "https://example.com/"}, nil, true},
+		{"no scheme", &DeepLink{IOSURL: "item/42"}, nil, true},
+		{"javascript", &DeepLink{IOSURL: "javascript:alert(1)"}, nil, true},
+		{"data", &DeepLink{AndroidURL: "DATA:text/html,hi"}, nil, true},
+		{"too long", &DeepLink{IOSURL: "myapp://" + strings.Repeat("a", 2048)}, nil, true},
+		{"invalid fallback", &DeepLink{IOSURL: "myapp://a", FallbackURL: "ftp://example.com/"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := validateDeepLink(config, tt.deepLink)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateDeepLink error = %v, want error %t", tt.name, err, tt.wantErr)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("%s: validateDeepLink = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDeepLinkInterstitial(t *testing.T) {
+	discardLogs(t)
+	config := newTestConfig(t)
+	router := newRedirectTestRouter(config)
+
+	save := func(alias string, deepLink *DeepLink) {
+		urlData := &URLData{
+			Alias:       alias,
+			URL:         "https://example.com/item/42",
+			OriginalURL: "https://example.com/item/42",
+			MaxClicks:   1000,
+			CreatedAt:   time.Now(),
+			DeepLink:    deepLink,
+		}
+		if err := SaveURL(config, urlData); err != nil {
+			t.Fatalf("SaveURL(%s): %v", alias, err)
+		}
+	}
+	save("with-fallback", &DeepLink{IOSURL: "myapp://item/42", FallbackURL: "https://example.com/get-the-app"})
+	save("ios-only", &DeepLink{IOSURL: "myapp://item/42"})
+
+	tests := []struct {
+		name         string
+		alias        string
+		userAgent    string
+		wantApp      bool
+		wantFallback string // Interstitial fallback, or the redirect target
+	}{
+		{"iPhone with fallback", "with-fallback", iPhoneUA, true, "https://example.com/get-the-app"},
+		{"iPhone falls back to the destination", "ios-only", iPhoneUA, true, "https://example.com/item/42"},
+		{"Android without an Android URL", "ios-only", androidUA, false, "https://example.com/item/42"},
+		{"desktop", "with-fallback", macUA, false, "https://example.com/item/42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+tt.alias, nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			req.Header.Set("Accept-Language", "en")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if !tt.wantApp {
+				if w.Code != http.StatusFound || w.Header().Get("Location") != tt.wantFallback {
+					t.Errorf("got %d to %q, want a redirect to %q", w.Code, w.Header().Get("Location"), tt.wantFallback)
+				}
+				return
+			}
+
+			body := html.UnescapeString(w.Body.String())
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want the interstitial page", w.Code)
+			}
+			if !strings.Contains(body, `href="myapp://item/42"`) {
+				t.Errorf("page does not link to the app:\n%s", body)
+			}
+			if !strings.Contains(body, `href="`+tt.wantFallback+`"`) {
+				t.Errorf("page does not fall back to %s:\n%s", tt.wantFallback, body)
+			}
+			if w.Header().Get("Cache-Control") == "" {
+				t.Error("interstitial page may be cached")
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxDestinations limits the number of A/B variants on a single link
+	maxDestinations = 10
+
+	// maxDestinationWeight keeps weights readable as percentages or ratios
+	maxDestinationWeight = 1000
+
+	// variantCookieMaxAge keeps visitors on the same variant for 30 days
+	variantCookieMaxAge = 30 * 24 * 60 * 60
+)
+
+// validateDestinations checks A/B destinations and returns them with
+// sanitized URLs and default weights filled in
+func validateDestinations(config *Config, destinations []Destination) ([]Destination, error) {
+	if len(destinations) == 0 {
+		return nil, nil
+	}
+	if len(destinations) < 2 {
+		return nil, fmt.Errorf("an A/B split needs at least 2 destinations")
+	}
+	if len(destinations) > maxDestinations {
+		return nil, fmt.Errorf("a link can have at most %d destinations", maxDestinations)
+	}
+
+	normalized := make([]Destination, 0, len(destinations))
+	for i, destination := range destinations {
+		sanitized, err := sanitizeURL(config, destination.URL)
+		if err != nil {
+			return nil, fmt.Errorf("destination %d: %v", i+1, err)
+		}
+
+		weight := destination.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		if weight < 0 || weight > maxDestinationWeight {
+			return nil, fmt.Errorf("destination %d: weight must be between 1 and %d", i+1, maxDestinationWeight)
+		}
+
+		normalized = append(normalized, Destination{URL: sanitized, Weight: weight})
+	}
+
+	return normalized, nil
+}
+
+// variantCookieName is the cookie holding a visitor's variant for an alias.
+// Aliases may contain characters that are not allowed in cookie names, so
+// the name is derived from a hash of the alias.
+func variantCookieName(alias string) string {
+	sum := sha256.Sum256([]byte(alias))
+	return "ab_" + base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(sum[:10])
+}
+
+// assignVariant returns the 1-based variant for a visitor. Returning
+// visitors keep the variant stored in their cookie; new visitors are
+// assigned by weight and get a cookie scoped to the short link.
+func assignVariant(c *gin.Context, urlData *URLData) int {
+	if value, err := c.Cookie(variantCookieName(urlData.Alias)); err == nil {
+		if variant, err := strconv.Atoi(value); err == nil && variant >= 1 && variant <= len(urlData.Destinations) {
+			return variant
+		}
+	}
+
+	variant := pickWeighted(urlData.Destinations)
+
+	// Browsers match the path as sent, with non-ASCII characters escaped
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(variantCookieName(urlData.Alias), strconv.Itoa(variant), variantCookieMaxAge, "/"+url.PathEscape(urlData.Alias), "", false, true)
+
+	return variant
+}
+
+// pickWeighted chooses a 1-based variant with probability proportional to
+// its weight
+func pickWeighted(destinations []Destination) int {
+	total := 0
+	for _, destination := range destinations {
+		total += destination.Weight
+	}
+
+	n := rand.IntN(total)
+	for i, destination := range destinations {
+		if n < destination.Weight {
+			return i + 1
+		}
+		n -= destination.Weight
+	}
+
+	return len(destinations)
+}
+
+// buildDestinationInfo reports each variant's target and actual share of
+// traffic
+func buildDestinationInfo(destinations []Destination) []DestinationInfo {
+	totalWeight, totalClicks := 0, 0
+	for _, destination := range destinations {
+		totalWeight += destination.Weight
+		totalClicks += destination.Clicks
+	}
+
+	info := make([]DestinationInfo, 0, len(destinations))
+	for i, destination := range destinations {
+		item := DestinationInfo{
+			Variant: i + 1,
+			URL:     destination.URL,
+			Weight:  destination.Weight,
+			Clicks:  destination.Clicks,
+		}
+		if totalWeight > 0 {
+			item.WeightShare = float64(destination.Weight) / float64(totalWeight) * 100
+		}
+		if totalClicks > 0 {
+			item.ClickShare = float64(destination.Clicks) / float64(totalClicks) * 100
+		}
+		info = append(info, item)
+	}
+
+	return info
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAssignVariantCookie(t *testing.T) {
+	for _, alias := range []string{"promo", "тест-аб", "東京タワー"} {
+		t.Run(alias, func(t *testing.T) {
+			urlData := &URLData{
+				Alias: alias,
+				Destinations: []Destination{
+					{URL: "https://a.example.com", Weight: 1},
+					{URL: "https://b.example.com", Weight: 1},
+				},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			variant := assignVariant(c, urlData)
+
+			cookies := w.Result().Cookies()
+			if len(cookies) != 1 {
+				t.Fatalf("got %d cookies, want 1 (Set-Cookie: %q)", len(cookies), w.Header().Values("Set-Cookie"))
+			}
+			cookie := cookies[0]
+			if err := cookie.Valid(); err != nil {
+				t.Errorf("invalid cookie: %v", err)
+			}
+
+			// The path must match the request path a browser sends
+			shortLink := httptest.NewRequest(http.MethodGet, "http://localhost/"+alias, nil)
+			if cookie.Path != shortLink.URL.EscapedPath() {
+				t.Errorf("cookie path = %q, want %q", cookie.Path, shortLink.URL.EscapedPath())
+			}
+
+			// A returning visitor keeps the variant
+			for i := 0; i < 10; i++ {
+				w := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(w)
+				c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+				c.Request.AddCookie(cookie)
+				if got := assignVariant(c, urlData); got != variant {
+					t.Fatalf("returning visitor got variant %d, want %d", got, variant)
+				}
+				if len(w.Result().Cookies()) != 0 {
+					t.Fatal("returning visitor got a new cookie")
+				}
+			}
+		})
+	}
+}
+
+func TestAssignVariantIgnoresUnhashedCookie(t *testing.T) {
+	urlData := &URLData{
+		Alias: "promo",
+		Destinations: []Destination{
+			{URL: "https://a.example.com", Weight: 1},
+			{URL: "https://b.example.com", Weight: 0},
+		},
+	}
+
+	// Only the hashed cookie name is read, so the unhashed one cannot pin
+	// the visitor to a variant
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/promo", nil)
+	c.Request.AddCookie(&http.Cookie{Name: "ab_promo", Value: "2"})
+	if got := assignVariant(c, urlData); got != 1 {
+		t.Errorf("variant = %d, want 1 from the weights", got)
+	}
+	if len(w.Result().Cookies()) != 1 {
+		t.Error("visitor with only the unhashed cookie did not get a new one")
+	}
+}
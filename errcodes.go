@@ -0,0 +1,87 @@
+package main
+
+import "net/http"
+
+// Machine-readable error codes sent in ErrorResponse.Code. Clients match on
+// them, so a code is never renamed or given a different meaning; new
+// failures get new codes.
+const (
+	errCodeInvalidRequest        = "INVALID_REQUEST"
+	errCodeInvalidURL            = "INVALID_URL"
+	errCodeURLTooLong            = "URL_TOO_LONG"
+	errCodeInvalidRedirectChain  = "INVALID_REDIRECT_CHAIN"
+	errCodeInvalidBotResponse    = "INVALID_BOT_RESPONSE"
+	errCodeInvalidOpenGraph      = "INVALID_OPEN_GRAPH"
+	errCodeInvalidRules          = "INVALID_RULES"
+	errCodeInvalidDeepLink       = "INVALID_DEEP_LINK"
+	errCodeInvalidDestinations   = "INVALID_DESTINATIONS"
+	errCodeInvalidAliasStyle     = "INVALID_ALIAS_STYLE"
+	errCodeInvalidAlias          = "INVALID_ALIAS"
+	errCodeMissingAlias          = "MISSING_ALIAS"
+	errCodeInvalidHealthFilter   = "INVALID_HEALTH_FILTER"
+	errCodeInvalidIdempotencyKey = "INVALID_IDEMPOTENCY_KEY"
+	errCodeInvalidEventFilter    = "INVALID_EVENT_FILTER"
+	errCodeAPIKeyRequired        = "API_KEY_REQUIRED"
+	errCodeInvalidAPIKey         = "INVALID_API_KEY"
+	errCodeAliasPrefixRequired   = "ALIAS_PREFIX_REQUIRED"
+	errCodeNotLinkOwner          = "NOT_LINK_OWNER"
+	errCodeURLNotFound           = "URL_NOT_FOUND"
+	errCodeEndpointNotFound      = "ENDPOINT_NOT_FOUND"
+	errCodeAppLinksNotConfigured = "APP_LINKS_NOT_CONFIGURED"
+	errCodeAliasTaken            = "ALIAS_TAKEN"
+	errCodeAliasTooSimilar       = "ALIAS_TOO_SIMILAR"
+	errCodeIdempotencyKeyInUse   = "IDEMPOTENCY_KEY_IN_USE"
+	errCodeIdempotencyKeyReused  = "IDEMPOTENCY_KEY_REUSED"
+	errCodeRequestTooLarge       = "REQUEST_TOO_LARGE"
+	errCodeAliasGenerationFailed = "ALIAS_GENERATION_FAILED"
+	errCodeDatabaseError         = "DATABASE_ERROR"
+	errCodeStatsError            = "STATS_ERROR"
+	errCodeRetrievalError        = "RETRIEVAL_ERROR"
+	errCodeCleanupError          = "CLEANUP_ERROR"
+	errCodeInternalError         = "INTERNAL_ERROR"
+)
+
+// errorCode documents one error code
+type errorCode struct {
+	code        string
+	status      int
+	description string
+}
+
+// errorCodes is the catalog of every error code the API returns, published
+// in the OpenAPI spec
+var errorCodes = []errorCode{
+	{errCodeInvalidRequest, http.StatusBadRequest, "The body is not valid JSON or lacks a required field"},
+	{errCodeInvalidURL, http.StatusBadRequest, "The destination is not a valid http(s) URL or points back to this server"},
+	{errCodeURLTooLong, http.StatusBadRequest, "The destination is longer than 2048 characters"},
+	{errCodeInvalidRedirectChain, http.StatusBadRequest, "The destination redirects in a loop or too many times"},
+	{errCodeInvalidBotResponse, http.StatusBadRequest, "bot_response is not redirect or metadata"},
+	{errCodeInvalidOpenGraph, http.StatusBadRequest, "An Open Graph override is too long or not a valid image URL"},
+	{errCodeInvalidRules, http.StatusBadRequest, "A redirect rule is invalid"},
+	{errCodeInvalidDeepLink, http.StatusBadRequest, "A deep link URL is invalid"},
+	{errCodeInvalidDestinations, http.StatusBadRequest, "An A/B destination or weight is invalid"},
+	{errCodeInvalidAliasStyle, http.StatusBadRequest, "alias_style names no configured alias strategy"},
+	{errCodeInvalidAlias, http.StatusBadRequest, "The custom alias is malformed, reserved or not allowed by the alias policy"},
+	{errCodeMissingAlias, http.StatusBadRequest, "The request has no alias"},
+	{errCodeInvalidHealthFilter, http.StatusBadRequest, "health is not ok, broken or unchecked"},
+	{errCodeInvalidIdempotencyKey, http.StatusBadRequest, "The Idempotency-Key header is too long or not printable ASCII"},
+	{errCodeInvalidEventFilter, http.StatusBadRequest, "An event stream filter names an unknown event type or an invalid owner"},
+	{errCodeAPIKeyRequired, http.StatusUnauthorized, "The request has no API key, and REQUIRE_API_KEY is set or the route is an event stream"},
+	{errCodeInvalidAPIKey, http.StatusUnauthorized, "The API key is unknown or revoked"},
+	{errCodeAliasPrefixRequired, http.StatusForbidden, "The custom alias does not start with the API key's alias prefix"},
+	{errCodeNotLinkOwner, http.StatusForbidden, "The short URL was not created with the request's API key"},
+	{errCodeURLNotFound, http.StatusNotFound, "No short URL has the alias"},
+	{errCodeEndpointNotFound, http.StatusNotFound, "No route matches the method and path"},
+	{errCodeAppLinksNotConfigured, http.StatusNotFound, "Universal Links or App Links are not configured"},
+	{errCodeAliasTaken, http.StatusConflict, "The custom alias is already in use"},
+	{errCodeAliasTooSimilar, http.StatusConflict, "The custom alias looks like an existing alias"},
+	{errCodeIdempotencyKeyInUse, http.StatusConflict, "A request with the same Idempotency-Key is still running; retry later"},
+	{errCodeRequestTooLarge, http.StatusRequestEntityTooLarge, "The body of a request with an Idempotency-Key is larger than 64 KiB"},
+	{errCodeIdempotencyKeyReused, http.StatusUnprocessableEntity, "The Idempotency-Key was used for a request with a different route or body"},
+	{errCodeAliasGenerationFailed, http.StatusInternalServerError, "No free alias could be generated; retry or pick a custom alias"},
+	{errCodeDatabaseError, http.StatusInternalServerError, "The database could not be read or written"},
+	{errCodeStatsError, http.StatusInternalServerError, "Statistics could not be computed"},
+	{errCodeRetrievalError, http.StatusInternalServerError, "Short URLs could not be listed"},
+	{errCodeCleanupError, http.StatusInternalServerError, "Expired short URLs could not be deleted"},
+	{errCodeInternalError, http.StatusInternalServerError, "An unexpected server error"},
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// Types of link events
+const (
+	eventLinkCreated = "link.created"
+	eventLinkClicked = "link.clicked"
+	eventLinkExpired = "link.expired"
+)
+
+var eventTypes = []string{eventLinkCreated, eventLinkClicked, eventLinkExpired}
+
+const (
+	// eventSubscriberBuffer is how many events a subscriber may fall behind
+	// before it is disconnected
+	eventSubscriberBuffer = 256
+
+	// eventsRetry is how long browsers wait before reconnecting a stream
+	eventsRetry = 3 * time.Second
+)
+
+// eventHub fans link events out to the subscribers of /api/v1/events and
+// keeps the most recent ones, so reconnecting clients can catch up
+type eventHub struct {
+	mu          sync.Mutex
+	lastID      uint64
+	recent      []LinkEvent // Oldest first, at most size
+	size        int
+	subscribers map[*eventSubscriber]struct{}
+	closed      bool
+}
+
+// eventSubscriber is one open event stream
+type eventSubscriber struct {
+	filter eventFilter
+	events chan LinkEvent // Closed when the hub drops the subscriber
+}
+
+// eventFilter selects the events a subscriber receives. Empty fields
+// match every event.
+type eventFilter struct {
+	types   map[string]bool
+	aliases map[string]bool
+	owner   int64
+}
+
+// matches reports whether an event passes the filter
+func (f eventFilter) matches(event LinkEvent) bool {
+	return (len(f.types) == 0 || f.types[event.Type]) &&
+		(len(f.aliases) == 0 || f.aliases[event.Alias]) &&
+		(f.owner == 0 || f.owner == event.Owner)
+}
+
+// startEventHub creates the hub publishing link events to subscribers
+func startEventHub(config *Config) {
+	config.events = &eventHub{
+		size:        config.EventsReplayBuffer,
+		subscribers: make(map[*eventSubscriber]struct{}),
+	}
+}
+
+// stopEventHub ends all event streams. It runs when the server starts
+// shutting down, since open streams would otherwise hold the shutdown up.
+func stopEventHub(config *Config) {
+	config.events.close()
+}
+
+// publish numbers an event and sends it to every matching subscriber.
+// Subscribers too far behind are disconnected rather than slowing down
+// redirects; they can reconnect with Last-Event-ID. A nil hub, as in the
+// CLI, drops the event.
+func (h *eventHub) publish(event LinkEvent) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+
+	h.lastID++
+	event.ID = h.lastID
+	event.Timestamp = time.Now()
+
+	if h.size > 0 {
+		if len(h.recent) >= h.size {
+			h.recent = h.recent[len(h.recent)-h.size+1:]
+		}
+		h.recent = append(h.recent, event)
+	}
+
+	for sub := range h.subscribers {
+		if !sub.filter.matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			log.Printf("Warning: event stream fell %d events behind, disconnecting it", eventSubscriberBuffer)
+			delete(h.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// subscribe registers a subscriber. With resume, it also returns the
+// buffered events after lastID, so a reconnecting client misses nothing
+// that is still buffered. A lastID ahead of the hub is from before a
+// restart, and all buffered events are returned. It returns nil when the
+// hub is shutting down.
+func (h *eventHub) subscribe(filter eventFilter, lastID uint64, resume bool) (*eventSubscriber, []LinkEvent) {
+	if h == nil {
+		return nil, nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, nil
+	}
+
+	var backlog []LinkEvent
+	if resume {
+		if lastID > h.lastID {
+			lastID = 0
+		}
+		if len(h.recent) > 0 && h.recent[0].ID > lastID+1 {
+			log.Printf("Warning: events %d to %d are no longer buffered for a reconnecting stream", lastID+1, h.recent[0].ID-1)
+		}
+		for _, event := range h.recent {
+			if event.ID > lastID && filter.matches(event) {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	sub := &eventSubscriber{filter: filter, events: make(chan LinkEvent, eventSubscriberBuffer)}
+	h.subscribers[sub] = struct{}{}
+	return sub, backlog
+}
+
+// unsubscribe removes a subscriber, unless the hub already dropped it
+func (h *eventHub) unsubscribe(sub *eventSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, found := h.subscribers[sub]; found {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// close disconnects every subscriber and refuses new ones
+func (h *eventHub) close() {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// eventsHandler streams link events as Server-Sent Events. Clients resume
+// after a disconnect with the Last-Event-ID header, which EventSource sends
+// automatically, or the last_event_id query parameter.
+func eventsHandler(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, status, errResp := parseEventFilter(c)
+		if errResp != nil {
+			c.JSON(status, errResp)
+			return
+		}
+
+		lastEventID := c.GetHeader("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = c.Query("last_event_id")
+		}
+		lastID, err := strconv.ParseUint(lastEventID, 10, 64)
+		resume := err == nil
+
+		sub, backlog := config.events.subscribe(filter, lastID, resume)
+		if sub == nil {
+			c.Header("Retry-After", "1")
+			c.Status(http.StatusServiceUnavailable)
+			return
+		}
+		defer config.events.unsubscribe(sub)
+
+		log.Printf("Event stream opened by %s (resuming after %q, %d buffered events)", c.ClientIP(), lastEventID, len(backlog))
+
+		header := c.Writer.Header()
+		header.Set("Content-Type", sse.ContentType)
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		header.Set("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+		c.Status(http.StatusOK)
+
+		fmt.Fprintf(c.Writer, "retry: %d\n\n", eventsRetry.Milliseconds())
+		for _, event := range backlog {
+			if writeLinkEvent(c, event) != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(config.EventsHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				log.Printf("Event stream closed by %s", c.ClientIP())
+				return
+
+			case event, open := <-sub.events:
+				if !open {
+					return
+				}
+				if writeLinkEvent(c, event) != nil {
+					return
+				}
+
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeLinkEvent writes an event in SSE format, named after its type
+func writeLinkEvent(c *gin.Context, event LinkEvent) error {
+	return sse.Encode(c.Writer, sse.Event{
+		Id:    strconv.FormatUint(event.ID, 10),
+		Event: event.Type,
+		Data:  event,
+	})
+}
+
+// parseEventFilter reads the type, alias and owner query parameters of
+// /api/v1/events. type and alias take comma-separated lists. API keys only
+// receive events of their own links, so owner can only be "me" or the ID
+// of the key making the request. Admin sessions receive every event, or
+// those of the key given as owner.
+func parseEventFilter(c *gin.Context) (eventFilter, int, *ErrorResponse) {
+	var filter eventFilter
+	invalid := func(message string) *ErrorResponse {
+		return &ErrorResponse{
+			Error:     "Invalid event filter",
+			Message:   message,
+			Code:      errCodeInvalidEventFilter,
+			Timestamp: time.Now(),
+		}
+	}
+
+	for _, eventType := range queryList(c, "type") {
+		if !slices.Contains(eventTypes, eventType) {
+			return filter, http.StatusBadRequest, invalid(fmt.Sprintf("type must be one of %s", strings.Join(eventTypes, ", ")))
+		}
+		if filter.types == nil {
+			filter.types = make(map[string]bool)
+		}
+		filter.types[eventType] = true
+	}
+
+	for _, alias := range queryList(c, "alias") {
+		if filter.aliases == nil {
+			filter.aliases = make(map[string]bool)
+		}
+		filter.aliases[normalizeAlias(alias)] = true
+	}
+
+	var apiKey *APIKey
+	if value, exists := c.Get(apiKeyContextKey); exists {
+		apiKey = value.(*APIKey)
+	}
+	admin := c.GetBool(adminContextKey)
+
+	owner := c.Query("owner")
+	switch {
+	case apiKey == nil && !admin:
+		return filter, http.StatusUnauthorized, &ErrorResponse{
+			Error:     "API key required",
+			Message:   "Event streams carry the links of the API key making the request; provide one in the X-API-Key header or as a Bearer token",
+			Code:      errCodeAPIKeyRequired,
+			Timestamp: time.Now(),
+		}
+	case admin && owner == "":
+		// Admin sessions see the links of every key
+	case admin && owner != "me":
+		id, err := strconv.ParseInt(owner, 10, 64)
+		if err != nil || id <= 0 {
+			return filter, http.StatusBadRequest, invalid("owner must be \"me\" or the ID of an API key")
+		}
+		filter.owner = id
+	case apiKey == nil:
+		return filter, http.StatusBadRequest, invalid("owner=me needs an API key")
+	case owner != "" && owner != "me" && owner != strconv.FormatInt(apiKey.ID, 10):
+		return filter, http.StatusBadRequest, invalid("owner must be \"me\" or the ID of the API key making the request")
+	default:
+		filter.owner = apiKey.ID
+	}
+
+	return filter, 0, nil
+}
+
+// queryList returns the non-empty items of a comma-separated query parameter
+func queryList(c *gin.Context, key string) []string {
+	var items []string
+	for _, item := range strings.Split(c.Query(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
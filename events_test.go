@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// replayEvents requests an event stream from the start of the replay
+// buffer and returns the aliases of the events it sent. The request is
+// cancelled up front, so the handler returns once the backlog is written.
+func replayEvents(t *testing.T, router *gin.Engine, path string, prepare func(*http.Request)) (int, []string) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "0")
+	prepare(req)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var aliases []string
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		data, found := strings.CutPrefix(line, "data:")
+		if !found {
+			continue
+		}
+		var event LinkEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("invalid event data %q: %v", data, err)
+		}
+		aliases = append(aliases, event.Alias)
+	}
+	return w.Code, aliases
+}
+
+func TestEventStreamsOnlyCarryOwnLinks(t *testing.T) {
+	discardLogs(t)
+	t.Setenv("ADMIN_PASSWORD", "secret")
+	config := newTestConfig(t)
+	startEventHub(config)
+	t.Cleanup(func() { stopEventHub(config) })
+
+	router := gin.New()
+	registerRoutes(router, config)
+
+	key, apiKey, err := createAPIKey(config, "mine", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := createAPIKey(config, "other", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherOwner := strconv.FormatInt(otherKey.ID, 10)
+
+	config.events.publish(LinkEvent{Type: eventLinkCreated, Alias: "anonymous"})
+	config.events.publish(LinkEvent{Type: eventLinkCreated, Alias: "theirs", Owner: otherKey.ID})
+	config.events.publish(LinkEvent{Type: eventLinkClicked, Alias: "mine", Owner: apiKey.ID})
+
+	withKey := func(req *http.Request) { req.Header.Set("X-API-Key", key) }
+	withoutKey := func(*http.Request) {}
+	withAdminSession := func(req *http.Request) {
+		req.AddCookie(&http.Cookie{Name: adminSessionCookie, Value: newAdminSession(config)})
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		prepare     func(*http.Request)
+		wantStatus  int
+		wantAliases []string
+	}{
+		{"no key", "/api/v1/events", withoutKey, http.StatusUnauthorized, nil},
+		{"unversioned without key", "/api/events", withoutKey, http.StatusUnauthorized, nil},
+		{"key without filter", "/api/v1/events", withKey, http.StatusOK, []string{"mine"}},
+		{"unversioned with key", "/api/events", withKey, http.StatusOK, []string{"mine"}},
+		{"key with owner=me", "/api/v1/events?owner=me", withKey, http.StatusOK, []string{"mine"}},
+		{"key with other owner", "/api/v1/events?owner=" + otherOwner, withKey, http.StatusBadRequest, nil},
+		{"admin session", "/admin/events", withAdminSession, http.StatusOK, []string{"anonymous", "theirs", "mine"}},
+		{"admin session with owner", "/admin/events?owner=" + otherOwner, withAdminSession, http.StatusOK, []string{"theirs"}},
+		{"admin session cookie on the API", "/api/v1/events", withAdminSession, http.StatusUnauthorized, nil},
+		{"admin without session", "/admin/events", withoutKey, http.StatusFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, aliases := replayEvents(t, router, tt.path, tt.prepare)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if !slices.Equal(aliases, tt.wantAliases) {
+				t.Errorf("events of %q, want %q", aliases, tt.wantAliases)
+			}
+		})
+	}
+}
+
+func TestUnversionedEventStreamNotDeprecated(t *testing.T) {
+	discardLogs(t)
+	config := newTestConfig(t)
+	startEventHub(config)
+	t.Cleanup(func() { stopEventHub(config) })
+
+	router := gin.New()
+	registerRoutes(router, config)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/events", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if deprecation := w.Header().Get("Deprecation"); deprecation != "" {
+		t.Errorf("GET /api/events sent Deprecation: %s", deprecation)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+	"net"
+	"sync/atomic"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// geoIPDB is the country database opened at startup, nil when GEOIP_DB_PATH
+// is not set
+var geoIPDB atomic.Pointer[maxminddb.Reader]
+
+// geoIPRecord is the part of a MaxMind Country or City record used for rules
+type geoIPRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+// openGeoIPDatabase opens the configured MaxMind-format database. Without it
+// country rules never match, so a failure is logged rather than fatal.
+func openGeoIPDatabase(config *Config) {
+	if config.GeoIPDBPath == "" {
+		return
+	}
+
+	reader, err := maxminddb.Open(config.GeoIPDBPath)
+	if err != nil {
+		log.Printf("⚠️  GeoIP database unavailable, country rules will not match: %v", err)
+		return
+	}
+
+	geoIPDB.Store(reader)
+	log.Printf("🌍 GeoIP database loaded: %s (%s)", config.GeoIPDBPath, reader.Metadata.DatabaseType)
+}
+
+// closeGeoIPDatabase releases the GeoIP database
+func closeGeoIPDatabase() {
+	if reader := geoIPDB.Swap(nil); reader != nil {
+		reader.Close()
+	}
+}
+
+// lookupCountry returns the ISO 3166-1 alpha-2 country code for an IP
+// address, or "" when it is unknown
+func lookupCountry(clientIP string) string {
+	reader := geoIPDB.Load()
+	if reader == nil {
+		return ""
+	}
+
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return ""
+	}
+
+	var record geoIPRecord
+	if err := reader.Lookup(ip, &record); err != nil {
+		log.Printf("Warning: GeoIP lookup failed for %s: %v", clientIP, err)
+		return ""
+	}
+
+	return record.Country.ISOCode
+}
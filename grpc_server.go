@@ -0,0 +1,452 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "url-shortener/shortenerpb"
+)
+
+// grpcErrorDomain is the domain of the ErrorInfo attached to gRPC errors
+const grpcErrorDomain = "url-shortener"
+
+// grpcCodes maps the HTTP status of an ErrorResponse to a gRPC code
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.AlreadyExists,
+	http.StatusUnprocessableEntity: codes.FailedPrecondition,
+	http.StatusInternalServerError: codes.Internal,
+}
+
+// grpcAPIKeyContext is the context key of the API key that authenticated
+// a gRPC call
+type grpcAPIKeyContext struct{}
+
+// grpcShortener implements the URLShortener gRPC service on the same
+// storage and validation as the HTTP API
+type grpcShortener struct {
+	pb.UnimplementedURLShortenerServer
+	config *Config
+}
+
+// startGRPCServer serves the gRPC API on GRPC_PORT, when it is set
+func startGRPCServer(config *Config) {
+	if config.GRPCPort == "" {
+		return
+	}
+
+	listener, err := net.Listen("tcp", ":"+config.GRPCPort)
+	if err != nil {
+		log.Fatalf("Failed to start gRPC server: %v", err)
+	}
+
+	config.grpcServer = newGRPCServer(config)
+	go func() {
+		if err := config.grpcServer.Serve(listener); err != nil {
+			log.Printf("gRPC server stopped: %v", err)
+		}
+	}()
+
+	log.Printf("🛰️  gRPC server starting on port %s", config.GRPCPort)
+}
+
+// stopGRPCServer lets running calls finish, cutting off streams that are
+// still open after 10 seconds
+func stopGRPCServer(config *Config) {
+	if config.grpcServer == nil {
+		return
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		config.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		log.Printf("Warning: gRPC calls still running after 10s, closing them")
+		config.grpcServer.Stop()
+	}
+	config.grpcServer = nil
+}
+
+// newGRPCServer creates a gRPC server with the URLShortener service and
+// server reflection registered
+func newGRPCServer(config *Config) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(grpcUnaryInterceptor(config)),
+		grpc.ChainStreamInterceptor(grpcStreamInterceptor(config)),
+	)
+	pb.RegisterURLShortenerServer(server, &grpcShortener{config: config})
+	reflection.Register(server)
+	return server
+}
+
+// grpcUnaryInterceptor authenticates, logs and recovers unary calls
+func grpcUnaryInterceptor(config *Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		start := time.Now()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = grpcPanicError(info.FullMethod, recovered)
+			}
+			logGRPCCall(ctx, info.FullMethod, start, err)
+		}()
+
+		if ctx, err = grpcAuthenticate(ctx, config); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// grpcStreamInterceptor authenticates, logs and recovers streaming calls
+func grpcStreamInterceptor(config *Config) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		start := time.Now()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = grpcPanicError(info.FullMethod, recovered)
+			}
+			logGRPCCall(stream.Context(), info.FullMethod, start, err)
+		}()
+
+		ctx, err := grpcAuthenticate(stream.Context(), config)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authenticatedStream carries the API key of a streaming call in its context
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcAuthenticate checks the API key sent in the x-api-key or
+// authorization metadata, like apiKeyMiddleware, and adds it to ctx.
+// Server reflection is always allowed, so clients can discover the API.
+func grpcAuthenticate(ctx context.Context, config *Config) (context.Context, error) {
+	if method, _ := grpc.Method(ctx); strings.HasPrefix(method, "/grpc.reflection.") {
+		return ctx, nil
+	}
+
+	var key string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("x-api-key"); len(values) > 0 {
+			key = values[0]
+		} else if values := md.Get("authorization"); len(values) > 0 && strings.HasPrefix(values[0], "Bearer ") {
+			key = strings.TrimPrefix(values[0], "Bearer ")
+		}
+	}
+
+	apiKey, httpStatus, errResp := authenticateAPIKey(config, key)
+	if errResp != nil {
+		if errResp.Code == errCodeInvalidAPIKey {
+			log.Printf("Invalid API key from %s (gRPC)", grpcPeer(ctx))
+		}
+		return ctx, grpcError(httpStatus, errResp)
+	}
+	if apiKey != nil {
+		ctx = context.WithValue(ctx, grpcAPIKeyContext{}, apiKey)
+	}
+	return ctx, nil
+}
+
+// grpcPanicError logs a panic in a gRPC handler and turns it into an
+// Internal error, so one bad call does not take the server down
+func grpcPanicError(method string, recovered interface{}) error {
+	log.Printf("🚨 Panic in gRPC %s: %v", method, recovered)
+	return grpcError(http.StatusInternalServerError, &ErrorResponse{
+		Error:     "Internal server error",
+		Message:   "An unexpected error occurred",
+		Code:      errCodeInternalError,
+		Timestamp: time.Now(),
+	})
+}
+
+// logGRPCCall logs a finished gRPC call like requestLoggingMiddleware
+func logGRPCCall(ctx context.Context, method string, start time.Time, err error) {
+	log.Printf("[gRPC] %s | %s | %v | %s", grpcPeer(ctx), status.Code(err), time.Since(start), method)
+}
+
+// grpcPeer returns the address of the client of a gRPC call
+func grpcPeer(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return "unknown"
+}
+
+// grpcError converts an ErrorResponse into a gRPC status. The error code is
+// attached as an ErrorInfo reason, with the details as its metadata.
+func grpcError(httpStatus int, errResp *ErrorResponse) error {
+	code, found := grpcCodes[httpStatus]
+	if !found {
+		code = codes.Unknown
+	}
+
+	message := errResp.Error
+	if errResp.Message != "" {
+		message += ": " + errResp.Message
+	}
+
+	info := &errdetails.ErrorInfo{Reason: errResp.Code, Domain: grpcErrorDomain}
+	if details, ok := errResp.Details.(map[string]interface{}); ok && len(details) > 0 {
+		info.Metadata = make(map[string]string, len(details))
+		for key, value := range details {
+			info.Metadata[key] = fmt.Sprint(value)
+		}
+	}
+
+	st := status.New(code, message)
+	if withDetails, err := st.WithDetails(info); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// grpcDatabaseError is returned when the database fails during a gRPC call
+func grpcDatabaseError(message string) error {
+	return grpcError(http.StatusInternalServerError, &ErrorResponse{
+		Error:     "Database error",
+		Message:   message,
+		Code:      errCodeDatabaseError,
+		Timestamp: time.Now(),
+	})
+}
+
+// grpcNotFound is returned for an alias without a short URL
+func grpcNotFound(alias string) error {
+	return grpcError(http.StatusNotFound, &ErrorResponse{
+		Error:     "URL not found",
+		Message:   fmt.Sprintf("No URL found for alias: %s", alias),
+		Code:      errCodeURLNotFound,
+		Timestamp: time.Now(),
+	})
+}
+
+// grpcAlias normalizes the alias of a request like aliasParam, rejecting
+// an empty one
+func grpcAlias(alias string) (string, error) {
+	alias = normalizeAlias(alias)
+	if alias == "" {
+		return "", grpcError(http.StatusBadRequest, &ErrorResponse{
+			Error:     "Missing alias parameter",
+			Code:      errCodeMissingAlias,
+			Timestamp: time.Now(),
+		})
+	}
+	return alias, nil
+}
+
+// Shorten creates a short URL with the validation of POST /api/v1/shorten
+func (s *grpcShortener) Shorten(ctx context.Context, in *pb.ShortenRequest) (*pb.ShortURL, error) {
+	log.Printf("Shorten request from %s (gRPC): URL=%s, Alias=%s", grpcPeer(ctx), in.GetUrl(), in.GetAlias())
+
+	req := ShortenRequest{
+		URL:           in.GetUrl(),
+		Alias:         in.GetAlias(),
+		AliasStyle:    in.GetAliasStyle(),
+		BotResponse:   in.GetBotResponse(),
+		OGTitle:       in.GetOgTitle(),
+		OGDescription: in.GetOgDescription(),
+		OGImage:       in.GetOgImage(),
+	}
+	if in.MaxClicks != nil {
+		maxClicks := int(in.GetMaxClicks())
+		req.MaxClicks = &maxClicks
+	}
+	for _, destination := range in.GetDestinations() {
+		req.Destinations = append(req.Destinations, Destination{URL: destination.GetUrl(), Weight: int(destination.GetWeight())})
+	}
+
+	apiKey, _ := ctx.Value(grpcAPIKeyContext{}).(*APIKey)
+	response, httpStatus, errResp := createShortURL(s.config, req, apiKey)
+	if errResp != nil {
+		return nil, grpcError(httpStatus, errResp)
+	}
+
+	return &pb.ShortURL{
+		Alias:       response.Alias,
+		ShortUrl:    response.ShortURL,
+		OriginalUrl: response.OriginalURL,
+		DisplayUrl:  response.DisplayURL,
+		FinalUrl:    response.FinalURL,
+		MaxClicks:   int32(response.MaxClicks),
+		Clicks:      int32(response.Clicks),
+		CreatedAt:   timestamppb.New(response.CreatedAt),
+	}, nil
+}
+
+// Resolve returns the default destination of an alias without counting a
+// click, read through the alias cache like redirects
+func (s *grpcShortener) Resolve(ctx context.Context, in *pb.ResolveRequest) (*pb.ResolveResponse, error) {
+	alias, err := grpcAlias(in.GetAlias())
+	if err != nil {
+		return nil, err
+	}
+
+	urlData, err := GetCachedURL(s.config, alias)
+	if err != nil {
+		log.Printf("Database error resolving %s: %v", alias, err)
+		return nil, grpcDatabaseError("Failed to retrieve URL")
+	}
+	if urlData == nil {
+		return nil, grpcNotFound(alias)
+	}
+
+	return &pb.ResolveResponse{
+		Alias:       urlData.Alias,
+		Destination: urlData.URL,
+		Expired:     urlData.Clicks >= urlData.MaxClicks,
+	}, nil
+}
+
+// GetInfo returns a short URL like GET /api/v1/urls/:alias
+func (s *grpcShortener) GetInfo(ctx context.Context, in *pb.GetInfoRequest) (*pb.URLInfo, error) {
+	alias, err := grpcAlias(in.GetAlias())
+	if err != nil {
+		return nil, err
+	}
+
+	urlData, err := GetURLByAlias(s.config, alias)
+	if err != nil {
+		log.Printf("Database error retrieving URL info for %s: %v", alias, err)
+		return nil, grpcDatabaseError("Failed to retrieve URL")
+	}
+	if urlData == nil {
+		return nil, grpcNotFound(alias)
+	}
+
+	return grpcURLInfo(buildURLInfo(urlData)), nil
+}
+
+// List streams the short URLs matching the filters of GET /api/v1/urls,
+// without paging
+func (s *grpcShortener) List(in *pb.ListRequest, stream pb.URLShortener_ListServer) error {
+	if errResp := checkHealthFilter(in.GetHealth()); errResp != nil {
+		return grpcError(http.StatusBadRequest, errResp)
+	}
+
+	urls, err := GetAllURLs(s.config)
+	if err != nil {
+		log.Printf("Error retrieving URLs: %v", err)
+		return grpcError(http.StatusInternalServerError, &ErrorResponse{
+			Error:     "Failed to retrieve URLs",
+			Message:   err.Error(),
+			Code:      errCodeRetrievalError,
+			Timestamp: time.Now(),
+		})
+	}
+
+	for _, url := range filterURLs(urls, in.GetStatus(), in.GetSearch(), in.GetHealth()) {
+		if err := stream.Send(grpcURLInfo(buildURLInfo(&url))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a short URL like DELETE /api/v1/urls/:alias
+func (s *grpcShortener) Delete(ctx context.Context, in *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	alias, err := grpcAlias(in.GetAlias())
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, _ := ctx.Value(grpcAPIKeyContext{}).(*APIKey)
+	if httpStatus, errResp := checkLinkOwner(s.config, alias, apiKey); errResp != nil {
+		return nil, grpcError(httpStatus, errResp)
+	}
+
+	found, err := DeleteURL(s.config, alias)
+	if err != nil {
+		log.Printf("Database error deleting %s: %v", alias, err)
+		return nil, grpcDatabaseError("Failed to delete URL")
+	}
+	if !found {
+		return nil, grpcNotFound(alias)
+	}
+
+	log.Printf("Deleted %s (requested by %s via gRPC)", alias, grpcPeer(ctx))
+	return &pb.DeleteResponse{}, nil
+}
+
+// GetStats returns the totals of GET /api/v1/stats
+func (s *grpcShortener) GetStats(ctx context.Context, in *pb.GetStatsRequest) (*pb.Stats, error) {
+	stats, err := GetStats(s.config)
+	if err != nil {
+		log.Printf("Error retrieving stats: %v", err)
+		return nil, grpcError(http.StatusInternalServerError, &ErrorResponse{
+			Error:     "Failed to retrieve statistics",
+			Message:   err.Error(),
+			Code:      errCodeStatsError,
+			Timestamp: time.Now(),
+		})
+	}
+
+	return &pb.Stats{
+		TotalUrls:    int32(stats.TotalURLs),
+		TotalClicks:  int32(stats.TotalClicks),
+		TotalHits:    int32(stats.TotalHits),
+		TotalBotHits: int32(stats.TotalBotHits),
+		ActiveUrls:   int32(stats.ActiveURLs),
+		ExpiredUrls:  int32(stats.ExpiredURLs),
+	}, nil
+}
+
+// grpcURLInfo converts URL details into their protobuf message
+func grpcURLInfo(info URLInfoResponse) *pb.URLInfo {
+	message := &pb.URLInfo{
+		Alias:           info.Alias,
+		ShortUrl:        info.ShortURL,
+		OriginalUrl:     info.OriginalURL,
+		DisplayUrl:      info.DisplayURL,
+		FinalUrl:        info.FinalURL,
+		Clicks:          int32(info.Clicks),
+		Hits:            int32(info.Hits),
+		BotHits:         int32(info.BotHits),
+		MaxClicks:       int32(info.MaxClicks),
+		RemainingClicks: int32(info.RemainingClicks),
+		Expired:         info.IsExpired,
+		CreatedAt:       timestamppb.New(info.CreatedAt),
+		Health:          info.Health,
+		Title:           info.Title,
+		Description:     info.Description,
+		FaviconUrl:      info.FaviconURL,
+	}
+	for _, destination := range info.Destinations {
+		message.Destinations = append(message.Destinations, &pb.Destination{
+			Url:    destination.URL,
+			Weight: int32(destination.Weight),
+			Clicks: int32(destination.Clicks),
+		})
+	}
+	return message
+}
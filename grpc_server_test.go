@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "url-shortener/shortenerpb"
+)
+
+// newGRPCTestClient serves the gRPC API over an in-memory listener and
+// returns a connection to it
+func newGRPCTestClient(t *testing.T, config *Config) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := newGRPCServer(config)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// withAPIKey adds an API key to the metadata of outgoing calls
+func withAPIKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+// wantCode fails the test unless err carries the gRPC code
+func wantCode(t *testing.T, call string, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Errorf("%s: code %v (%v), want %v", call, status.Code(err), err, code)
+	}
+}
+
+func TestGRPCShortenGetInfoStatsDelete(t *testing.T) {
+	discardLogs(t)
+	config := newTestConfig(t)
+	client := pb.NewURLShortenerClient(newGRPCTestClient(t, config))
+
+	owner, _, err := createAPIKey(config, "owner", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := createAPIKey(config, "other", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := withAPIKey(owner)
+
+	maxClicks := int32(3)
+	created, err := client.Shorten(ctx, &pb.ShortenRequest{
+		Url:       "Example.com/landing?b=2&a=1",
+		Alias:     "grpc-link",
+		MaxClicks: &maxClicks,
+	})
+	if err != nil {
+		t.Fatalf("Shorten: %v", err)
+	}
+	if created.GetAlias() != "grpc-link" || created.GetOriginalUrl() != "http://example.com/landing?a=1&b=2" || created.GetMaxClicks() != 3 {
+		t.Errorf("Shorten = %v", created)
+	}
+	if created.GetCreatedAt() == nil {
+		t.Error("Shorten returned no created_at")
+	}
+
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com", Alias: "grpc-link"})
+	wantCode(t, "Shorten with a taken alias", err, codes.AlreadyExists)
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "javascript:alert(1)"})
+	wantCode(t, "Shorten with a javascript: URL", err, codes.InvalidArgument)
+
+	info, err := client.GetInfo(ctx, &pb.GetInfoRequest{Alias: "grpc-link"})
+	if err != nil {
+		t.Fatalf("GetInfo: %v", err)
+	}
+	if info.GetShortUrl() != created.GetShortUrl() || info.GetRemainingClicks() != 3 || info.GetExpired() || info.GetHealth() != linkHealthUnchecked {
+		t.Errorf("GetInfo = %v", info)
+	}
+	_, err = client.GetInfo(ctx, &pb.GetInfoRequest{Alias: "missing-link"})
+	wantCode(t, "GetInfo of a missing alias", err, codes.NotFound)
+
+	stats, err := client.GetStats(ctx, &pb.GetStatsRequest{})
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.GetTotalUrls() != 1 || stats.GetActiveUrls() != 1 || stats.GetTotalClicks() != 0 {
+		t.Errorf("GetStats = %v", stats)
+	}
+
+	_, err = client.Delete(context.Background(), &pb.DeleteRequest{Alias: "grpc-link"})
+	wantCode(t, "Delete without a key", err, codes.Unauthenticated)
+	_, err = client.Delete(withAPIKey(other), &pb.DeleteRequest{Alias: "grpc-link"})
+	wantCode(t, "Delete with another key", err, codes.PermissionDenied)
+
+	if _, err := client.Delete(ctx, &pb.DeleteRequest{Alias: "grpc-link"}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	_, err = client.GetInfo(ctx, &pb.GetInfoRequest{Alias: "grpc-link"})
+	wantCode(t, "GetInfo after Delete", err, codes.NotFound)
+	_, err = client.Delete(ctx, &pb.DeleteRequest{Alias: "grpc-link"})
+	wantCode(t, "Delete twice", err, codes.NotFound)
+}
+
+func TestGRPCRequireAPIKey(t *testing.T) {
+	discardLogs(t)
+	t.Setenv("REQUIRE_API_KEY", "true")
+	config := newTestConfig(t)
+	client := pb.NewURLShortenerClient(newGRPCTestClient(t, config))
+
+	_, err := client.GetStats(context.Background(), &pb.GetStatsRequest{})
+	wantCode(t, "GetStats without a key", err, codes.Unauthenticated)
+	_, err = client.GetStats(withAPIKey("us_not-a-key"), &pb.GetStatsRequest{})
+	wantCode(t, "GetStats with an invalid key", err, codes.Unauthenticated)
+
+	key, _, err := createAPIKey(config, "client", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bearer := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+key)
+	if _, err := client.GetStats(bearer, &pb.GetStatsRequest{}); err != nil {
+		t.Errorf("GetStats with a bearer key: %v", err)
+	}
+}
+
+func TestGRPCReflection(t *testing.T) {
+	discardLogs(t)
+	t.Setenv("REQUIRE_API_KEY", "true")
+	config := newTestConfig(t)
+	conn := newGRPCTestClient(t, config)
+
+	// Reflection works without an API key
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatalf("ServerReflectionInfo: %v", err)
+	}
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	response, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+
+	var services []string
+	for _, service := range response.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	if !slices.Contains(services, pb.URLShortener_ServiceDesc.ServiceName) {
+		t.Errorf("reflection lists %v, want %s", services, pb.URLShortener_ServiceDesc.ServiceName)
+	}
+}
@@ -2,10 +2,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,22 +16,19 @@ import (
 // Global config variable (set in main)
 var appConfig *Config
 
+// maxClicksLimit is the highest max_clicks a link may be given
+const maxClicksLimit = 10000
+
 // shortenHandler handles URL shortening requests with enhanced validation and features
 func shortenHandler(config *Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		startTime := time.Now()
-
-		var req struct {
-			URL       string `json:"url"`
-			Alias     string `json:"alias"`
-			MaxClicks *int   `json:"max_clicks"`
-		}
+		var req ShortenRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			log.Printf("Invalid request format from %s: %v", c.ClientIP(), err)
 			c.JSON(http.StatusBadRequest, ErrorResponse{
 				Error:     "Invalid request format",
 				Message:   "Please provide a valid JSON request with 'url' field",
-				Code:      "INVALID_REQUEST",
+				Code:      errCodeInvalidRequest,
 				Details:   map[string]interface{}{"validation_error": err.Error()},
 				Timestamp: time.Now(),
 			})
@@ -40,167 +39,352 @@ func shortenHandler(config *Config) gin.HandlerFunc {
 		log.Printf("Shorten request from %s: URL=%s, Alias=%s, UserAgent=%s",
 			c.ClientIP(), req.URL, req.Alias, c.GetHeader("User-Agent"))
 
-		// Sanitize and validate URL with enhanced validation
-		sanitizedURL, err := sanitizeURL(req.URL)
-		if err != nil {
-			log.Printf("Invalid URL from %s: %s - %v", c.ClientIP(), req.URL, err)
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error:     "Invalid URL",
-				Message:   fmt.Sprintf("The provided URL is not valid: %v", err),
-				Details:   map[string]interface{}{"original_url": req.URL},
-				Timestamp: time.Now(),
-			})
-			return
+		// Keys with an alias prefix restrict the aliases they may create
+		var apiKey *APIKey
+		if value, exists := c.Get(apiKeyContextKey); exists {
+			apiKey = value.(*APIKey)
 		}
 
-		// Check for URL length limits
-		if len(sanitizedURL) > 2048 {
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error:     "URL too long",
-				Message:   "URL must be less than 2048 characters",
-				Timestamp: time.Now(),
-			})
+		response, status, errResp := createShortURL(config, req, apiKey)
+		if errResp != nil {
+			c.JSON(status, errResp)
 			return
 		}
 
-		// Check if URL already exists (optional deduplication)
-		if existingURL, _ := GetURLByAlias(config, sanitizedURL); existingURL != nil {
+		c.JSON(status, response)
+	}
+}
+
+// createShortURL validates a shorten request and stores the new short URL.
+// It is shared by the HTTP handlers and the command-line client, and returns
+// the HTTP status that best describes the outcome. apiKey is the key that
+// authenticated the request, if any.
+func createShortURL(config *Config, req ShortenRequest, apiKey *APIKey) (*ShortenResponse, int, *ErrorResponse) {
+	startTime := time.Now()
+
+	// A/B split links default to their first destination
+	if req.URL == "" && len(req.Destinations) > 0 {
+		req.URL = req.Destinations[0].URL
+	}
+
+	// Sanitize and validate URL with enhanced validation
+	sanitizedURL, err := sanitizeURL(config, req.URL)
+	if err != nil {
+		log.Printf("Invalid URL: %s - %v", req.URL, err)
+		return nil, http.StatusBadRequest, &ErrorResponse{
+			Error:     "Invalid URL",
+			Message:   fmt.Sprintf("The provided URL is not valid: %v", err),
+			Code:      errCodeInvalidURL,
+			Details:   map[string]interface{}{"original_url": req.URL},
+			Timestamp: time.Now(),
+		}
+	}
+
+	// Check for URL length limits
+	if len(sanitizedURL) > 2048 {
+		return nil, http.StatusBadRequest, &ErrorResponse{
+			Error:     "URL too long",
+			Message:   "URL must be less than 2048 characters",
+			Code:      errCodeURLTooLong,
+			Timestamp: time.Now(),
+		}
+	}
+
+	// Determine max clicks (use custom value if provided, otherwise use config default)
+	maxClicks := config.GetMaxClicks()
+	if req.MaxClicks != nil && *req.MaxClicks > 0 && *req.MaxClicks <= maxClicksLimit {
+		maxClicks = *req.MaxClicks
+	}
+
+	var aliasPrefix string
+	var apiKeyID int64
+	if apiKey != nil {
+		aliasPrefix = apiKey.AliasPrefix
+		apiKeyID = apiKey.ID
+	}
+
+	// Reuse an active link to the same normalized URL (optional deduplication)
+	if config.GetURLNormalization().Deduplicate && isPlainShortenRequest(req) {
+		existingURL, err := FindDuplicateURL(config, sanitizedURL, maxClicks, aliasPrefix)
+		if err != nil {
+			log.Printf("Database error looking up duplicates of %s: %v", sanitizedURL, err)
+		}
+		if existingURL != nil {
 			log.Printf("URL already exists: %s -> %s", sanitizedURL, existingURL.ShortURL)
-			c.JSON(http.StatusOK, ShortenResponse{
+			return &ShortenResponse{
 				ShortURL:    existingURL.ShortURL,
 				OriginalURL: existingURL.URL,
+				DisplayURL:  displayURL(existingURL.URL),
 				Alias:       existingURL.Alias,
 				CreatedAt:   existingURL.CreatedAt,
 				MaxClicks:   existingURL.MaxClicks,
 				Clicks:      existingURL.Clicks,
-			})
-			return
+			}, http.StatusOK, nil
 		}
+	}
 
-		// Determine max clicks (use custom value if provided, otherwise use config default)
-		maxClicks := config.MaxClicks
-		if req.MaxClicks != nil && *req.MaxClicks > 0 && *req.MaxClicks <= 10000 {
-			maxClicks = *req.MaxClicks
+	// Validate how bots are served
+	botResponse := req.BotResponse
+	if botResponse == "" {
+		botResponse = botResponseRedirect
+	}
+	if botResponse != botResponseRedirect && botResponse != botResponseMetadata {
+		return nil, http.StatusBadRequest, &ErrorResponse{
+			Error:     "Invalid bot response",
+			Message:   fmt.Sprintf("bot_response must be %q or %q", botResponseRedirect, botResponseMetadata),
+			Code:      errCodeInvalidBotResponse,
+			Details:   map[string]interface{}{"bot_response": req.BotResponse},
+			Timestamp: time.Now(),
 		}
+	}
 
-		var alias string
-		if req.Alias != "" {
-			// Enhanced custom alias validation
-			validatedAlias, err := generateCustomAlias(req.Alias)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, ErrorResponse{
-					Error:     "Invalid custom alias",
-					Message:   err.Error(),
-					Details:   map[string]interface{}{"alias": req.Alias},
-					Timestamp: time.Now(),
-				})
-				return
-			}
+	// Validate Open Graph overrides
+	ogTitle, ogDescription, ogImage, err := validateOpenGraph(config, req.OGTitle, req.OGDescription, req.OGImage)
+	if err != nil {
+		return nil, http.StatusBadRequest, &ErrorResponse{
+			Error:     "Invalid Open Graph fields",
+			Message:   err.Error(),
+			Code:      errCodeInvalidOpenGraph,
+			Timestamp: time.Now(),
+		}
+	}
 
-			// Check if alias already exists
-			existingURL, err := GetURLByAlias(config, validatedAlias)
-			if err != nil {
-				log.Printf("Database error checking alias %s: %v", validatedAlias, err)
-				c.JSON(http.StatusInternalServerError, ErrorResponse{
-					Error:     "Database error",
-					Message:   "Failed to check alias availability",
-					Timestamp: time.Now(),
-				})
-				return
+	// Validate redirect rules
+	rules, err := validateRedirectRules(config, req.Rules)
+	if err != nil {
+		return nil, http.StatusBadRequest, &ErrorResponse{
+			Error:     "Invalid redirect rules",
+			Message:   err.Error(),
+			Code:      errCodeInvalidRules,
+			Timestamp: time.Now(),
+		}
+	}
+
+	// Validate app deep links
+	deepLink, err := validateDeepLink(config, req.DeepLink)
+	if err != nil {
+		return nil, http.StatusBadRequest, &ErrorResponse{
+			Error:     "Invalid deep link",
+			Message:   err.Error(),
+			Code:      errCodeInvalidDeepLink,
+			Timestamp: time.Now(),
+		}
+	}
+
+	// Validate A/B destinations
+	destinations, err := validateDestinations(config, req.Destinations)
+	if err != nil {
+		return nil, http.StatusBadRequest, &ErrorResponse{
+			Error:     "Invalid destinations",
+			Message:   err.Error(),
+			Code:      errCodeInvalidDestinations,
+			Timestamp: time.Now(),
+		}
+	}
+
+	// Validate the requested style of a generated alias
+	if req.AliasStyle != "" {
+		if _, found := config.aliases[req.AliasStyle]; !found {
+			return nil, http.StatusBadRequest, &ErrorResponse{
+				Error:     "Invalid alias style",
+				Message:   fmt.Sprintf("alias_style must be one of: %s", strings.Join(config.aliasStrategyNames(), ", ")),
+				Code:      errCodeInvalidAliasStyle,
+				Details:   map[string]interface{}{"alias_style": req.AliasStyle},
+				Timestamp: time.Now(),
 			}
+		}
+	}
 
-			if existingURL != nil {
-				c.JSON(http.StatusConflict, ErrorResponse{
-					Error:     "Alias already exists",
-					Message:   fmt.Sprintf("The alias '%s' is already taken. Please choose a different one.", validatedAlias),
-					Details:   map[string]interface{}{"alias": validatedAlias},
-					Timestamp: time.Now(),
-				})
-				return
+	var alias string
+	if req.Alias != "" {
+		// Enhanced custom alias validation
+		validatedAlias, err := generateCustomAlias(req.Alias)
+		if err == nil {
+			err = config.aliasPolicy.check(validatedAlias)
+		}
+		if err != nil {
+			return nil, http.StatusBadRequest, &ErrorResponse{
+				Error:     "Invalid custom alias",
+				Message:   err.Error(),
+				Code:      errCodeInvalidAlias,
+				Details:   map[string]interface{}{"alias": req.Alias},
+				Timestamp: time.Now(),
 			}
+		}
 
-			alias = validatedAlias
-		} else {
-			// Enhanced random alias generation with retry handling
-			for attempts := 0; attempts < 20; attempts++ {
-				randomAlias := generateRandomAlias()
-				existingURL, err := GetURLByAlias(config, randomAlias)
-				if err != nil {
-					log.Printf("Database error generating alias (attempt %d): %v", attempts+1, err)
-					if attempts >= 19 {
-						c.JSON(http.StatusInternalServerError, ErrorResponse{
-							Error:     "Database error",
-							Message:   "Failed to generate unique alias",
-							Timestamp: time.Now(),
-						})
-						return
-					}
-					continue
-				}
+		if aliasPrefix != "" && !hasAliasPrefix(config, validatedAlias, aliasPrefix) {
+			return nil, http.StatusForbidden, &ErrorResponse{
+				Error:     "Alias prefix required",
+				Message:   fmt.Sprintf("Aliases created with this API key must start with '%s'", aliasPrefix),
+				Code:      errCodeAliasPrefixRequired,
+				Details:   map[string]interface{}{"alias": validatedAlias, "alias_prefix": aliasPrefix},
+				Timestamp: time.Now(),
+			}
+		}
 
-				if existingURL == nil {
-					alias = randomAlias
-					break
-				}
+		// Check if alias already exists
+		taken, err := AliasTaken(config, validatedAlias)
+		if err != nil {
+			log.Printf("Database error checking alias %s: %v", validatedAlias, err)
+			return nil, http.StatusInternalServerError, &ErrorResponse{
+				Error:     "Database error",
+				Message:   "Failed to check alias availability",
+				Code:      errCodeDatabaseError,
+				Timestamp: time.Now(),
 			}
+		}
 
-			if alias == "" {
-				c.JSON(http.StatusInternalServerError, ErrorResponse{
-					Error:     "Failed to generate unique alias",
-					Message:   "Please try again or provide a custom alias",
-					Timestamp: time.Now(),
-				})
-				return
+		if taken {
+			return nil, http.StatusConflict, &ErrorResponse{
+				Error:     "Alias already exists",
+				Message:   fmt.Sprintf("The alias '%s' is already taken. Please choose a different one.", validatedAlias),
+				Code:      errCodeAliasTaken,
+				Details:   map[string]interface{}{"alias": validatedAlias},
+				Timestamp: time.Now(),
 			}
 		}
 
-		// Create URL data with enhanced fields
-		shortURL := fmt.Sprintf("%s/%s", config.BaseURL, alias)
-		urlData := URLData{
-			Alias:       alias,
-			URL:         sanitizedURL,
-			OriginalURL: sanitizedURL,
-			ShortURL:    shortURL,
-			Clicks:      0,
-			MaxClicks:   maxClicks,
-			CreatedAt:   time.Now(),
+		// Check for existing aliases that look the same
+		lookalike, err := ConfusableAlias(config, validatedAlias)
+		if err != nil {
+			log.Printf("Database error checking lookalikes of alias %s: %v", validatedAlias, err)
+			return nil, http.StatusInternalServerError, &ErrorResponse{
+				Error:     "Database error",
+				Message:   "Failed to check alias availability",
+				Code:      errCodeDatabaseError,
+				Timestamp: time.Now(),
+			}
 		}
 
-		// Save to database with error handling
-		if err := SaveURL(config, urlData); err != nil {
-			log.Printf("Error saving URL %s: %v", alias, err)
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Error:     "Failed to save URL",
-				Message:   "Please try again",
-				Details:   map[string]interface{}{"alias": alias},
+		if lookalike != "" {
+			return nil, http.StatusConflict, &ErrorResponse{
+				Error:     "Alias too similar",
+				Message:   fmt.Sprintf("The alias '%s' looks like the existing alias '%s'. Please choose a different one.", validatedAlias, lookalike),
+				Code:      errCodeAliasTooSimilar,
+				Details:   map[string]interface{}{"alias": validatedAlias, "similar_to": lookalike},
 				Timestamp: time.Now(),
-			})
-			return
+			}
 		}
 
-		// Enhanced success logging
-		duration := time.Since(startTime)
-		log.Printf("Successfully created short URL: %s -> %s (took %v)", shortURL, sanitizedURL, duration)
+		alias = validatedAlias
+	}
 
-		// Return enhanced success response
-		response := ShortenResponse{
-			ShortURL:    shortURL,
-			OriginalURL: sanitizedURL,
-			Alias:       alias,
-			CreatedAt:   urlData.CreatedAt,
-			MaxClicks:   urlData.MaxClicks,
-			Clicks:      urlData.Clicks,
+	// Record where the destination's redirects lead, refusing loops
+	var finalURL string
+	if config.ResolveRedirects {
+		resolved, err := resolveRedirects(config, sanitizedURL)
+		if err != nil {
+			log.Printf("Rejected redirect chain of %s: %v", sanitizedURL, err)
+			return nil, http.StatusBadRequest, &ErrorResponse{
+				Error:     "Invalid redirect chain",
+				Message:   err.Error(),
+				Code:      errCodeInvalidRedirectChain,
+				Details:   map[string]interface{}{"original_url": sanitizedURL},
+				Timestamp: time.Now(),
+			}
+		}
+		if resolved != sanitizedURL {
+			finalURL = resolved
 		}
+	}
 
-		c.JSON(http.StatusCreated, response)
+	// Create URL data with enhanced fields; without an alias one is
+	// generated when saving
+	urlData := URLData{
+		Alias:       alias,
+		URL:         sanitizedURL,
+		OriginalURL: sanitizedURL,
+		FinalURL:    finalURL,
+		ShortURL:    fmt.Sprintf("%s/%s", config.BaseURL, alias),
+		Clicks:      0,
+		MaxClicks:   maxClicks,
+		BotResponse: botResponse,
+		CreatedAt:   time.Now(),
+
+		OGTitle:       ogTitle,
+		OGDescription: ogDescription,
+		OGImage:       ogImage,
+		Rules:         rules,
+		Destinations:  destinations,
+		DeepLink:      deepLink,
+
+		aliasStyle:  req.AliasStyle,
+		aliasPrefix: aliasPrefix,
+		apiKeyID:    apiKeyID,
+	}
 
+	// Save to database with error handling
+	if err := SaveURL(config, &urlData); err != nil {
+		log.Printf("Error saving URL %s: %v", alias, err)
+		if isUniqueViolation(err) {
+			// Another request took the custom alias since it was checked
+			return nil, http.StatusConflict, &ErrorResponse{
+				Error:     "Alias already exists",
+				Message:   fmt.Sprintf("The alias '%s' is already taken. Please choose a different one.", alias),
+				Code:      errCodeAliasTaken,
+				Details:   map[string]interface{}{"alias": alias},
+				Timestamp: time.Now(),
+			}
+		}
+		if errors.Is(err, errAliasesExhausted) {
+			return nil, http.StatusInternalServerError, &ErrorResponse{
+				Error:     "Failed to generate unique alias",
+				Message:   "Please try again or provide a custom alias",
+				Code:      errCodeAliasGenerationFailed,
+				Timestamp: time.Now(),
+			}
+		}
+		return nil, http.StatusInternalServerError, &ErrorResponse{
+			Error:     "Failed to save URL",
+			Message:   "Please try again",
+			Code:      errCodeDatabaseError,
+			Details:   map[string]interface{}{"alias": alias},
+			Timestamp: time.Now(),
+		}
 	}
+
+	// Title and favicon are fetched in the background
+	wakeMetadataFetcher(config)
+
+	config.events.publish(LinkEvent{
+		Type:        eventLinkCreated,
+		Alias:       urlData.Alias,
+		ShortURL:    urlData.ShortURL,
+		Destination: sanitizedURL,
+		MaxClicks:   urlData.MaxClicks,
+		Owner:       apiKeyID,
+	})
+
+	// Enhanced success logging
+	duration := time.Since(startTime)
+	log.Printf("Successfully created short URL: %s -> %s (took %v)", urlData.ShortURL, sanitizedURL, duration)
+
+	// Return enhanced success response
+	return &ShortenResponse{
+		ShortURL:    urlData.ShortURL,
+		OriginalURL: sanitizedURL,
+		DisplayURL:  displayURL(sanitizedURL),
+		FinalURL:    finalURL,
+		Alias:       urlData.Alias,
+		CreatedAt:   urlData.CreatedAt,
+		MaxClicks:   urlData.MaxClicks,
+		Clicks:      urlData.Clicks,
+	}, http.StatusCreated, nil
+}
+
+// isPlainShortenRequest reports whether a request only asks for a link to
+// its URL, so an existing link to the same URL serves it equally well
+func isPlainShortenRequest(req ShortenRequest) bool {
+	return req.Alias == "" && req.AliasStyle == "" &&
+		(req.BotResponse == "" || req.BotResponse == botResponseRedirect) &&
+		req.OGTitle == "" && req.OGDescription == "" && req.OGImage == "" &&
+		len(req.Rules) == 0 && len(req.Destinations) == 0 && req.DeepLink == nil
 }
 
 // redirectHandler handles URL redirection with enhanced tracking and error handling
 func redirectHandler(config *Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		alias := c.Param("alias")
+		alias := aliasParam(c)
 		if alias == "" {
 			log.Printf("Missing alias in redirect request from %s", c.ClientIP())
 			c.HTML(http.StatusNotFound, "404.html", gin.H{
@@ -216,74 +400,153 @@ func redirectHandler(config *Config) gin.HandlerFunc {
 		log.Printf("Redirect request: alias=%s, ip=%s, user_agent=%s, referrer=%s",
 			alias, c.ClientIP(), userAgent, referrer)
 
-		// Try to increment click count first - this will handle all validation
-		newClickCount, err := IncrementURLClicks(config, alias)
-		if err != nil {
-			log.Printf("Failed to increment clicks for %s: %v", alias, err)
+		// The link is read once, from the cache when possible. Its click
+		// count may be stale when another process changed it; the increment
+		// below still enforces the limit.
+		urlData, err := GetCachedURL(config, alias)
+		if err != nil || urlData == nil || urlData.Clicks >= urlData.MaxClicks {
+			renderUnavailable(c, alias, urlData, err)
+			return
+		}
 
-			// Get URL data to provide better error messages
-			urlData, dbErr := GetURLByAlias(config, alias)
-			if dbErr != nil || urlData == nil {
-				log.Printf("URL not found for alias: %s", alias)
-				c.HTML(http.StatusNotFound, "404.html", gin.H{
-					"error":   "URL Not Found",
-					"message": fmt.Sprintf("No URL found for alias: %s", alias),
-					"alias":   alias,
-				})
-				return
-			}
+		// Refuse links whose destination keeps failing health checks
+		if isDeadLink(config, urlData) {
+			log.Printf("Destination of %s failed %d checks in a row, not redirecting", alias, urlData.HealthFailures)
+			c.HTML(http.StatusServiceUnavailable, "404.html", gin.H{
+				"error":   "Destination Unavailable",
+				"message": "The page this link points to is not responding. Please try again later.",
+				"alias":   alias,
+			})
+			return
+		}
 
-			// Check if it's an expiration error
-			if urlData.Clicks >= urlData.MaxClicks {
-				log.Printf("URL expired: %s (%d/%d clicks)", alias, urlData.Clicks, urlData.MaxClicks)
-				c.HTML(http.StatusGone, "404.html", gin.H{
-					"error":      "URL Expired",
-					"message":    fmt.Sprintf("This URL has reached its maximum click limit of %d", urlData.MaxClicks),
-					"alias":      alias,
-					"clicks":     urlData.Clicks,
-					"max_clicks": urlData.MaxClicks,
-					"is_expired": true,
-				})
-				return
-			}
+		event := ClickEvent{
+			Alias:       alias,
+			VisitorHash: visitorHash(config, alias, c.ClientIP(), userAgent),
+			UserAgent:   userAgent,
+			Referrer:    referrer,
+		}
 
-			// Other database errors
-			c.HTML(http.StatusInternalServerError, "404.html", gin.H{
-				"error":   "Database Error",
-				"message": "Failed to process request",
-			})
+		// Social crawlers get the link's own preview card when it has one
+		if isSocialCrawler(userAgent) && serveOpenGraphCard(c, config, urlData, event) {
 			return
 		}
 
-		// If we get here, the click was successfully incremented
-		// Get the URL data for redirect
-		urlData, err := GetURLByAlias(config, alias)
-		if err != nil || urlData == nil {
-			log.Printf("Database error after successful increment for alias %s: %v", alias, err)
-			c.HTML(http.StatusInternalServerError, "404.html", gin.H{
-				"error":   "Database Error",
-				"message": "Failed to retrieve URL data",
-			})
+		// Bots get the link's bot response and are counted under bot_hits.
+		// Known bots only use up a click with SKIP_BOT_CLICKS off, and HEAD
+		// requests never do. Likely bots always use one, so a plain curl
+		// cannot follow a link past its max_clicks.
+		botLevel, botReason := classifyBot(c.Request)
+		event.Bot = botLevel != botNone
+		if botLevel == botKnown && (config.GetClickPolicy().SkipBotClicks || c.Request.Method == http.MethodHead) {
+			serveBotHit(c, config, urlData, event, botReason)
+			return
+		}
+
+		// Repeat visitors are redirected without using up a click
+		if counted, reason := shouldCountClick(config, event); !counted {
+			serveUncountedHit(c, config, urlData, event, reason)
+			return
+		}
+
+		// The increment enforces the limit against the stored count
+		newClickCount, err := IncrementURLClicks(config, alias)
+		if err != nil {
+			log.Printf("Failed to increment clicks for %s: %v", alias, err)
+
+			// Get URL data to provide better error messages
+			config.cache.invalidate(alias)
+			urlData, dbErr := GetURLByAlias(config, alias)
+			renderUnavailable(c, alias, urlData, dbErr)
 			return
 		}
+		config.cache.setClicks(alias, newClickCount)
 
 		// Log successful click tracking
 		log.Printf("Click tracked: %s (%d/%d clicks)", alias, newClickCount, urlData.MaxClicks)
 
+		// Record the click for time-series analytics (non-fatal)
+		event.Counted = true
+		var destination string
+		if event.Bot {
+			destination = botDestination(c, urlData, &event)
+		} else {
+			destination = resolveDestination(c, urlData, &event)
+		}
+		if err := RecordClickEvent(config, event); err != nil {
+			log.Printf("Warning: failed to record click event for %s: %v", alias, err)
+		}
+
+		clicked := LinkEvent{
+			Type:        eventLinkClicked,
+			Alias:       alias,
+			ShortURL:    urlData.ShortURL,
+			Destination: destination,
+			Clicks:      newClickCount,
+			MaxClicks:   urlData.MaxClicks,
+			Owner:       urlData.apiKeyID,
+		}
+		config.events.publish(clicked)
+
 		// Check if this was the last allowed click
 		if newClickCount >= urlData.MaxClicks {
 			log.Printf("Info: URL %s has reached its maximum click limit (%d/%d)", alias, newClickCount, urlData.MaxClicks)
+			clicked.Type = eventLinkExpired
+			config.events.publish(clicked)
 		}
 
-		// Add cache-control headers to prevent browser caching
-		c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
-		c.Header("Pragma", "no-cache")
-		c.Header("Expires", "0")
+		if event.Bot {
+			sendBot(c, urlData, destination)
+			return
+		}
+		sendVisitor(c, urlData, destination)
+	}
+}
 
-		// Enhanced redirect with proper status code
-		log.Printf("Redirecting %s to %s", alias, urlData.URL)
-		c.Redirect(http.StatusFound, urlData.URL) // Use 302 instead of 301 to prevent caching
+// renderUnavailable renders the error page for a URL that cannot be
+// redirected: missing, expired, or failing for another reason
+func renderUnavailable(c *gin.Context, alias string, urlData *URLData, dbErr error) {
+	if dbErr != nil || urlData == nil {
+		log.Printf("URL not found for alias: %s", alias)
+		c.HTML(http.StatusNotFound, "404.html", gin.H{
+			"error":   "URL Not Found",
+			"message": fmt.Sprintf("No URL found for alias: %s", alias),
+			"alias":   alias,
+		})
+		return
 	}
+
+	// Check if it's an expiration error
+	if urlData.Clicks >= urlData.MaxClicks {
+		log.Printf("URL expired: %s (%d/%d clicks)", alias, urlData.Clicks, urlData.MaxClicks)
+		c.HTML(http.StatusGone, "404.html", gin.H{
+			"error":      "URL Expired",
+			"message":    fmt.Sprintf("This URL has reached its maximum click limit of %d", urlData.MaxClicks),
+			"alias":      alias,
+			"clicks":     urlData.Clicks,
+			"max_clicks": urlData.MaxClicks,
+			"is_expired": true,
+		})
+		return
+	}
+
+	// Other database errors
+	c.HTML(http.StatusInternalServerError, "404.html", gin.H{
+		"error":   "Database Error",
+		"message": "Failed to process request",
+	})
+}
+
+// redirectTo sends an uncacheable temporary redirect
+func redirectTo(c *gin.Context, alias, destination string) {
+	// Add cache-control headers to prevent browser caching
+	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+	c.Header("Pragma", "no-cache")
+	c.Header("Expires", "0")
+
+	// Enhanced redirect with proper status code
+	log.Printf("Redirecting %s to %s", alias, destination)
+	c.Redirect(http.StatusFound, destination) // Use 302 instead of 301 to prevent caching
 }
 
 // statsHandler provides enhanced statistics
@@ -295,7 +558,7 @@ func statsHandler(config *Config) gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, ErrorResponse{
 				Error:     "Failed to retrieve statistics",
 				Message:   err.Error(),
-				Code:      "STATS_ERROR",
+				Code:      errCodeStatsError,
 				Timestamp: time.Now(),
 			})
 			return
@@ -308,34 +571,34 @@ func statsHandler(config *Config) gin.HandlerFunc {
 // healthHandler provides comprehensive health check
 func healthHandler(config *Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		health := gin.H{
-			"status":    "healthy",
-			"timestamp": time.Now().UTC(),
-			"version":   "1.0.0",
+		health := HealthResponse{
+			Status:    "healthy",
+			Timestamp: time.Now().UTC(),
+			Version:   "1.0.0",
 		}
 
 		// Check database connection
 		if db := config.GetDB(); db != nil {
 			if err := db.Ping(); err != nil {
-				health["status"] = "unhealthy"
-				health["database"] = "disconnected"
-				health["error"] = err.Error()
+				health.Status = "unhealthy"
+				health.Database = "disconnected"
+				health.Error = err.Error()
 				c.JSON(http.StatusServiceUnavailable, health)
 				return
 			}
-			health["database"] = "connected"
+			health.Database = "connected"
 
 			// Add database stats
 			if stats, err := GetStats(config); err == nil {
-				health["stats"] = gin.H{
-					"total_urls":   stats.TotalURLs,
-					"total_clicks": stats.TotalClicks,
-					"active_urls":  stats.ActiveURLs,
-					"expired_urls": stats.ExpiredURLs,
+				health.Stats = &HealthStats{
+					TotalURLs:   stats.TotalURLs,
+					TotalClicks: stats.TotalClicks,
+					ActiveURLs:  stats.ActiveURLs,
+					ExpiredURLs: stats.ExpiredURLs,
 				}
 			}
 		} else {
-			health["database"] = "not_configured"
+			health.Database = "not_configured"
 		}
 
 		c.JSON(http.StatusOK, health)
@@ -359,36 +622,13 @@ func requestLoggingMiddleware() gin.HandlerFunc {
 	})
 }
 
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		origin := c.GetHeader("Origin")
-
-		// Set CORS headers
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Requested-With")
-		c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers")
-		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Max-Age", "86400")
-
-		// Handle preflight OPTIONS request
-		if c.Request.Method == "OPTIONS" {
-			log.Printf("CORS preflight request from origin: %s", origin)
-			c.AbortWithStatus(http.StatusNoContent)
-			return
-		}
-
-		c.Next()
-	}
-}
-
 func urlInfoHandler(config *Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		alias := c.Param("alias")
+		alias := aliasParam(c)
 		if alias == "" {
 			c.JSON(http.StatusBadRequest, ErrorResponse{
 				Error:     "Missing alias parameter",
-				Code:      "MISSING_ALIAS",
+				Code:      errCodeMissingAlias,
 				Timestamp: time.Now(),
 			})
 			return
@@ -401,7 +641,7 @@ func urlInfoHandler(config *Config) gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, ErrorResponse{
 				Error:     "Database error",
 				Message:   "Failed to retrieve URL",
-				Code:      "DATABASE_ERROR",
+				Code:      errCodeDatabaseError,
 				Timestamp: time.Now(),
 			})
 			return
@@ -411,30 +651,59 @@ func urlInfoHandler(config *Config) gin.HandlerFunc {
 			c.JSON(http.StatusNotFound, ErrorResponse{
 				Error:     "URL not found",
 				Message:   fmt.Sprintf("No URL found for alias: %s", alias),
-				Code:      "URL_NOT_FOUND",
+				Code:      errCodeURLNotFound,
 				Timestamp: time.Now(),
 			})
 			return
 		}
 
-		// Calculate remaining clicks
-		remainingClicks := urlData.MaxClicks - urlData.Clicks
-		if remainingClicks < 0 {
-			remainingClicks = 0
+		info := buildURLInfo(urlData)
+		if len(urlData.Rules) > 0 {
+			if info.RuleHits, err = GetRuleHits(config, alias); err != nil {
+				log.Printf("Error retrieving rule hits for %s: %v", alias, err)
+			}
 		}
 
-		// Return comprehensive URL information
-		c.JSON(http.StatusOK, gin.H{
-			"alias":            urlData.Alias,
-			"original_url":     urlData.URL,
-			"short_url":        urlData.ShortURL,
-			"clicks":           urlData.Clicks,
-			"max_clicks":       urlData.MaxClicks,
-			"remaining_clicks": remainingClicks,
-			"created_at":       urlData.CreatedAt,
-			"is_expired":       urlData.Clicks >= urlData.MaxClicks,
-			"usage_percentage": float64(urlData.Clicks) / float64(urlData.MaxClicks) * 100,
-		})
+		c.JSON(http.StatusOK, info)
+	}
+}
+
+// buildURLInfo derives usage information for a stored URL
+func buildURLInfo(urlData *URLData) URLInfoResponse {
+	// Calculate remaining clicks
+	remainingClicks := urlData.MaxClicks - urlData.Clicks
+	if remainingClicks < 0 {
+		remainingClicks = 0
+	}
+
+	return URLInfoResponse{
+		Alias:           urlData.Alias,
+		OriginalURL:     urlData.URL,
+		DisplayURL:      displayURL(urlData.URL),
+		FinalURL:        urlData.FinalURL,
+		ShortURL:        urlData.ShortURL,
+		Clicks:          urlData.Clicks,
+		Hits:            urlData.Hits,
+		BotHits:         urlData.BotHits,
+		BotResponse:     urlData.BotResponse,
+		MaxClicks:       urlData.MaxClicks,
+		RemainingClicks: remainingClicks,
+		CreatedAt:       urlData.CreatedAt,
+		IsExpired:       urlData.Clicks >= urlData.MaxClicks,
+		UsagePercentage: float64(urlData.Clicks) / float64(urlData.MaxClicks) * 100,
+		OGTitle:         urlData.OGTitle,
+		OGDescription:   urlData.OGDescription,
+		OGImage:         urlData.OGImage,
+		Rules:           urlData.Rules,
+		Destinations:    buildDestinationInfo(urlData.Destinations),
+		DeepLink:        urlData.DeepLink,
+		Health:          urlData.Health,
+		HealthStatus:    urlData.HealthStatus,
+		HealthError:     urlData.HealthError,
+		HealthCheckedAt: urlData.HealthCheckedAt,
+		Title:           urlData.Title,
+		Description:     urlData.Description,
+		FaviconURL:      urlData.FaviconURL,
 	}
 }
 
@@ -448,91 +717,240 @@ func cleanupHandler(config *Config) gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, ErrorResponse{
 				Error:     "Failed to cleanup expired URLs",
 				Message:   err.Error(),
-				Code:      "CLEANUP_ERROR",
+				Code:      errCodeCleanupError,
 				Timestamp: time.Now(),
 			})
 			return
 		}
 
 		log.Printf("Cleanup completed: %d URLs deleted", deletedCount)
-		c.JSON(http.StatusOK, gin.H{
-			"message":       "Cleanup completed successfully",
-			"deleted_count": deletedCount,
-			"timestamp":     time.Now(),
+		c.JSON(http.StatusOK, CleanupResponse{
+			Message:      "Cleanup completed successfully",
+			DeletedCount: deletedCount,
+			Timestamp:    time.Now(),
 		})
 	}
 }
 
+// deleteURLHandler removes a single URL by alias
+func deleteURLHandler(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alias := aliasParam(c)
+
+		if status, errResp := checkLinkOwner(config, alias, requestAPIKey(c)); errResp != nil {
+			c.JSON(status, errResp)
+			return
+		}
+
+		found, err := DeleteURL(config, alias)
+		if err != nil {
+			log.Printf("Database error deleting %s: %v", alias, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:     "Database error",
+				Message:   "Failed to delete URL",
+				Code:      errCodeDatabaseError,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		if !found {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:     "URL not found",
+				Message:   fmt.Sprintf("No URL found for alias: %s", alias),
+				Code:      errCodeURLNotFound,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		log.Printf("Deleted %s (requested by %s)", alias, c.ClientIP())
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// updateRulesHandler replaces the redirect rules of a URL
+func updateRulesHandler(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alias := aliasParam(c)
+
+		if status, errResp := checkLinkOwner(config, alias, requestAPIKey(c)); errResp != nil {
+			c.JSON(status, errResp)
+			return
+		}
+
+		var req UpdateRulesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:     "Invalid request format",
+				Message:   err.Error(),
+				Code:      errCodeInvalidRequest,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		rules, err := validateRedirectRules(config, req.Rules)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:     "Invalid redirect rules",
+				Message:   err.Error(),
+				Code:      errCodeInvalidRules,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		found, err := UpdateURLRules(config, alias, rules)
+		if err != nil {
+			log.Printf("Database error updating rules for %s: %v", alias, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:     "Database error",
+				Message:   "Failed to update rules",
+				Code:      errCodeDatabaseError,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		if !found {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:     "URL not found",
+				Message:   fmt.Sprintf("No URL found for alias: %s", alias),
+				Code:      errCodeURLNotFound,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		log.Printf("Updated %d redirect rules for %s (requested by %s)", len(rules), alias, c.ClientIP())
+		if rules == nil {
+			rules = []RedirectRule{}
+		}
+		c.JSON(http.StatusOK, UpdateRulesRequest{Rules: rules})
+	}
+}
+
 func listURLsHandler(config *Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Parse pagination parameters
 		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-		if page < 1 {
-			page = 1
-		}
-		if limit < 1 || limit > 100 {
-			limit = 50
-		}
 
 		// Parse filter parameters
 		status := c.Query("status") // "active", "expired", or "all"
+		search := c.Query("q")
+		health := c.Query("health") // "ok", "broken", "unchecked", or all when empty
+
+		if errResp := checkHealthFilter(health); errResp != nil {
+			c.JSON(http.StatusBadRequest, errResp)
+			return
+		}
 
-		urls, err := GetAllURLs(config)
+		response, err := queryURLs(config, page, limit, status, search, health)
 		if err != nil {
 			log.Printf("Error retrieving URLs: %v", err)
 			c.JSON(http.StatusInternalServerError, ErrorResponse{
 				Error:     "Failed to retrieve URLs",
 				Message:   err.Error(),
-				Code:      "RETRIEVAL_ERROR",
+				Code:      errCodeRetrievalError,
 				Timestamp: time.Now(),
 			})
 			return
 		}
 
-		// Filter URLs based on status
-		var filteredURLs []URLData
-		for _, url := range urls {
-			switch status {
-			case "active":
-				if url.Clicks < url.MaxClicks {
-					filteredURLs = append(filteredURLs, url)
-				}
-			case "expired":
-				if url.Clicks >= url.MaxClicks {
-					filteredURLs = append(filteredURLs, url)
-				}
-			default:
-				filteredURLs = append(filteredURLs, url)
-			}
-		}
+		c.JSON(http.StatusOK, response)
+	}
+}
 
-		// Calculate pagination
-		totalURLs := len(filteredURLs)
-		totalPages := (totalURLs + limit - 1) / limit
-		start := (page - 1) * limit
-		end := start + limit
-		if end > totalURLs {
-			end = totalURLs
-		}
+// queryURLs filters, searches and paginates stored URLs. It backs both the
+// JSON listing endpoint and the admin dashboard.
+func queryURLs(config *Config, page, limit int, status, search, health string) (*ListURLsResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	urls, err := GetAllURLs(config)
+	if err != nil {
+		return nil, err
+	}
 
-		var paginatedURLs []URLData
-		if start < totalURLs {
-			paginatedURLs = filteredURLs[start:end]
+	filteredURLs := filterURLs(urls, status, search, health)
+
+	// Calculate pagination
+	totalURLs := len(filteredURLs)
+	totalPages := (totalURLs + limit - 1) / limit
+	start := (page - 1) * limit
+	end := start + limit
+	if end > totalURLs {
+		end = totalURLs
+	}
+
+	var paginatedURLs []URLData
+	if start < totalURLs {
+		paginatedURLs = filteredURLs[start:end]
+	}
+
+	return &ListURLsResponse{
+		URLs:       paginatedURLs,
+		Count:      len(paginatedURLs),
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}, nil
+}
+
+// checkHealthFilter rejects a health filter other than ok, broken,
+// unchecked or empty
+func checkHealthFilter(health string) *ErrorResponse {
+	if health == "" || health == linkHealthOK || health == linkHealthBroken || health == linkHealthUnchecked {
+		return nil
+	}
+
+	return &ErrorResponse{
+		Error:     "Invalid health filter",
+		Message:   fmt.Sprintf("health must be %q, %q or %q", linkHealthOK, linkHealthBroken, linkHealthUnchecked),
+		Code:      errCodeInvalidHealthFilter,
+		Details:   map[string]interface{}{"health": health},
+		Timestamp: time.Now(),
+	}
+}
+
+// filterURLs keeps the URLs matching a status ("active", "expired" or all),
+// a search term and a link health, shared by the HTTP and gRPC listings
+func filterURLs(urls []URLData, status, search, health string) []URLData {
+	search = strings.ToLower(strings.TrimSpace(search))
+	var filteredURLs []URLData
+	for _, url := range urls {
+		if search != "" &&
+			!strings.Contains(strings.ToLower(url.Alias), search) &&
+			!strings.Contains(strings.ToLower(url.URL), search) {
+			continue
 		}
 
-		response := ListURLsResponse{
-			URLs:       paginatedURLs,
-			Count:      len(paginatedURLs),
-			Page:       page,
-			Limit:      limit,
-			TotalPages: totalPages,
-			HasNext:    page < totalPages,
-			HasPrev:    page > 1,
+		if health != "" && url.Health != health {
+			continue
 		}
 
-		c.JSON(http.StatusOK, response)
+		switch status {
+		case "active":
+			if url.Clicks < url.MaxClicks {
+				filteredURLs = append(filteredURLs, url)
+			}
+		case "expired":
+			if url.Clicks >= url.MaxClicks {
+				filteredURLs = append(filteredURLs, url)
+			}
+		default:
+			filteredURLs = append(filteredURLs, url)
+		}
 	}
+	return filteredURLs
 }
 
 func notFoundHandler() gin.HandlerFunc {
@@ -543,8 +961,19 @@ func notFoundHandler() gin.HandlerFunc {
 		c.JSON(http.StatusNotFound, ErrorResponse{
 			Error:     "Endpoint not found",
 			Message:   fmt.Sprintf("The requested endpoint %s %s was not found", c.Request.Method, c.Request.URL.Path),
-			Code:      "ENDPOINT_NOT_FOUND",
+			Code:      errCodeEndpointNotFound,
 			Timestamp: time.Now(),
 		})
 	}
 }
+
+// recoveryHandler answers requests whose handler panicked with an error
+// response, after gin has logged the panic
+func recoveryHandler(c *gin.Context, err interface{}) {
+	c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{
+		Error:     "Internal server error",
+		Message:   "The request could not be completed",
+		Code:      errCodeInternalError,
+		Timestamp: time.Now(),
+	})
+}
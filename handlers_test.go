@@ -0,0 +1,201 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// benchmarkRedirect measures counted redirects of one link
+func benchmarkRedirect(b *testing.B) {
+	discardLogs(b)
+	config := newTestConfig(b)
+	startClickBuffer(config)
+	b.Cleanup(func() { stopClickBuffer(config) })
+
+	urlData := saveTestURL(b, config, "bench-link", "https://example.com/landing")
+	if _, err := config.GetDB().Exec("UPDATE urls SET max_clicks = ? WHERE alias = ?", 1<<40, urlData.Alias); err != nil {
+		b.Fatal(err)
+	}
+
+	router := gin.New()
+	router.GET("/:alias", redirectHandler(config))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/bench-link", nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) Firefox/140.0")
+		req.Header.Set("Accept-Language", "en")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusFound {
+			b.Fatalf("redirect status = %d, want %d", w.Code, http.StatusFound)
+		}
+	}
+}
+
+func BenchmarkRedirectStrict(b *testing.B) {
+	benchmarkRedirect(b)
+}
+
+func BenchmarkRedirectStrictNoCache(b *testing.B) {
+	b.Setenv("ALIAS_CACHE_SIZE", "0")
+	benchmarkRedirect(b)
+}
+
+func BenchmarkRedirectBuffered(b *testing.B) {
+	b.Setenv("CLICK_WRITES", clickWritesBuffered)
+	benchmarkRedirect(b)
+}
+
+func BenchmarkRedirectUnique(b *testing.B) {
+	b.Setenv("CLICK_COUNTING", "unique")
+	benchmarkRedirect(b)
+}
+
+// newRedirectTestRouter serves redirects like the server does, with the
+// error page templates
+func newRedirectTestRouter(config *Config) *gin.Engine {
+	router := gin.New()
+	router.SetFuncMap(templateFuncs())
+	router.LoadHTMLGlob("static/*.html")
+	router.Any("/:alias", redirectHandler(config))
+	return router
+}
+
+func TestRedirectBotClicks(t *testing.T) {
+	discardLogs(t)
+	config := newTestConfig(t)
+	router := newRedirectTestRouter(config)
+
+	const (
+		browser   = "Mozilla/5.0 (X11; Linux x86_64) Firefox/140.0"
+		curl      = "curl/8.7.1"
+		googlebot = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	)
+
+	tests := []struct {
+		name           string
+		method         string
+		userAgent      string
+		acceptLanguage string
+		wantCounted    bool
+	}{
+		{"browser", http.MethodGet, browser, "en", true},
+		{"curl", http.MethodGet, curl, "", true},
+		{"no user agent", http.MethodGet, "", "", true},
+		{"no accept-language", http.MethodGet, browser, "", true},
+		{"crawler signature", http.MethodGet, googlebot, "en", false},
+		{"head request", http.MethodHead, browser, "en", false},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alias := "bot-test-" + strconv.Itoa(i)
+			urlData := saveTestURL(t, config, alias, "https://example.com/landing")
+			if _, err := config.GetDB().Exec("UPDATE urls SET max_clicks = 1 WHERE alias = ?", alias); err != nil {
+				t.Fatal(err)
+			}
+			config.cache.invalidate(alias)
+
+			req := httptest.NewRequest(tt.method, "/"+urlData.Alias, nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusFound {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusFound)
+			}
+
+			// A counted hit used up the only click, so the link has expired
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+urlData.Alias, nil))
+			wantStatus := http.StatusFound
+			if tt.wantCounted {
+				wantStatus = http.StatusGone
+			}
+			if w.Code != wantStatus {
+				t.Errorf("status of the next hit = %d, want %d", w.Code, wantStatus)
+			}
+		})
+	}
+}
+
+func TestRedirectUniqueVisitors(t *testing.T) {
+	const (
+		firefox = "Mozilla/5.0 (X11; Linux x86_64) Firefox/140.0"
+		safari  = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15"
+	)
+
+	for _, clickWrites := range []string{clickWritesStrict, clickWritesBuffered} {
+		t.Run(clickWrites, func(t *testing.T) {
+			discardLogs(t)
+			t.Setenv("CLICK_COUNTING", "unique")
+			t.Setenv("CLICK_WRITES", clickWrites)
+			t.Setenv("CLICK_FLUSH_INTERVAL", "1h")
+			config := newTestConfig(t)
+			startClickBuffer(config)
+			t.Cleanup(func() { stopClickBuffer(config) })
+			router := newRedirectTestRouter(config)
+			saveTestURL(t, config, "unique-link", "https://example.com/landing")
+
+			visit := func(remoteAddr, userAgent string) {
+				t.Helper()
+				req := httptest.NewRequest(http.MethodGet, "/unique-link", nil)
+				req.RemoteAddr = remoteAddr
+				req.Header.Set("User-Agent", userAgent)
+				req.Header.Set("Accept-Language", "en")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if w.Code != http.StatusFound {
+					t.Fatalf("status = %d, want %d", w.Code, http.StatusFound)
+				}
+			}
+			clicks := func() int {
+				t.Helper()
+				urlData, err := GetURLByAlias(config, "unique-link")
+				if err != nil {
+					t.Fatalf("GetURLByAlias: %v", err)
+				}
+				return urlData.Clicks
+			}
+
+			visit("192.0.2.1:1234", firefox)
+			visit("192.0.2.1:5678", firefox)
+			if got := clicks(); got != 1 {
+				t.Errorf("clicks after a repeat visit = %d, want 1", got)
+			}
+
+			if clickWrites == clickWritesBuffered {
+				// The first hit must still be queued, so the repeat was
+				// caught by the buffer rather than the database
+				var stored int
+				if err := config.GetDB().QueryRow("SELECT COUNT(*) FROM click_events").Scan(&stored); err != nil {
+					t.Fatal(err)
+				}
+				if stored != 0 {
+					t.Fatalf("%d hits written before the flush, want them queued", stored)
+				}
+			}
+
+			// Another address or browser is another visitor
+			visit("192.0.2.2:1234", firefox)
+			visit("192.0.2.1:1234", safari)
+			if got := clicks(); got != 3 {
+				t.Errorf("clicks after two new visitors = %d, want 3", got)
+			}
+
+			// Still a repeat once the queued hits are written
+			config.clickBuffer.flush()
+			visit("192.0.2.1:1234", firefox)
+			if got := clicks(); got != 3 {
+				t.Errorf("clicks after a repeat visit = %d, want 3", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// newTestConfig loads the configuration like the server does, with a fresh
+// database in a temporary directory. Tests adjust settings with t.Setenv
+// before calling it.
+func newTestConfig(t testing.TB) *Config {
+	t.Helper()
+
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "urls.db"))
+	t.Setenv("GIN_MODE", "test")
+
+	// An empty config file keeps a local config.yaml out of the tests
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", configFile)
+
+	config := LoadConfig()
+	t.Cleanup(func() { config.CloseDB() })
+	return config
+}
+
+// saveTestURL stores a link that allows plenty of clicks
+func saveTestURL(t testing.TB, config *Config, alias, destination string) *URLData {
+	t.Helper()
+
+	urlData := &URLData{
+		Alias:       alias,
+		URL:         destination,
+		OriginalURL: destination,
+		MaxClicks:   1000,
+		CreatedAt:   time.Now(),
+	}
+	if err := SaveURL(config, urlData); err != nil {
+		t.Fatalf("SaveURL(%s): %v", alias, err)
+	}
+	return urlData
+}
+
+// discardLogs silences the standard logger for the rest of a test or
+// benchmark
+func discardLogs(t testing.TB) {
+	output := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(output) })
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxIdempotencyKeyLength bounds the Idempotency-Key header
+	maxIdempotencyKeyLength = 255
+
+	// maxIdempotentBodySize bounds the body of requests with an
+	// Idempotency-Key, which is read before the handler runs. A shorten
+	// request with rules, destinations and Open Graph overrides fits easily.
+	maxIdempotentBodySize = 64 << 10
+)
+
+// idempotentResponse is what is stored for an idempotency key
+type idempotentResponse struct {
+	requestHash string
+	status      int // 0 while the first request is still running
+	contentType string
+	body        []byte
+}
+
+// responseRecorder keeps a copy of the response body written through it
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotencyMiddleware makes requests with an Idempotency-Key header safe
+// to retry. The first response for a key is stored for IDEMPOTENCY_TTL,
+// and retries with the same key and body get it again without running the
+// handler. Reusing a key for a different request is refused. Keys are
+// scoped to the API key, or the client IP without one. Responses with a
+// 5xx status are not stored, so the request can be retried for real.
+func idempotencyMiddleware(config *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength || strings.IndexFunc(key, func(r rune) bool { return r < 0x20 || r > 0x7e }) >= 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{
+				Error:     "Invalid idempotency key",
+				Message:   fmt.Sprintf("Idempotency-Key must be at most %d printable ASCII characters", maxIdempotencyKeyLength),
+				Code:      errCodeInvalidIdempotencyKey,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+				Error:     "Request too large",
+				Message:   fmt.Sprintf("Requests with an Idempotency-Key may have a body of at most %d bytes", maxIdempotentBodySize),
+				Code:      errCodeRequestTooLarge,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{
+				Error:     "Invalid request format",
+				Message:   err.Error(),
+				Code:      errCodeInvalidRequest,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := idempotencyScope(c)
+		requestHash := idempotencyRequestHash(c.Request.Method, c.FullPath(), body)
+
+		stored, err := ClaimIdempotencyKey(config, scope, key, requestHash, config.IdempotencyTTL)
+		if err != nil {
+			log.Printf("Database error claiming idempotency key: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{
+				Error:     "Database error",
+				Message:   "Failed to check the idempotency key",
+				Code:      errCodeDatabaseError,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		if stored != nil {
+			serveStoredResponse(c, key, requestHash, stored)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// Release the key unless the response is stored, also when the
+		// handler panics
+		saved := false
+		defer func() {
+			if saved {
+				return
+			}
+			if err := ReleaseIdempotencyKey(config, scope, key); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}()
+
+		c.Next()
+
+		if recorder.Status() >= 500 {
+			return
+		}
+		if err := SaveIdempotentResponse(config, scope, key, recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			log.Printf("Warning: %v", err)
+			return
+		}
+		saved = true
+	}
+}
+
+// serveStoredResponse answers a request whose idempotency key was used
+// before: with the stored response when the request is the same, or with
+// an error when it differs or the first request has not finished
+func serveStoredResponse(c *gin.Context, key, requestHash string, stored *idempotentResponse) {
+	switch {
+	case stored.requestHash != requestHash:
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Error:     "Idempotency key reused",
+			Message:   "This Idempotency-Key was already used for a different request",
+			Code:      errCodeIdempotencyKeyReused,
+			Details:   map[string]interface{}{"idempotency_key": key},
+			Timestamp: time.Now(),
+		})
+
+	case stored.status == 0:
+		c.Header("Retry-After", "1")
+		c.AbortWithStatusJSON(http.StatusConflict, ErrorResponse{
+			Error:     "Idempotency key in use",
+			Message:   "A request with this Idempotency-Key is still being processed",
+			Code:      errCodeIdempotencyKeyInUse,
+			Details:   map[string]interface{}{"idempotency_key": key},
+			Timestamp: time.Now(),
+		})
+
+	default:
+		log.Printf("Replaying stored %d response for idempotency key %q", stored.status, key)
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(stored.status, stored.contentType, stored.body)
+		c.Abort()
+	}
+}
+
+// idempotencyScope returns who an idempotency key belongs to, so clients
+// cannot replay each other's responses
+func idempotencyScope(c *gin.Context) string {
+	if value, exists := c.Get(apiKeyContextKey); exists {
+		return fmt.Sprintf("key:%d", value.(*APIKey).ID)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// idempotencyRequestHash fingerprints a request, so a key reused for a
+// different route or body can be told apart from a retry
+func idempotencyRequestHash(method, route string, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", method, route)
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
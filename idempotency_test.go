@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idempotentRequest sends a POST with an Idempotency-Key header
+func idempotentRequest(router *gin.Engine, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	discardLogs(t)
+	config := newTestConfig(t)
+	router := gin.New()
+	registerRoutes(router, config)
+
+	body := `{"url": "https://example.com/report"}`
+	first := idempotentRequest(router, "/api/v1/shorten", "retry-1", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request status = %d, want %d: %s", first.Code, http.StatusCreated, first.Body)
+	}
+
+	retry := idempotentRequest(router, "/api/v1/shorten", "retry-1", body)
+	if retry.Code != first.Code || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %s, want the stored %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry is missing Idempotent-Replayed: true")
+	}
+
+	urls, err := GetAllURLs(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 1 {
+		t.Errorf("%d links stored, want 1", len(urls))
+	}
+
+	// Error responses are replayed as well
+	invalid := idempotentRequest(router, "/api/v1/shorten", "retry-2", `{"url": "not a url"}`)
+	replayed := idempotentRequest(router, "/api/v1/shorten", "retry-2", `{"url": "not a url"}`)
+	if invalid.Code != http.StatusBadRequest || replayed.Code != invalid.Code || replayed.Body.String() != invalid.Body.String() {
+		t.Errorf("replayed error = %d %s, want the stored %d %s", replayed.Code, replayed.Body, invalid.Code, invalid.Body)
+	}
+}
+
+func TestIdempotencyKeyReused(t *testing.T) {
+	discardLogs(t)
+	config := newTestConfig(t)
+	router := gin.New()
+	registerRoutes(router, config)
+
+	if w := idempotentRequest(router, "/api/v1/shorten", "reused", `{"url": "https://example.com/a"}`); w.Code != http.StatusCreated {
+		t.Fatalf("first request status = %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	w := idempotentRequest(router, "/api/v1/shorten", "reused", `{"url": "https://example.com/b"}`)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), errCodeIdempotencyKeyReused) {
+		t.Errorf("different body = %d %s, want %d %s", w.Code, w.Body, http.StatusUnprocessableEntity, errCodeIdempotencyKeyReused)
+	}
+
+	// The key is scoped to the route as well as the body
+	w = idempotentRequest(router, "/shorten", "reused", `{"url": "https://example.com/a"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different route status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestIdempotencyKeyInFlight(t *testing.T) {
+	discardLogs(t)
+	config := newTestConfig(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := gin.New()
+	router.POST("/slow", idempotencyMiddleware(config), func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"done": true})
+	})
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- idempotentRequest(router, "/slow", "slow-1", `{}`) }()
+	<-started
+
+	w := idempotentRequest(router, "/slow", "slow-1", `{}`)
+	if w.Code != http.StatusConflict || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("concurrent retry = %d with Retry-After %q, want %d with 1", w.Code, w.Header().Get("Retry-After"), http.StatusConflict)
+	}
+
+	close(release)
+	if w := <-first; w.Code != http.StatusCreated {
+		t.Errorf("first request status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if w := idempotentRequest(router, "/slow", "slow-1", `{}`); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry after completion = %d, want a replayed %d", w.Code, http.StatusCreated)
+	}
+}
+
+func TestIdempotencyServerErrorReleasesKey(t *testing.T) {
+	discardLogs(t)
+	config := newTestConfig(t)
+
+	var calls atomic.Int32
+	router := gin.New()
+	router.POST("/flaky", idempotencyMiddleware(config), func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "try again"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"done": true})
+	})
+
+	if w := idempotentRequest(router, "/flaky", "flaky-1", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("first request status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	w := idempotentRequest(router, "/flaky", "flaky-1", `{}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry = %d, replayed %q, want a fresh %d", w.Code, w.Header().Get("Idempotent-Replayed"), http.StatusCreated)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("handler ran %d times, want 2", calls.Load())
+	}
+}
+
+func TestIdempotencyBodyLimit(t *testing.T) {
+	discardLogs(t)
+	config := newTestConfig(t)
+	router := gin.New()
+	registerRoutes(router, config)
+
+	body := `{"url": "https://example.com/", "title": "` + strings.Repeat("x", maxIdempotentBodySize) + `"}`
+	w := idempotentRequest(router, "/api/v1/shorten", "large", body)
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), errCodeRequestTooLarge) {
+		t.Errorf("oversized body = %d %s, want %d %s", w.Code, w.Body, http.StatusRequestEntityTooLarge, errCodeRequestTooLarge)
+	}
+}
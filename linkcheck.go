@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Link health states reported in URLData.Health
+const (
+	linkHealthUnchecked = "unchecked"
+	linkHealthOK        = "ok"
+	linkHealthBroken    = "broken"
+)
+
+// linkCheckUserAgent identifies the checker to destination servers
+const linkCheckUserAgent = "url-shortener-linkcheck/1.0"
+
+// linkChecker periodically requests the destination of every active link
+// and records whether it still answers. Requests to one host are spaced by
+// LINK_CHECK_HOST_DELAY, at most LINK_CHECK_CONCURRENCY run at once.
+type linkChecker struct {
+	config *Config
+	client *http.Client
+
+	// mu guards nextRequest, the earliest time each host may be contacted,
+	// and the queue of the running round
+	mu          sync.Mutex
+	nextRequest map[string]time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// linkCheckTarget is an active link due for a check
+type linkCheckTarget struct {
+	alias string
+	url   string
+}
+
+// linkCheckQueue holds the targets of a round that are not checked yet,
+// grouped by host
+type linkCheckQueue map[string][]linkCheckTarget
+
+// linkCheckResult is the outcome of checking one destination
+type linkCheckResult struct {
+	status int    // Final HTTP status, 0 when no response was received
+	err    string // Network error, "" when a response was received
+}
+
+// broken reports whether the result means the destination is dead.
+// Authentication and rate limiting answers show that the server is alive.
+func (r linkCheckResult) broken() bool {
+	if r.err != "" {
+		return true
+	}
+	switch r.status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return false
+	}
+	return r.status >= 400
+}
+
+// startLinkChecker starts checking link destinations every
+// LINK_CHECK_INTERVAL, until stopLinkChecker is called
+func startLinkChecker(config *Config) {
+	if config.LinkCheckInterval <= 0 {
+		return
+	}
+
+	checker := newLinkChecker(config)
+	config.linkChecker = checker
+
+	go func() {
+		defer close(checker.done)
+
+		ticker := time.NewTicker(config.LinkCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			// Links checked during the previous round were stored slightly
+			// after it started, so allow half an interval of slack
+			checker.run(config.LinkCheckInterval / 2)
+			select {
+			case <-ticker.C:
+			case <-checker.stop:
+				return
+			}
+		}
+	}()
+
+	log.Printf("🩺 Link checker enabled, checking destinations every %v", config.LinkCheckInterval)
+}
+
+// newLinkChecker creates a link checker for the configured timeout and
+// concurrency
+func newLinkChecker(config *Config) *linkChecker {
+	dialer := safeDialer(config.LinkCheckTimeout, config.OutboundAllowPrivate)
+	return &linkChecker{
+		config: config,
+		client: &http.Client{
+			Timeout: config.LinkCheckTimeout,
+			Transport: &http.Transport{
+				Proxy:                 nil, // A proxy would dial on the checker's behalf
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   config.LinkCheckTimeout,
+				ResponseHeaderTimeout: config.LinkCheckTimeout,
+				MaxIdleConns:          config.LinkCheckConcurrency,
+				IdleConnTimeout:       30 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 10 {
+					return fmt.Errorf("stopped after 10 redirects")
+				}
+				return nil
+			},
+		},
+		nextRequest: make(map[string]time.Time),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// stopLinkChecker stops the link checker, waiting for running checks
+func stopLinkChecker(config *Config) {
+	checker := config.linkChecker
+	if checker == nil {
+		return
+	}
+
+	close(checker.stop)
+	<-checker.done
+}
+
+// run checks every active link that was not checked within the given age
+func (lc *linkChecker) run(age time.Duration) {
+	targets, err := GetLinkCheckTargets(lc.config, age)
+	if err != nil {
+		log.Printf("Warning: link check skipped: %v", err)
+		return
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	queue := make(linkCheckQueue)
+	for _, target := range targets {
+		host := linkCheckHost(target.url)
+		queue[host] = append(queue[host], target)
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	broken := 0
+
+	for i := 0; i < lc.config.LinkCheckConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				target, ok := lc.next(queue)
+				if !ok {
+					return
+				}
+
+				result := lc.check(target.url)
+				if err := SaveLinkCheck(lc.config, target.alias, result); err != nil {
+					log.Printf("Warning: failed to store link check for %s: %v", target.alias, err)
+				}
+				if result.broken() {
+					mu.Lock()
+					broken++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	log.Printf("🩺 Checked %d link destinations in %v, %d broken", len(targets), time.Since(start).Round(time.Millisecond), broken)
+}
+
+// next takes a queued target whose host may be contacted now and reserves
+// the host's following slot. Targets of hosts still waiting out
+// LINK_CHECK_HOST_DELAY stay queued, so one slow host does not hold up the
+// others; when every queued host is waiting, the worker sleeps until the
+// first is free. It returns false once the queue is empty or the checker
+// stops.
+func (lc *linkChecker) next(queue linkCheckQueue) (linkCheckTarget, bool) {
+	for {
+		select {
+		case <-lc.stop:
+			return linkCheckTarget{}, false
+		default:
+		}
+
+		lc.mu.Lock()
+		if len(queue) == 0 {
+			lc.mu.Unlock()
+			return linkCheckTarget{}, false
+		}
+
+		now := time.Now()
+		var earliest time.Time
+		for host, targets := range queue {
+			next := lc.nextRequest[host]
+			if !next.After(now) {
+				if len(targets) == 1 {
+					delete(queue, host)
+				} else {
+					queue[host] = targets[1:]
+				}
+				lc.nextRequest[host] = now.Add(lc.config.LinkCheckHostDelay)
+				lc.mu.Unlock()
+				return targets[0], true
+			}
+			if earliest.IsZero() || next.Before(earliest) {
+				earliest = next
+			}
+		}
+		lc.mu.Unlock()
+
+		select {
+		case <-time.After(time.Until(earliest)):
+		case <-lc.stop:
+			return linkCheckTarget{}, false
+		}
+	}
+}
+
+// linkCheckHost returns the host that LINK_CHECK_HOST_DELAY applies to
+func linkCheckHost(rawURL string) string {
+	if parsedURL, err := url.Parse(rawURL); err == nil {
+		return strings.ToLower(parsedURL.Host)
+	}
+	return rawURL
+}
+
+// check requests a destination with HEAD, falling back to GET for servers
+// that do not support HEAD
+func (lc *linkChecker) check(rawURL string) linkCheckResult {
+	result := lc.request(http.MethodHead, rawURL)
+	if result.err == "" && (result.status == http.StatusMethodNotAllowed || result.status == http.StatusNotImplemented) {
+		result = lc.request(http.MethodGet, rawURL)
+	}
+	return result
+}
+
+// request sends one request and reports the final status after redirects
+func (lc *linkChecker) request(method, rawURL string) linkCheckResult {
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return linkCheckResult{err: err.Error()}
+	}
+	req.Header.Set("User-Agent", linkCheckUserAgent)
+
+	resp, err := lc.client.Do(req)
+	if err != nil {
+		return linkCheckResult{err: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	// Read a little of the body so the connection can be reused
+	io.CopyN(io.Discard, resp.Body, 4096)
+	return linkCheckResult{status: resp.StatusCode}
+}
+
+// linkHealth derives the health state of a link from its last check
+func linkHealth(urlData *URLData) string {
+	switch {
+	case urlData.HealthCheckedAt == nil:
+		return linkHealthUnchecked
+	case urlData.HealthFailures > 0:
+		return linkHealthBroken
+	default:
+		return linkHealthOK
+	}
+}
+
+// isDeadLink reports whether redirects to a link should be refused because
+// its destination failed LINK_CHECK_FAILURE_THRESHOLD checks in a row
+func isDeadLink(config *Config, urlData *URLData) bool {
+	return config.LinkCheckBlockDead && urlData.HealthFailures >= config.LinkCheckFailureThreshold
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLinkCheckerCheck(t *testing.T) {
+	t.Setenv("OUTBOUND_ALLOW_PRIVATE", "true")
+	checker := newLinkChecker(newTestConfig(t))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantErr    bool
+		wantBroken bool
+	}{
+		{"/ok", http.StatusOK, false, false},
+		{"/missing", http.StatusNotFound, false, true},
+		{"/login", http.StatusUnauthorized, false, false},
+		{"/error", http.StatusBadGateway, false, true},
+		{"/get-only", http.StatusOK, false, false},
+		{"/moved", http.StatusOK, false, false},
+		{"/loop", 0, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result := checker.check(server.URL + tt.path)
+			if result.status != tt.wantStatus || (result.err != "") != tt.wantErr {
+				t.Errorf("check = {status: %d, err: %q}, want status %d, error %v", result.status, result.err, tt.wantStatus, tt.wantErr)
+			}
+			if result.broken() != tt.wantBroken {
+				t.Errorf("broken() = %v, want %v", result.broken(), tt.wantBroken)
+			}
+		})
+	}
+}
+
+func TestLinkCheckerRefusesPrivateAddresses(t *testing.T) {
+	checker := newLinkChecker(newTestConfig(t))
+
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+
+	result := checker.check(server.URL)
+	if requested {
+		t.Error("checker requested a loopback address without OUTBOUND_ALLOW_PRIVATE")
+	}
+	if !strings.Contains(result.err, errForbiddenAddress.Error()) {
+		t.Errorf("check error = %q, want %q", result.err, errForbiddenAddress)
+	}
+	if !result.broken() {
+		t.Error("refused check is not reported as broken")
+	}
+}
+
+func TestLinkCheckerIgnoresProxy(t *testing.T) {
+	checker := newLinkChecker(newTestConfig(t))
+
+	// A proxy would connect to private addresses on the checker's behalf
+	if checker.client.Transport.(*http.Transport).Proxy != nil {
+		t.Error("link checker transport uses a proxy")
+	}
+}
+
+// TestLinkCheckerRunSkipsWaitingHosts checks that a worker moves on to
+// another host while the next check of a host has to wait
+func TestLinkCheckerRunSkipsWaitingHosts(t *testing.T) {
+	const hostDelay = 400 * time.Millisecond
+	t.Setenv("OUTBOUND_ALLOW_PRIVATE", "true")
+	t.Setenv("LINK_CHECK_CONCURRENCY", "2")
+	t.Setenv("LINK_CHECK_HOST_DELAY", hostDelay.String())
+	config := newTestConfig(t)
+
+	var mu sync.Mutex
+	requests := make(map[string][]time.Time)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		host, _, _ := strings.Cut(r.Host, ":")
+		requests[host] = append(requests[host], time.Now())
+	}))
+	defer server.Close()
+
+	// 127.0.0.1 and localhost reach the same server as different hosts
+	busyHost := server.URL
+	otherHost := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	for _, alias := range []string{"busy-1", "busy-2", "busy-3"} {
+		saveTestURL(t, config, alias, busyHost+"/"+alias)
+	}
+	saveTestURL(t, config, "other-1", otherHost+"/other-1")
+
+	start := time.Now()
+	checker := newLinkChecker(config)
+	checker.run(0)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	busy := requests["127.0.0.1"]
+	if len(busy) != 3 {
+		t.Fatalf("busy host got %d requests, want 3", len(busy))
+	}
+	for i := 1; i < len(busy); i++ {
+		if gap := busy[i].Sub(busy[i-1]); gap < hostDelay-50*time.Millisecond {
+			t.Errorf("requests %d and %d to the busy host were %v apart, want at least %v", i, i+1, gap, hostDelay)
+		}
+	}
+
+	other := requests["localhost"]
+	if len(other) != 1 {
+		t.Fatalf("other host got %d requests, want 1", len(other))
+	}
+	if wait := other[0].Sub(start); wait >= hostDelay/2 {
+		t.Errorf("other host was checked after %v, waiting behind the busy host", wait)
+	}
+
+	for _, alias := range []string{"busy-1", "busy-2", "busy-3", "other-1"} {
+		urlData, err := GetURLByAlias(config, alias)
+		if err != nil {
+			t.Fatalf("GetURLByAlias(%s): %v", alias, err)
+		}
+		if urlData.Health != linkHealthOK {
+			t.Errorf("%s health = %q, want %q", alias, urlData.Health, linkHealthOK)
+		}
+	}
+}
+
+// TestLinkCheckerStopWhileWaiting checks that stopping the checker does not
+// wait out the host delay of queued checks
+func TestLinkCheckerStopWhileWaiting(t *testing.T) {
+	t.Setenv("OUTBOUND_ALLOW_PRIVATE", "true")
+	t.Setenv("LINK_CHECK_HOST_DELAY", "1h")
+	config := newTestConfig(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	saveTestURL(t, config, "first", server.URL+"/first")
+	saveTestURL(t, config, "second", server.URL+"/second")
+
+	checker := newLinkChecker(config)
+	done := make(chan struct{})
+	go func() {
+		checker.run(0)
+		close(done)
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	close(checker.stop)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after the checker was stopped")
+	}
+}
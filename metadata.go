@@ -0,0 +1,349 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// Limits for metadata stored on a link
+const (
+	maxMetaTitleLength       = 200
+	maxMetaDescriptionLength = 500
+	maxFaviconURLLength      = 2048
+)
+
+const (
+	metadataBatchSize    = 50 // Links read from the database at a time
+	metadataMaxRedirects = 5
+)
+
+// metadataUserAgent identifies the fetcher to destination servers. Some
+// sites only serve their real page to agents that look like a browser.
+const metadataUserAgent = "Mozilla/5.0 (compatible; url-shortener-preview/1.0)"
+
+// metadataFetcher fills in the title, description and favicon of links
+// whose destination has not been fetched yet. It works through pending
+// links whenever it is woken by a new link, and once at startup so links
+// created by the CLI or before an upgrade are included.
+type metadataFetcher struct {
+	config *Config
+	client *http.Client
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// metadataTarget is a link whose destination metadata is missing
+type metadataTarget struct {
+	alias string
+	url   string
+}
+
+// pageMetadata is what was learned about a destination page
+type pageMetadata struct {
+	title       string
+	description string
+	favicon     string
+	err         string // Why the page could not be read, "" on success
+}
+
+// startMetadataFetcher starts fetching destination metadata in the
+// background, until stopMetadataFetcher is called
+func startMetadataFetcher(config *Config) {
+	if !config.MetadataFetch {
+		return
+	}
+
+	dialer := safeDialer(config.MetadataTimeout, config.OutboundAllowPrivate)
+	fetcher := &metadataFetcher{
+		config: config,
+		client: &http.Client{
+			Timeout: config.MetadataTimeout,
+			Transport: &http.Transport{
+				Proxy:                 nil, // A proxy would dial on the fetcher's behalf
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   config.MetadataTimeout,
+				ResponseHeaderTimeout: config.MetadataTimeout,
+				MaxIdleConns:          config.MetadataWorkers,
+				IdleConnTimeout:       30 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= metadataMaxRedirects {
+					return fmt.Errorf("stopped after %d redirects", metadataMaxRedirects)
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	config.metadataFetcher = fetcher
+
+	go func() {
+		defer close(fetcher.done)
+
+		for {
+			fetcher.run()
+			select {
+			case <-fetcher.wake:
+			case <-fetcher.stop:
+				return
+			}
+		}
+	}()
+
+	log.Printf("🔖 Metadata fetcher enabled with %d workers", config.MetadataWorkers)
+}
+
+// stopMetadataFetcher stops the metadata fetcher, waiting for running fetches
+func stopMetadataFetcher(config *Config) {
+	fetcher := config.metadataFetcher
+	if fetcher == nil {
+		return
+	}
+
+	close(fetcher.stop)
+	<-fetcher.done
+}
+
+// wakeMetadataFetcher tells the fetcher that a link is waiting for its
+// metadata. It never blocks and does nothing when the fetcher is disabled.
+func wakeMetadataFetcher(config *Config) {
+	if fetcher := config.metadataFetcher; fetcher != nil {
+		select {
+		case fetcher.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run fetches metadata for pending links until none are left
+func (mf *metadataFetcher) run() {
+	for {
+		targets, err := GetMetadataTargets(mf.config, metadataBatchSize)
+		if err != nil {
+			log.Printf("Warning: metadata fetch skipped: %v", err)
+			return
+		}
+		if len(targets) == 0 {
+			return
+		}
+
+		start := time.Now()
+		jobs := make(chan metadataTarget)
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		saved := 0
+
+		for i := 0; i < mf.config.MetadataWorkers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for target := range jobs {
+					meta := mf.fetch(target.url)
+					if err := SaveMetadata(mf.config, target.alias, target.url, meta); err != nil {
+						log.Printf("Warning: failed to store metadata for %s: %v", target.alias, err)
+						continue
+					}
+					mu.Lock()
+					saved++
+					mu.Unlock()
+				}
+			}()
+		}
+
+		stopped := false
+	feed:
+		for _, target := range targets {
+			select {
+			case jobs <- target:
+			case <-mf.stop:
+				stopped = true
+				break feed
+			}
+		}
+		close(jobs)
+		wg.Wait()
+
+		log.Printf("🔖 Fetched metadata for %d of %d links in %v", saved, len(targets), time.Since(start).Round(time.Millisecond))
+
+		// Stop rather than retry the same links forever if nothing could be stored
+		if stopped || saved == 0 {
+			return
+		}
+	}
+}
+
+// fetch downloads the start of a destination page and extracts its metadata
+func (mf *metadataFetcher) fetch(rawURL string) pageMetadata {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return pageMetadata{err: err.Error()}
+	}
+	req.Header.Set("User-Agent", metadataUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+
+	resp, err := mf.client.Do(req)
+	if err != nil {
+		return pageMetadata{err: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return pageMetadata{err: fmt.Sprintf("destination answered HTTP %d", resp.StatusCode)}
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return pageMetadata{err: fmt.Sprintf("destination is not an HTML page (%s)", mediaType)}
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, int64(mf.config.MetadataMaxBytes)), contentType)
+	if err != nil {
+		return pageMetadata{err: err.Error()}
+	}
+
+	// Relative links resolve against the page that was finally served
+	return parseMetadata(body, resp.Request.URL)
+}
+
+// parseMetadata reads the title, description and favicon from the head of
+// an HTML page. Open Graph tags are used when the page lacks the standard
+// ones; without an icon link the favicon is the site's /favicon.ico.
+func parseMetadata(r io.Reader, pageURL *url.URL) pageMetadata {
+	var meta pageMetadata
+	var ogTitle, ogDescription, touchIcon string
+	base := pageURL
+	inTitle := false
+
+	tokenizer := html.NewTokenizer(r)
+parse:
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			break parse // End of the page or of the size limit
+
+		case html.TextToken:
+			if inTitle && meta.title == "" {
+				meta.title = string(tokenizer.Text())
+			}
+
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break parse
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "body":
+				break parse
+			case "title":
+				inTitle = tokenType == html.StartTagToken
+			case "base":
+				if href, err := base.Parse(attr(token, "href")); err == nil {
+					base = href
+				}
+			case "meta":
+				content := attr(token, "content")
+				if strings.EqualFold(attr(token, "name"), "description") {
+					meta.description = content
+				}
+				switch strings.ToLower(attr(token, "property")) {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDescription = content
+				}
+			case "link":
+				rels := strings.Fields(strings.ToLower(attr(token, "rel")))
+				href := attr(token, "href")
+				for _, rel := range rels {
+					if rel == "icon" && meta.favicon == "" {
+						meta.favicon = href
+					} else if rel == "apple-touch-icon" && touchIcon == "" {
+						touchIcon = href
+					}
+				}
+			}
+		}
+	}
+
+	if cleanText(meta.title) == "" {
+		meta.title = ogTitle
+	}
+	if cleanText(meta.description) == "" {
+		meta.description = ogDescription
+	}
+	if meta.favicon == "" {
+		meta.favicon = touchIcon
+	}
+
+	meta.title = truncateRunes(cleanText(meta.title), maxMetaTitleLength)
+	meta.description = truncateRunes(cleanText(meta.description), maxMetaDescriptionLength)
+	meta.favicon = faviconURL(base, meta.favicon)
+	return meta
+}
+
+// attr returns the value of a token's attribute, "" when it is missing
+func attr(token html.Token, name string) string {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// cleanText collapses runs of whitespace, including newlines, to one space
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// truncateRunes shortens s to at most limit characters, marking the cut
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
+
+// faviconURL resolves an icon link against the page. Icons that are not
+// served over http(s), such as data: URIs, fall back to /favicon.ico.
+func faviconURL(base *url.URL, href string) string {
+	if href != "" {
+		if icon, err := base.Parse(strings.TrimSpace(href)); err == nil &&
+			(icon.Scheme == "http" || icon.Scheme == "https") && len(icon.String()) <= maxFaviconURLLength {
+			return icon.String()
+		}
+	}
+
+	icon, err := base.Parse("/favicon.ico")
+	if err != nil {
+		return ""
+	}
+	return icon.String()
+}
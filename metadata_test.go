@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMetadata(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/blog/post?id=1")
+
+	tests := []struct {
+		name            string
+		page            string
+		wantTitle       string
+		wantDescription string
+		wantFavicon     string
+	}{
+		{
+			name:            "standard tags",
+			page:            `<html><head><title>Post</title><meta name="description" content="About the post"><link rel="icon" href="/icon.png"></head></html>`,
+			wantTitle:       "Post",
+			wantDescription: "About the post",
+			wantFavicon:     "https://example.com/icon.png",
+		},
+		{
+			name:            "open graph without standard tags",
+			page:            `<head><meta property="og:title" content="OG Post"><meta property="og:description" content="OG about"></head>`,
+			wantTitle:       "OG Post",
+			wantDescription: "OG about",
+			wantFavicon:     "https://example.com/favicon.ico",
+		},
+		{
+			name:        "title preferred over open graph",
+			page:        `<head><meta property="og:title" content="OG Post"><title>Post</title></head>`,
+			wantTitle:   "Post",
+			wantFavicon: "https://example.com/favicon.ico",
+		},
+		{
+			name:        "blank title falls back to open graph",
+			page:        "<head><title>\n  </title><meta property=\"og:title\" content=\"OG Post\"></head>",
+			wantTitle:   "OG Post",
+			wantFavicon: "https://example.com/favicon.ico",
+		},
+		{
+			name:        "whitespace collapsed",
+			page:        "<head><title>\n  A   long\n\ttitle  </title></head>",
+			wantTitle:   "A long title",
+			wantFavicon: "https://example.com/favicon.ico",
+		},
+		{
+			name:            "long text truncated",
+			page:            `<head><title>` + strings.Repeat("t", 300) + `</title><meta name="description" content="` + strings.Repeat("é", 600) + `"></head>`,
+			wantTitle:       strings.Repeat("t", maxMetaTitleLength-1) + "…",
+			wantDescription: strings.Repeat("é", maxMetaDescriptionLength-1) + "…",
+			wantFavicon:     "https://example.com/favicon.ico",
+		},
+		{
+			name:        "relative favicon",
+			page:        `<head><link rel="shortcut icon" href="../static/fav.ico"></head>`,
+			wantFavicon: "https://example.com/static/fav.ico",
+		},
+		{
+			name:        "favicon relative to base",
+			page:        `<head><base href="https://cdn.example.net/assets/"><link rel="icon" href="fav.svg"></head>`,
+			wantFavicon: "https://cdn.example.net/assets/fav.svg",
+		},
+		{
+			name:        "protocol-relative favicon",
+			page:        `<head><link rel="icon" href="//static.example.org/fav.png"></head>`,
+			wantFavicon: "https://static.example.org/fav.png",
+		},
+		{
+			name:        "apple touch icon without icon",
+			page:        `<head><link rel="apple-touch-icon" href="touch.png"></head>`,
+			wantFavicon: "https://example.com/blog/touch.png",
+		},
+		{
+			name:        "data URI favicon",
+			page:        `<head><link rel="icon" href="data:image/png;base64,AAAA"></head>`,
+			wantFavicon: "https://example.com/favicon.ico",
+		},
+		{
+			name:        "body tags ignored",
+			page:        `<head></head><body><title>Not the title</title></body>`,
+			wantFavicon: "https://example.com/favicon.ico",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := parseMetadata(strings.NewReader(tt.page), pageURL)
+			if meta.title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", meta.title, tt.wantTitle)
+			}
+			if meta.description != tt.wantDescription {
+				t.Errorf("description = %q, want %q", meta.description, tt.wantDescription)
+			}
+			if meta.favicon != tt.wantFavicon {
+				t.Errorf("favicon = %q, want %q", meta.favicon, tt.wantFavicon)
+			}
+		})
+	}
+}
+
+// newTestMetadataFetcher fetches with a plain client, since the test
+// servers listen on loopback
+func newTestMetadataFetcher(maxBytes int, timeout time.Duration) *metadataFetcher {
+	return &metadataFetcher{
+		config: &Config{MetadataMaxBytes: maxBytes},
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func TestMetadataFetchSizeLimit(t *testing.T) {
+	padding := strings.Repeat(" ", 4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<html><head><title>Early</title>%s<meta name="description" content="Late"></head></html>`, padding)
+	}))
+	defer server.Close()
+
+	meta := newTestMetadataFetcher(1024, 5*time.Second).fetch(server.URL)
+	if meta.err != "" {
+		t.Fatalf("fetch: %s", meta.err)
+	}
+	if meta.title != "Early" || meta.description != "" {
+		t.Errorf("fetched title %q and description %q, want only what fits in 1024 bytes", meta.title, meta.description)
+	}
+
+	meta = newTestMetadataFetcher(8192, 5*time.Second).fetch(server.URL)
+	if meta.description != "Late" {
+		t.Errorf("description = %q with a larger limit, want Late", meta.description)
+	}
+}
+
+func TestMetadataFetchTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<html><head><title>Slow</title><meta name="description" content="Read in time">%s`, strings.Repeat(" ", 2048))
+		w.(http.Flusher).Flush()
+
+		// Never finish the head. The start is longer than the 1 KiB the
+		// charset detection waits for.
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	start := time.Now()
+	meta := newTestMetadataFetcher(1<<20, 200*time.Millisecond).fetch(server.URL)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("fetch took %v, the timeout did not stop it", elapsed)
+	}
+	if meta.title != "Slow" || meta.description != "Read in time" {
+		t.Errorf("fetched title %q and description %q, want the tags read before the timeout", meta.title, meta.description)
+	}
+}
+
+func TestMetadataFetchErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		}
+	}))
+	defer server.Close()
+
+	fetcher := newTestMetadataFetcher(1<<20, 5*time.Second)
+	if meta := fetcher.fetch(server.URL + "/missing"); !strings.Contains(meta.err, "404") {
+		t.Errorf("404 page: err = %q", meta.err)
+	}
+	if meta := fetcher.fetch(server.URL + "/image"); !strings.Contains(meta.err, "not an HTML page") {
+		t.Errorf("image: err = %q", meta.err)
+	}
+}
@@ -4,14 +4,33 @@ import "time"
 
 // ShortenRequest represents the request payload for shortening URLs
 type ShortenRequest struct {
-	URL   string `json:"url" binding:"required"`
-	Alias string `json:"alias,omitempty"`
+	URL         string `json:"url"`
+	Alias       string `json:"alias,omitempty"`
+	AliasStyle  string `json:"alias_style,omitempty"` // Generated alias style: random, words, or hashid when configured
+	MaxClicks   *int   `json:"max_clicks,omitempty"`
+	BotResponse string `json:"bot_response,omitempty"` // "redirect" (default) or "metadata"
+
+	// Open Graph overrides served to social crawlers instead of the redirect
+	OGTitle       string `json:"og_title,omitempty"`
+	OGDescription string `json:"og_description,omitempty"`
+	OGImage       string `json:"og_image,omitempty"`
+
+	// Rules choosing another destination by country, device, language or time
+	Rules []RedirectRule `json:"rules,omitempty"`
+
+	// Weighted A/B destinations; url defaults to the first one when omitted
+	Destinations []Destination `json:"destinations,omitempty"`
+
+	// App deep links opened on iOS and Android instead of the web destination
+	DeepLink *DeepLink `json:"deep_link,omitempty"`
 }
 
 // ShortenResponse represents the response for shortened URLs
 type ShortenResponse struct {
 	ShortURL    string    `json:"short_url"`
 	OriginalURL string    `json:"original_url"`
+	DisplayURL  string    `json:"display_url"`         // OriginalURL with an internationalized domain in Unicode
+	FinalURL    string    `json:"final_url,omitempty"` // Where OriginalURL redirects to, when RESOLVE_REDIRECTS is on
 	Alias       string    `json:"alias"`
 	CreatedAt   time.Time `json:"created_at"`
 	MaxClicks   int       `json:"max_clicks"`
@@ -20,37 +39,132 @@ type ShortenResponse struct {
 
 // StatsResponse represents statistics about the URL shortener
 type StatsResponse struct {
-	TotalURLs   int `json:"total_urls"`
-	TotalClicks int `json:"total_clicks"`
-	ActiveURLs  int `json:"active_urls"`
-	ExpiredURLs int `json:"expired_urls"`
+	TotalURLs    int `json:"total_urls"`
+	TotalClicks  int `json:"total_clicks"`
+	TotalHits    int `json:"total_hits"`
+	TotalBotHits int `json:"total_bot_hits"` // Reported separately, not part of the totals above
+	ActiveURLs   int `json:"active_urls"`
+	ExpiredURLs  int `json:"expired_urls"`
 }
 
 // URLData represents the stored URL data
 type URLData struct {
 	Alias       string    `json:"alias"`
 	URL         string    `json:"url"`
-	OriginalURL string    `json:"original_url"` // Same as URL, for compatibility
+	OriginalURL string    `json:"original_url"`        // Same as URL, for compatibility
+	FinalURL    string    `json:"final_url,omitempty"` // Where URL redirects to, when RESOLVE_REDIRECTS is on
 	ShortURL    string    `json:"short_url"`
 	Clicks      int       `json:"clicks"`
+	Hits        int       `json:"hits"` // All human hits, including ones not counted toward MaxClicks
+	BotHits     int       `json:"bot_hits"`
+	BotResponse string    `json:"bot_response"`
 	MaxClicks   int       `json:"max_clicks"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	OGTitle       string `json:"og_title,omitempty"`
+	OGDescription string `json:"og_description,omitempty"`
+	OGImage       string `json:"og_image,omitempty"`
+
+	Rules        []RedirectRule `json:"rules,omitempty"`
+	Destinations []Destination  `json:"destinations,omitempty"` // Only loaded for single URLs
+	DeepLink     *DeepLink      `json:"deep_link,omitempty"`
+
+	// Destination health from the link checker
+	Health          string     `json:"health"` // unchecked, ok or broken
+	HealthStatus    int        `json:"health_status,omitempty"`
+	HealthError     string     `json:"health_error,omitempty"`
+	HealthCheckedAt *time.Time `json:"health_checked_at,omitempty"`
+	HealthFailures  int        `json:"health_failures,omitempty"` // Failed checks in a row
+
+	// Destination page metadata from the metadata fetcher
+	Title             string     `json:"title,omitempty"`
+	Description       string     `json:"description,omitempty"`
+	FaviconURL        string     `json:"favicon_url,omitempty"`
+	MetadataError     string     `json:"metadata_error,omitempty"`
+	MetadataFetchedAt *time.Time `json:"metadata_fetched_at,omitempty"`
+
+	aliasStyle  string // Strategy for a generated alias, "" for ALIAS_STRATEGY
+	aliasPrefix string // Prefix of a generated alias, from the creating API key
+	apiKeyID    int64  // API key that created the link, 0 for none
+}
+
+// URLInfoResponse represents detailed information about a single URL
+type URLInfoResponse struct {
+	Alias           string    `json:"alias"`
+	OriginalURL     string    `json:"original_url"`
+	DisplayURL      string    `json:"display_url"` // OriginalURL with an internationalized domain in Unicode
+	FinalURL        string    `json:"final_url,omitempty"`
+	ShortURL        string    `json:"short_url"`
+	Clicks          int       `json:"clicks"`
+	Hits            int       `json:"hits"`
+	BotHits         int       `json:"bot_hits"`
+	BotResponse     string    `json:"bot_response"`
+	MaxClicks       int       `json:"max_clicks"`
+	RemainingClicks int       `json:"remaining_clicks"`
+	CreatedAt       time.Time `json:"created_at"`
+	IsExpired       bool      `json:"is_expired"`
+	UsagePercentage float64   `json:"usage_percentage"`
+	OGTitle         string    `json:"og_title,omitempty"`
+	OGDescription   string    `json:"og_description,omitempty"`
+	OGImage         string    `json:"og_image,omitempty"`
+
+	Rules    []RedirectRule `json:"rules,omitempty"`
+	RuleHits map[string]int `json:"rule_hits,omitempty"` // Counted clicks per matched rule
+
+	Destinations []DestinationInfo `json:"destinations,omitempty"`
+	DeepLink     *DeepLink         `json:"deep_link,omitempty"`
+
+	Health          string     `json:"health"` // unchecked, ok or broken
+	HealthStatus    int        `json:"health_status,omitempty"`
+	HealthError     string     `json:"health_error,omitempty"`
+	HealthCheckedAt *time.Time `json:"health_checked_at,omitempty"`
+
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	FaviconURL  string `json:"favicon_url,omitempty"`
+}
+
+// CleanupResponse represents the result of removing expired URLs
+type CleanupResponse struct {
+	Message      string    `json:"message"`
+	DeletedCount int       `json:"deleted_count"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// APIKey represents a stored API key (the secret itself is never stored)
+type APIKey struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	AliasPrefix string     `json:"alias_prefix,omitempty"` // Aliases created with this key must start with it
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
 }
 
 // HealthResponse represents the health check response
 type HealthResponse struct {
-	Status    string      `json:"status"`
-	Timestamp time.Time   `json:"timestamp"`
-	Version   string      `json:"version"`
-	Mode      string      `json:"mode"`
-	Config    interface{} `json:"config,omitempty"`
+	Status    string       `json:"status"` // healthy or unhealthy
+	Timestamp time.Time    `json:"timestamp"`
+	Version   string       `json:"version"`
+	Database  string       `json:"database"` // connected, disconnected or not_configured
+	Error     string       `json:"error,omitempty"`
+	Stats     *HealthStats `json:"stats,omitempty"`
+}
+
+// HealthStats summarizes the stored URLs in the health check response
+type HealthStats struct {
+	TotalURLs   int `json:"total_urls"`
+	TotalClicks int `json:"total_clicks"`
+	ActiveURLs  int `json:"active_urls"`
+	ExpiredURLs int `json:"expired_urls"`
 }
 
-// ErrorResponse represents an error response
+// ErrorResponse represents an error response. Code is one of the codes
+// listed in errorCodes.
 type ErrorResponse struct {
 	Error     string      `json:"error"`
 	Message   string      `json:"message,omitempty"`
-	Code      string      `json:"code,omitempty"`
+	Code      string      `json:"code"`
 	Details   interface{} `json:"details,omitempty"`
 	Timestamp time.Time   `json:"timestamp"`
 }
@@ -65,3 +179,90 @@ type ListURLsResponse struct {
 	HasNext    bool      `json:"has_next"`
 	HasPrev    bool      `json:"has_prev"`
 }
+
+// ClickEvent represents a single hit on a short URL
+type ClickEvent struct {
+	Alias       string
+	VisitorHash string
+	UserAgent   string
+	Referrer    string
+	Counted     bool   // Whether the hit counted toward max_clicks
+	Bot         bool   // Whether the hit was classified as a bot
+	Rule        string // Name of the redirect rule that chose the destination
+	Variant     int    // 1-based A/B destination that was chosen, 0 for none
+
+	ClickedAt time.Time // Set when the hit is buffered, zero for the current time
+}
+
+// LinkEvent is sent to /api/v1/events subscribers when a link is created,
+// clicked, or used up by its last allowed click
+type LinkEvent struct {
+	ID          uint64    `json:"id"`
+	Type        string    `json:"type"` // link.created, link.clicked or link.expired
+	Alias       string    `json:"alias"`
+	ShortURL    string    `json:"short_url"`
+	Destination string    `json:"destination"` // Where the visitor was sent, or the link's destination when created
+	Clicks      int       `json:"clicks"`
+	MaxClicks   int       `json:"max_clicks"`
+	Owner       int64     `json:"owner,omitempty"` // ID of the API key that created the link
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// ClickBucket represents the number of clicks on a single day
+type ClickBucket struct {
+	Day    string `json:"day"`
+	Clicks int    `json:"clicks"`
+}
+
+// RedirectRule sends matching visitors to another destination. All given
+// conditions must match; within a condition any listed value may match.
+type RedirectRule struct {
+	Name        string          `json:"name,omitempty"`
+	Destination string          `json:"destination"`
+	Countries   []string        `json:"countries,omitempty"` // ISO 3166-1 alpha-2, needs GEOIP_DB_PATH
+	Devices     []string        `json:"devices,omitempty"`   // mobile, tablet, desktop
+	OS          []string        `json:"os,omitempty"`        // ios, android, windows, macos, linux, chromeos
+	Languages   []string        `json:"languages,omitempty"` // Accept-Language tags, "de" also matches "de-AT"
+	TimeWindow  *RuleTimeWindow `json:"time_window,omitempty"`
+}
+
+// RuleTimeWindow restricts a rule to a period and/or daily hours
+type RuleTimeWindow struct {
+	From     *time.Time `json:"from,omitempty"`
+	Until    *time.Time `json:"until,omitempty"`
+	Days     []string   `json:"days,omitempty"`     // sun..sat
+	Start    string     `json:"start,omitempty"`    // Daily start, HH:MM
+	End      string     `json:"end,omitempty"`      // Daily end, HH:MM; may wrap past midnight
+	Timezone string     `json:"timezone,omitempty"` // IANA name for days and hours, default UTC
+
+	location *time.Location // Timezone, loaded once when validated or decoded
+}
+
+// UpdateRulesRequest replaces all redirect rules of a link
+type UpdateRulesRequest struct {
+	Rules []RedirectRule `json:"rules"`
+}
+
+// Destination is one weighted variant of an A/B split link
+type Destination struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight,omitempty"` // Relative share of new visitors, default 1
+	Clicks int    `json:"clicks"`
+}
+
+// DestinationInfo reports the traffic a single A/B destination received
+type DestinationInfo struct {
+	Variant     int     `json:"variant"` // 1-based position
+	URL         string  `json:"url"`
+	Weight      int     `json:"weight"`
+	WeightShare float64 `json:"weight_share"` // Percentage of traffic the weight targets
+	Clicks      int     `json:"clicks"`
+	ClickShare  float64 `json:"click_share"` // Percentage of variant clicks actually received
+}
+
+// DeepLink opens a link's content in a mobile app
+type DeepLink struct {
+	IOSURL      string `json:"ios_url,omitempty"`      // Custom scheme URI, e.g. myapp://item/42
+	AndroidURL  string `json:"android_url,omitempty"`  // Intent URL or custom scheme URI
+	FallbackURL string `json:"fallback_url,omitempty"` // Web page used when the app does not open
+}
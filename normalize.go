@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// URL_STRIP_TRACKING policies
+const (
+	stripTrackingNone     = "none"      // Keep every query parameter
+	stripTrackingClickIDs = "click_ids" // Drop ad click identifiers such as fbclid and gclid
+	stripTrackingAll      = "all"       // Also drop campaign parameters such as utm_source
+)
+
+// clickIDParams are query parameters ad networks append to identify a click
+var clickIDParams = []string{
+	"fbclid", "gclid", "gclsrc", "dclid", "gbraid", "wbraid", "msclkid",
+	"yclid", "twclid", "ttclid", "li_fat_id", "igshid", "mc_eid",
+}
+
+// campaignParams are query parameters describing a marketing campaign
+var campaignParams = []string{
+	"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content",
+	"utm_id", "mc_cid", "_hsenc", "_hsmi",
+}
+
+// schemePrefix matches a leading URI scheme such as "javascript:"
+var schemePrefix = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*):`)
+
+// defaultPorts maps schemes to the port they imply
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// urlNormalizationSteps canonicalize a parsed URL, in order. Two URLs that
+// lead to the same resource should come out identical, so the result can be
+// used for deduplication.
+var urlNormalizationSteps = []func(u *url.URL, policy URLNormalization) error{
+	normalizeScheme,
+	normalizeHost,
+	normalizePath,
+	normalizeFragment,
+	normalizeQuery,
+}
+
+// normalizeURL parses rawURL and returns its canonical form. URLs without
+// a scheme get policy.DefaultScheme; schemes other than http and https are
+// rejected.
+func normalizeURL(rawURL string, policy URLNormalization) (*url.URL, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return nil, fmt.Errorf("URL cannot be empty")
+	}
+
+	// Add scheme if missing. "host:port" looks like a scheme but is not one.
+	if strings.HasPrefix(rawURL, "//") {
+		rawURL = policy.DefaultScheme + ":" + rawURL
+	} else if !strings.Contains(rawURL, "://") {
+		if match := schemePrefix.FindStringSubmatch(rawURL); match != nil && !startsWithDigit(rawURL[len(match[0]):]) {
+			return nil, fmt.Errorf("unsupported URL scheme %q (only http and https are allowed)", match[1])
+		}
+		rawURL = policy.DefaultScheme + "://" + rawURL
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL format: %v", err)
+	}
+
+	for _, step := range urlNormalizationSteps {
+		if err := step(parsedURL, policy); err != nil {
+			return nil, err
+		}
+	}
+	return parsedURL, nil
+}
+
+// startsWithDigit reports whether s begins with an ASCII digit
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+// normalizeScheme lowercases the scheme and rejects anything but http(s)
+func normalizeScheme(u *url.URL, policy URLNormalization) error {
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q (only http and https are allowed)", u.Scheme)
+	}
+	return nil
+}
+
+// normalizeHost lowercases the host, converts internationalized domain
+// names to punycode and drops the scheme's default port
+func normalizeHost(u *url.URL, policy URLNormalization) error {
+	hostname := u.Hostname()
+	if hostname == "" {
+		return fmt.Errorf("URL must have a valid host")
+	}
+
+	host, err := asciiHost(strings.ToLower(hostname))
+	if err != nil {
+		return err
+	}
+
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]" // IPv6
+	default:
+		u.Host = host
+	}
+	return nil
+}
+
+// normalizePath gives URLs without a path the root path and removes "."
+// and ".." segments, as browsers do
+func normalizePath(u *url.URL, policy URLNormalization) error {
+	if u.Opaque != "" {
+		return nil
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+		return nil
+	}
+
+	// Resolving the path against the URL itself removes dot segments
+	// without touching the rest of the path
+	if strings.Contains(u.Path, ".") {
+		resolved := u.ResolveReference(&url.URL{Path: u.Path, RawPath: u.RawPath})
+		u.Path, u.RawPath = resolved.Path, resolved.RawPath
+	}
+	return nil
+}
+
+// normalizeFragment removes the fragment when configured. Fragments never
+// reach the destination server, but single-page apps may route by them.
+func normalizeFragment(u *url.URL, policy URLNormalization) error {
+	if policy.StripFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+	return nil
+}
+
+// normalizeQuery drops tracking parameters by policy, re-encodes the rest
+// consistently and sorts them by name. Parameters sharing a name keep
+// their order, since servers may read them as a list.
+func normalizeQuery(u *url.URL, policy URLNormalization) error {
+	u.ForceQuery = false
+	if u.RawQuery == "" {
+		return nil
+	}
+
+	type param struct {
+		name    string
+		encoded string
+	}
+
+	var params []param
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+
+		rawName, rawValue, hasValue := strings.Cut(pair, "=")
+		name, nameErr := url.QueryUnescape(rawName)
+		value, valueErr := url.QueryUnescape(rawValue)
+		if nameErr != nil || valueErr != nil {
+			// Keep parameters that are not valid query encoding untouched
+			params = append(params, param{name: rawName, encoded: pair})
+			continue
+		}
+
+		if isTrackingParam(name, policy.StripTracking) {
+			continue
+		}
+
+		encoded := url.QueryEscape(name)
+		if hasValue {
+			encoded += "=" + url.QueryEscape(value)
+		}
+		params = append(params, param{name: name, encoded: encoded})
+	}
+
+	sort.SliceStable(params, func(i, j int) bool {
+		return params[i].name < params[j].name
+	})
+
+	encoded := make([]string, len(params))
+	for i, p := range params {
+		encoded[i] = p.encoded
+	}
+	u.RawQuery = strings.Join(encoded, "&")
+	return nil
+}
+
+// isTrackingParam reports whether a query parameter is removed under the
+// given URL_STRIP_TRACKING policy
+func isTrackingParam(name, policy string) bool {
+	name = strings.ToLower(name)
+	switch policy {
+	case stripTrackingClickIDs:
+		return slices.Contains(clickIDParams, name)
+	case stripTrackingAll:
+		return slices.Contains(clickIDParams, name) || slices.Contains(campaignParams, name)
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package main
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	defaults := URLNormalization{DefaultScheme: "http", StripTracking: stripTrackingNone}
+	withPolicy := func(change func(*URLNormalization)) URLNormalization {
+		policy := defaults
+		change(&policy)
+		return policy
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		policy URLNormalization
+		want   string
+	}{
+		// Case
+		{"lowercase scheme and host", "HTTPS://Example.COM/Path", defaults, "https://example.com/Path"},
+		{"path case kept", "https://example.com/CaseSensitive/Page", defaults, "https://example.com/CaseSensitive/Page"},
+
+		// Missing schemes
+		{"default scheme", "example.com/page", defaults, "http://example.com/page"},
+		{"configured default scheme", "example.com", withPolicy(func(p *URLNormalization) { p.DefaultScheme = "https" }), "https://example.com/"},
+		{"scheme-relative", "//example.com/page", defaults, "http://example.com/page"},
+		{"host and port without scheme", "localhost:8080/page", defaults, "http://localhost:8080/page"},
+		{"surrounding whitespace", "  https://example.com/page \n", defaults, "https://example.com/page"},
+
+		// Ports
+		{"default http port", "http://example.com:80/page", defaults, "http://example.com/page"},
+		{"default https port", "https://example.com:443", defaults, "https://example.com/"},
+		{"other port kept", "https://example.com:8443/", defaults, "https://example.com:8443/"},
+		{"https port on http kept", "http://example.com:443/", defaults, "http://example.com:443/"},
+		{"IPv6 default port", "http://[::1]:80/", defaults, "http://[::1]/"},
+		{"IPv6 other port", "http://[::1]:8080/", defaults, "http://[::1]:8080/"},
+
+		// Paths and dot segments
+		{"empty path", "https://example.com", defaults, "https://example.com/"},
+		{"dot segments", "https://example.com/a/./b/../c", defaults, "https://example.com/a/c"},
+		{"trailing dot-dot", "https://example.com/a/b/..", defaults, "https://example.com/a/"},
+		{"dot-dot above root", "https://example.com/../a", defaults, "https://example.com/a"},
+		{"trailing slash kept", "https://example.com/a/b/", defaults, "https://example.com/a/b/"},
+		{"dots in names kept", "https://example.com/v1.2/file.tar.gz", defaults, "https://example.com/v1.2/file.tar.gz"},
+		{"escaped path kept", "https://example.com/a%2Fb/./c", defaults, "https://example.com/a%2Fb/c"},
+
+		// Query ordering and encoding
+		{"sorted query", "https://example.com/?b=2&a=1", defaults, "https://example.com/?a=1&b=2"},
+		{"repeated parameters keep order", "https://example.com/?b=2&a=1&b=1", defaults, "https://example.com/?a=1&b=2&b=1"},
+		{"consistent encoding", "https://example.com/?q=a%20b&x=%7e", defaults, "https://example.com/?q=a+b&x=~"},
+		{"parameter without value", "https://example.com/?debug&a=1", defaults, "https://example.com/?a=1&debug"},
+		{"empty query dropped", "https://example.com/page?", defaults, "https://example.com/page"},
+		{"empty pairs dropped", "https://example.com/?a=1&&b=2&", defaults, "https://example.com/?a=1&b=2"},
+		{"invalid encoding kept", "https://example.com/?b=%zz&a=1", defaults, "https://example.com/?a=1&b=%zz"},
+
+		// Tracking parameters
+		{"tracking kept by default", "https://example.com/?utm_source=x&fbclid=1", defaults, "https://example.com/?fbclid=1&utm_source=x"},
+		{"click ids stripped", "https://example.com/?utm_source=x&fbclid=1&gclid=2&id=3",
+			withPolicy(func(p *URLNormalization) { p.StripTracking = stripTrackingClickIDs }), "https://example.com/?id=3&utm_source=x"},
+		{"all tracking stripped", "https://example.com/?utm_source=x&FBCLID=1&id=3",
+			withPolicy(func(p *URLNormalization) { p.StripTracking = stripTrackingAll }), "https://example.com/?id=3"},
+
+		// Fragments
+		{"fragment kept", "https://example.com/app#/settings", defaults, "https://example.com/app#/settings"},
+		{"fragment stripped", "https://example.com/page#section",
+			withPolicy(func(p *URLNormalization) { p.StripFragment = true }), "https://example.com/page"},
+
+		// Internationalized domain names
+		{"IDN to punycode", "https://Bücher.de/katalog", defaults, "https://xn--bcher-kva.de/katalog"},
+		{"IDN with port", "http://bücher.de:80/", defaults, "http://xn--bcher-kva.de/"},
+		{"punycode unchanged", "https://xn--bcher-kva.de/", defaults, "https://xn--bcher-kva.de/"},
+		{"Cyrillic domain", "https://пример.рф/", defaults, "https://xn--e1afmkfd.xn--p1ai/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeURL(tt.input, tt.policy)
+			if err != nil {
+				t.Fatalf("normalizeURL(%q) error: %v", tt.input, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("normalizeURL(%q) = %q, want %q", tt.input, got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeURLEquivalentForms(t *testing.T) {
+	policy := URLNormalization{DefaultScheme: "http", StripTracking: stripTrackingClickIDs}
+	forms := []string{
+		"Example.com?b=2&a=1",
+		"http://example.com:80/?a=1&b=2",
+		"HTTP://EXAMPLE.COM/./?b=2&a=1&fbclid=abc",
+		"//example.com/x/..?a=1&b=2",
+	}
+
+	want := ""
+	for _, form := range forms {
+		got, err := normalizeURL(form, policy)
+		if err != nil {
+			t.Fatalf("normalizeURL(%q) error: %v", form, err)
+		}
+		if want == "" {
+			want = got.String()
+		} else if got.String() != want {
+			t.Errorf("normalizeURL(%q) = %q, want %q like %q", form, got.String(), want, forms[0])
+		}
+	}
+}
+
+func TestNormalizeURLRejects(t *testing.T) {
+	policy := URLNormalization{DefaultScheme: "https", StripTracking: stripTrackingNone}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"whitespace only", "   "},
+		{"javascript", "javascript:alert(1)"},
+		{"javascript uppercase", "JavaScript:alert(1)"},
+		{"mailto", "mailto:someone@example.com"},
+		{"data", "data:text/html,<script>alert(1)</script>"},
+		{"ftp", "ftp://example.com/file"},
+		{"file", "file:///etc/passwd"},
+		{"custom app scheme", "myapp://open"},
+		{"missing host", "http://"},
+		{"missing host with path", "https:///path"},
+		{"invalid port", "http://example.com:port/"},
+		{"space in host", "https://exa mple.com/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := normalizeURL(tt.input, policy); err == nil {
+				t.Errorf("normalizeURL(%q) = %q, want an error", tt.input, got)
+			}
+		})
+	}
+}
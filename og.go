@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits for Open Graph overrides, roughly what social networks display
+const (
+	maxOGTitleLength       = 200
+	maxOGDescriptionLength = 500
+)
+
+// socialCrawlerSignatures are lowercase User-Agent fragments of crawlers that
+// build link previews from Open Graph tags
+var socialCrawlerSignatures = []string{
+	"facebookexternalhit", "facebookcatalog", "meta-externalagent",
+	"twitterbot", "linkedinbot", "slackbot", "slack-imgproxy", "discordbot",
+	"telegrambot", "whatsapp", "skypeuripreview", "pinterest", "redditbot",
+	"embedly", "vkshare", "iframely", "mastodon", "bluesky", "snapchat",
+}
+
+// isSocialCrawler reports whether a User-Agent belongs to a link preview crawler
+func isSocialCrawler(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, signature := range socialCrawlerSignatures {
+		if strings.Contains(userAgent, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOpenGraph reports whether a link overrides its social preview
+func hasOpenGraph(urlData *URLData) bool {
+	return urlData.OGTitle != "" || urlData.OGDescription != "" || urlData.OGImage != ""
+}
+
+// validateOpenGraph checks Open Graph overrides and returns them trimmed,
+// with the image URL sanitized
+func validateOpenGraph(config *Config, title, description, image string) (string, string, string, error) {
+	title = strings.TrimSpace(title)
+	description = strings.TrimSpace(description)
+	image = strings.TrimSpace(image)
+
+	if utf8.RuneCountInString(title) > maxOGTitleLength {
+		return "", "", "", fmt.Errorf("og_title must be at most %d characters", maxOGTitleLength)
+	}
+	if utf8.RuneCountInString(description) > maxOGDescriptionLength {
+		return "", "", "", fmt.Errorf("og_description must be at most %d characters", maxOGDescriptionLength)
+	}
+
+	if image != "" {
+		sanitized, err := sanitizeURL(config, image)
+		if err != nil {
+			return "", "", "", fmt.Errorf("og_image is not a valid URL: %v", err)
+		}
+		image = sanitized
+	}
+
+	return title, description, image, nil
+}
+
+// serveOpenGraphCard serves a social crawler the link's own preview card in
+// place of the redirect. The hit is recorded as a bot hit and never uses up
+// a click. It returns false, leaving the request untouched, when the link is
+// unavailable or has no overrides.
+func serveOpenGraphCard(c *gin.Context, config *Config, urlData *URLData, event ClickEvent) bool {
+	if !hasOpenGraph(urlData) {
+		return false
+	}
+
+	log.Printf("Serving Open Graph card for %s to %s", event.Alias, event.UserAgent)
+
+	// Record the crawler hit (non-fatal)
+	event.Counted = false
+	event.Bot = true
+	if err := RecordClickEvent(config, event); err != nil {
+		log.Printf("Warning: failed to record crawler hit for %s: %v", event.Alias, err)
+	}
+
+	card := "summary"
+	if urlData.OGImage != "" {
+		card = "summary_large_image"
+	}
+
+	c.HTML(http.StatusOK, "og.html", gin.H{
+		"title":       urlData.OGTitle,
+		"description": urlData.OGDescription,
+		"image":       urlData.OGImage,
+		"shortURL":    urlData.ShortURL,
+		"alias":       urlData.Alias,
+		"card":        card,
+	})
+	return true
+}
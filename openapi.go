@@ -0,0 +1,495 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPIVersion is the version of the API described by the spec
+const openAPIVersion = "1.0.0"
+
+// apiOperation documents one JSON route. The spec served at
+// /api/openapi.json is built from apiOperations, with schemas reflected
+// from the request and response types the handlers use.
+type apiOperation struct {
+	method      string
+	path        string // Gin route path, such as /api/info/:alias
+	id          string // operationId, also the generated client's method name
+	tag         string
+	summary     string
+	description string
+	protected   bool // Behind apiKeyMiddleware
+	keyRequired bool // Needs an API key even without REQUIRE_API_KEY
+	deprecated  bool // A legacy route, see legacyAPIRoutes
+	stream      bool // 200 responses are a text/event-stream of the body type
+	params      []apiParam
+	request     interface{} // Zero value of the JSON body type, nil for none
+	responses   []apiResponse
+}
+
+// apiParam documents a path or query parameter
+type apiParam struct {
+	name        string
+	in          string // path, query or header
+	description string
+	schema      *openAPISchema
+}
+
+// apiResponse documents one response status of an operation
+type apiResponse struct {
+	status      int
+	description string
+	body        interface{} // Zero value of the JSON body type, nil for none
+}
+
+// Responses shared by several operations
+var (
+	apiErrorBody        = ErrorResponse{}
+	apiUnauthorized     = apiResponse{http.StatusUnauthorized, "Missing or invalid API key", apiErrorBody}
+	apiBadRequest       = apiResponse{http.StatusBadRequest, "Invalid request", apiErrorBody}
+	apiNotFound         = apiResponse{http.StatusNotFound, "No URL found for the alias", apiErrorBody}
+	apiNotOwner         = apiResponse{http.StatusForbidden, "The short URL was created with a different API key, or without one", apiErrorBody}
+	apiInternalError    = apiResponse{http.StatusInternalServerError, "Database error", apiErrorBody}
+	apiAliasParam       = apiParam{"alias", "path", "Alias of the short URL", &openAPISchema{Type: "string"}}
+	apiIdempotencyParam = apiParam{"Idempotency-Key", "header", "Unique key making retries of the request safe; the first response is replayed for IDEMPOTENCY_TTL", &openAPISchema{Type: "string"}}
+	apiShortenResponses = []apiResponse{
+		{http.StatusCreated, "Short URL created", ShortenResponse{}},
+		{http.StatusOK, "Existing short URL for the same destination, with DEDUPLICATE_URLS", ShortenResponse{}},
+		apiBadRequest,
+		{http.StatusForbidden, "Alias does not start with the API key's alias prefix", apiErrorBody},
+		{http.StatusConflict, "Alias taken or too similar to an existing alias, or a request with the same Idempotency-Key is still running", apiErrorBody},
+		{http.StatusRequestEntityTooLarge, "Body larger than 64 KiB on a request with an Idempotency-Key", apiErrorBody},
+		{http.StatusUnprocessableEntity, "Idempotency-Key already used for a different request", apiErrorBody},
+		apiInternalError,
+	}
+)
+
+// apiOperations lists every documented route. Routes under /api that are
+// missing here are reported at startup, see undocumentedRoutes.
+var apiOperations = []apiOperation{
+	{
+		method: http.MethodPost, path: apiV1Prefix + "/shorten", id: "createShortURL", tag: "urls", protected: true,
+		summary:     "Create a short URL",
+		description: "Creates a short URL for a destination, with an optional custom alias, click limit, redirect rules, A/B destinations and deep links.",
+		params:      []apiParam{apiIdempotencyParam},
+		request:     ShortenRequest{},
+		responses:   append([]apiResponse{apiUnauthorized}, apiShortenResponses...),
+	},
+	{
+		method: http.MethodPost, path: "/shorten", id: "createShortURLPublic", tag: "urls",
+		summary:     "Create a short URL without an API key",
+		description: "Same as " + apiV1Prefix + "/shorten, without API key checks. Used by the web form.",
+		params:      []apiParam{apiIdempotencyParam},
+		request:     ShortenRequest{},
+		responses:   apiShortenResponses,
+	},
+	{
+		method: http.MethodGet, path: apiV1Prefix + "/urls", id: "listURLs", tag: "urls", protected: true,
+		summary: "List short URLs",
+		params: []apiParam{
+			{"page", "query", "Page number, starting at 1", &openAPISchema{Type: "integer", Default: 1}},
+			{"limit", "query", "URLs per page, at most 100", &openAPISchema{Type: "integer", Default: 50}},
+			{"status", "query", "Only active or expired URLs", &openAPISchema{Type: "string", Enum: []string{"active", "expired"}}},
+			{"q", "query", "Search aliases and destinations", &openAPISchema{Type: "string"}},
+			{"health", "query", "Only URLs whose destination has this health", &openAPISchema{Type: "string", Enum: []string{linkHealthOK, linkHealthBroken, linkHealthUnchecked}}},
+		},
+		responses: []apiResponse{
+			{http.StatusOK, "A page of short URLs", ListURLsResponse{}},
+			{http.StatusBadRequest, "Invalid health filter", apiErrorBody},
+			apiUnauthorized,
+			{http.StatusInternalServerError, "Failed to retrieve URLs", apiErrorBody},
+		},
+	},
+	{
+		method: http.MethodGet, path: apiV1Prefix + "/urls/:alias", id: "getURLInfo", tag: "urls", protected: true,
+		summary:     "Get details of a short URL",
+		description: "Returns a short URL with its click counts, rule hits, A/B destination shares, destination health and metadata.",
+		params:      []apiParam{apiAliasParam},
+		responses: []apiResponse{
+			{http.StatusOK, "Short URL details", URLInfoResponse{}},
+			apiUnauthorized,
+			apiNotFound,
+			apiInternalError,
+		},
+	},
+	{
+		method: http.MethodDelete, path: apiV1Prefix + "/urls/:alias", id: "deleteURL", tag: "urls", protected: true,
+		summary:     "Delete a short URL",
+		description: "Needs the API key that created the short URL, also without REQUIRE_API_KEY.",
+		params:      []apiParam{apiAliasParam},
+		responses:   []apiResponse{{http.StatusNoContent, "Short URL deleted", nil}, apiUnauthorized, apiNotOwner, apiNotFound, apiInternalError},
+	},
+	{
+		method: http.MethodPut, path: apiV1Prefix + "/urls/:alias/rules", id: "updateURLRules", tag: "urls", protected: true,
+		summary:     "Replace the redirect rules of a short URL",
+		description: "Replaces all redirect rules. An empty list removes them. Needs the API key that created the short URL, also without REQUIRE_API_KEY.",
+		params:      []apiParam{apiAliasParam},
+		request:     UpdateRulesRequest{},
+		responses: []apiResponse{
+			{http.StatusOK, "The stored rules", UpdateRulesRequest{}},
+			apiBadRequest,
+			apiUnauthorized,
+			apiNotOwner,
+			apiNotFound,
+			apiInternalError,
+		},
+	},
+	{
+		method: http.MethodPost, path: apiV1Prefix + "/cleanup", id: "cleanupExpiredURLs", tag: "maintenance", protected: true,
+		summary:   "Delete URLs that reached their click limit",
+		responses: []apiResponse{{http.StatusOK, "Number of deleted URLs", CleanupResponse{}}, apiUnauthorized, apiInternalError},
+	},
+	{
+		method: http.MethodGet, path: apiV1Prefix + "/stats", id: "getStats", tag: "maintenance", protected: true,
+		summary:   "Get global statistics",
+		responses: []apiResponse{{http.StatusOK, "Totals over all URLs", StatsResponse{}}, apiUnauthorized, apiInternalError},
+	},
+	{
+		method: http.MethodGet, path: apiV1Prefix + "/events", id: "streamEvents", tag: "events", protected: true, keyRequired: true, stream: true,
+		summary:     "Stream link events",
+		description: "Server-Sent Events stream of links being created, clicked and used up. Each event is named after its type, and its data is a LinkEvent. Send the last received event ID as Last-Event-ID when reconnecting to receive the events missed in between. Only carries links created with the key of the request.",
+		params: []apiParam{
+			{"type", "query", "Comma-separated event types to receive", &openAPISchema{Type: "string"}},
+			{"alias", "query", "Comma-separated aliases to receive events of", &openAPISchema{Type: "string"}},
+			{"owner", "query", "me, or the ID of the request's key. Streams only carry links created with that key either way", &openAPISchema{Type: "string"}},
+			{"Last-Event-ID", "header", "ID of the last event received, to resume after it", &openAPISchema{Type: "string"}},
+			{"last_event_id", "query", "Same as Last-Event-ID, for clients that cannot set headers", &openAPISchema{Type: "string"}},
+		},
+		responses: []apiResponse{
+			{http.StatusOK, "Event stream", LinkEvent{}},
+			{http.StatusBadRequest, "Invalid event filter", apiErrorBody},
+			apiUnauthorized,
+			{http.StatusServiceUnavailable, "The server is shutting down", nil},
+		},
+	},
+	{
+		method: http.MethodGet, path: "/health", id: "getHealth", tag: "maintenance",
+		summary: "Check server health",
+		responses: []apiResponse{
+			{http.StatusOK, "The server and its database are available", HealthResponse{}},
+			{http.StatusServiceUnavailable, "The database is not reachable", HealthResponse{}},
+		},
+	},
+}
+
+// documentedOperations returns apiOperations, the event stream at
+// eventsPath, and the legacy routes, each documented like its successor
+// and marked deprecated
+func documentedOperations() []apiOperation {
+	operations := append([]apiOperation(nil), apiOperations...)
+	for _, op := range apiOperations {
+		if op.path == apiV1Prefix+"/events" {
+			op.path = eventsPath
+			op.id += "Unversioned"
+			op.description = fmt.Sprintf("Same as GET %s/events.", apiV1Prefix)
+			operations = append(operations, op)
+		}
+	}
+	for _, route := range legacyAPIRoutes {
+		for _, op := range apiOperations {
+			if op.method != route.method || op.path != route.successor {
+				continue
+			}
+			op.path = route.path
+			op.id += "Legacy"
+			op.description = fmt.Sprintf("Deprecated alias of %s %s.", route.method, route.successor)
+			op.deprecated = true
+			operations = append(operations, op)
+		}
+	}
+	return operations
+}
+
+// documentErrorCodes lists the error code catalog on the code property of
+// the ErrorResponse schema
+func documentErrorCodes(schemas openAPISchemas) {
+	errorSchema := schemas["ErrorResponse"]
+	if errorSchema == nil {
+		return
+	}
+
+	var description strings.Builder
+	description.WriteString("Machine-readable error code:\n")
+	codes := make([]string, 0, len(errorCodes))
+	for _, code := range errorCodes {
+		codes = append(codes, code.code)
+		fmt.Fprintf(&description, "\n- `%s` (%d): %s", code.code, code.status, code.description)
+	}
+
+	for _, property := range *errorSchema.Properties {
+		if property.name == "code" {
+			property.schema.Enum = codes
+			property.schema.Description = description.String()
+		}
+	}
+}
+
+// openAPISchema is a JSON Schema as used by OpenAPI 3.0
+type openAPISchema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Properties           *openAPIProperties `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *openAPISchema     `json:"items,omitempty"`
+	AdditionalProperties *openAPISchema     `json:"additionalProperties,omitempty"`
+}
+
+// openAPIProperties are the properties of an object schema, kept in the
+// order of the struct fields they describe
+type openAPIProperties []openAPIProperty
+
+// openAPIProperty is one named property of an object schema
+type openAPIProperty struct {
+	name   string
+	schema *openAPISchema
+}
+
+// MarshalJSON writes the properties as a JSON object in field order
+func (p openAPIProperties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, property := range p {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(property.name)
+		schema, err := json.Marshal(property.schema)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(schema)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// openAPISchemas reflects Go types into component schemas
+type openAPISchemas map[string]*openAPISchema
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor returns the schema of a Go type. Named structs are added to
+// the components and referenced.
+func (s openAPISchemas) schemaFor(t reflect.Type) *openAPISchema {
+	switch {
+	case t == timeType:
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Pointer:
+		return s.schemaFor(t.Elem())
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Uint, reflect.Uint32:
+		return &openAPISchema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &openAPISchema{Type: "array", Items: s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: s.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if _, found := s[t.Name()]; !found {
+			s[t.Name()] = nil // Reserve the name for recursive types
+			s[t.Name()] = s.objectSchema(t)
+		}
+		return &openAPISchema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	return &openAPISchema{} // Any value
+}
+
+// objectSchema describes the JSON form of a struct. Fields without
+// omitempty are required.
+func (s openAPISchemas) objectSchema(t reflect.Type) *openAPISchema {
+	schema := &openAPISchema{Type: "object", Properties: &openAPIProperties{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		*schema.Properties = append(*schema.Properties, openAPIProperty{name, s.schemaFor(field.Type)})
+		if !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// ginPathParam matches Gin path parameters such as :alias
+var ginPathParam = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// openAPIPath converts a Gin route path to an OpenAPI path template
+func openAPIPath(path string) string {
+	return ginPathParam.ReplaceAllString(path, "{$1}")
+}
+
+// buildOpenAPISpec assembles the OpenAPI 3 document describing the API
+func buildOpenAPISpec(config *Config) map[string]interface{} {
+	schemas := openAPISchemas{}
+	paths := map[string]map[string]interface{}{}
+
+	// Without REQUIRE_API_KEY, keys are optional on protected routes
+	keySecurity := []map[string][]string{{"ApiKeyAuth": {}}, {"BearerAuth": {}}}
+	security := keySecurity
+	if !config.RequireAPIKey {
+		security = append(security, map[string][]string{})
+	}
+
+	for _, op := range documentedOperations() {
+		operation := map[string]interface{}{
+			"operationId": op.id,
+			"summary":     op.summary,
+			"tags":        []string{op.tag},
+		}
+		if op.description != "" {
+			operation["description"] = op.description
+		}
+		if op.deprecated {
+			operation["deprecated"] = true
+		}
+		if op.keyRequired {
+			operation["security"] = keySecurity
+		} else if op.protected {
+			operation["security"] = security
+		} else {
+			operation["security"] = []map[string][]string{}
+		}
+
+		var params []map[string]interface{}
+		for _, param := range op.params {
+			params = append(params, map[string]interface{}{
+				"name":        param.name,
+				"in":          param.in,
+				"required":    param.in == "path",
+				"description": param.description,
+				"schema":      param.schema,
+			})
+		}
+		if params != nil {
+			operation["parameters"] = params
+		}
+
+		if op.request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemas.schemaFor(reflect.TypeOf(op.request))},
+				},
+			}
+		}
+
+		responses := map[string]interface{}{}
+		for _, resp := range op.responses {
+			response := map[string]interface{}{"description": resp.description}
+			if resp.body != nil {
+				mediaType := "application/json"
+				if op.stream && resp.status == http.StatusOK {
+					mediaType = "text/event-stream"
+				}
+				response["content"] = map[string]interface{}{
+					mediaType: map[string]interface{}{"schema": schemas.schemaFor(reflect.TypeOf(resp.body))},
+				}
+			}
+			responses[fmt.Sprint(resp.status)] = response
+		}
+		operation["responses"] = responses
+
+		path := openAPIPath(op.path)
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(op.method)] = operation
+	}
+	documentErrorCodes(schemas)
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "URL Shortener API",
+			"version":     openAPIVersion,
+			"description": "Create, inspect and manage short URLs. Errors carry a machine-readable `code`, listed in the ErrorResponse schema. Operations marked deprecated are the unversioned routes from before /api/v1.",
+		},
+		"servers": []map[string]string{{"url": config.BaseURL}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"ApiKeyAuth": map[string]string{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"BearerAuth": map[string]string{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// openAPIHandler serves the OpenAPI spec, built once per server
+func openAPIHandler(config *Config) gin.HandlerFunc {
+	spec, err := json.MarshalIndent(buildOpenAPISpec(config), "", "  ")
+	if err != nil {
+		panic(fmt.Sprintf("invalid OpenAPI spec: %v", err)) // Programming error in apiOperations
+	}
+
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	}
+}
+
+// apiDocsHandler serves Swagger UI for the OpenAPI spec
+func apiDocsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.File("./static/api_docs.html")
+	}
+}
+
+// undocumentedRoutes compares the registered JSON routes with
+// apiOperations and returns the differences, so the spec cannot silently
+// fall behind the handlers
+func undocumentedRoutes(routes gin.RoutesInfo) []string {
+	documented := make(map[string]bool)
+	for _, op := range documentedOperations() {
+		documented[op.method+" "+op.path] = true
+	}
+
+	var problems []string
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		isAPI := strings.HasPrefix(route.Path, "/api/") && route.Path != "/api/openapi.json" && route.Path != "/api/docs"
+		if isAPI && !documented[key] {
+			problems = append(problems, key+" is not documented")
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			problems = append(problems, key+" is documented but not registered")
+		}
+	}
+
+	sort.Strings(problems)
+	return problems
+}
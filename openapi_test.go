@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRoutesDocumented builds the router like the server, with the admin
+// dashboard enabled, and checks that the spec describes every API route
+func TestRoutesDocumented(t *testing.T) {
+	discardLogs(t)
+	t.Setenv("ADMIN_PASSWORD", "secret")
+	config := newTestConfig(t)
+
+	router := gin.New()
+	registerRoutes(router, config)
+
+	for _, problem := range undocumentedRoutes(router.Routes()) {
+		t.Error(problem)
+	}
+}
+
+// TestOpenAPISpecUpToDate checks that client/openapi.json is the spec
+// "url-shortener openapi" writes with the default settings, as
+// go generate ./client runs it
+func TestOpenAPISpecUpToDate(t *testing.T) {
+	discardLogs(t)
+	committed, err := os.ReadFile(filepath.Join("client", "openapi.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chdirTemp(t)
+	for _, key := range []string{"CONFIG_FILE", "BASE_URL", "REQUIRE_API_KEY"} {
+		t.Setenv(key, "")
+	}
+	config, err := ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+
+	spec, err := json.MarshalIndent(buildOpenAPISpec(config), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec = append(spec, '\n')
+
+	if !bytes.Equal(committed, spec) {
+		t.Error("client/openapi.json is out of date, run go generate ./client")
+	}
+}
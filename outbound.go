@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// errForbiddenAddress is returned when a destination resolves to an address
+// outbound requests may not connect to
+var errForbiddenAddress = errors.New("destination resolves to a non-public address")
+
+// reservedPrefixes are non-public ranges that netip.Addr has no method for
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // Reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, may map to private IPv4
+	netip.MustParsePrefix("fec0::/10"),     // Deprecated site-local
+}
+
+// isPublicAddr reports whether an address is routable on the internet, as
+// opposed to loopback, private, link-local, multicast or reserved ranges
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// safeDialer returns a dialer for requests the server makes to link
+// destinations. It refuses connections to non-public addresses unless
+// allowPrivate (OUTBOUND_ALLOW_PRIVATE) is set. The check runs on the
+// resolved address of each connection, so neither DNS answers nor redirects
+// can point a request at internal services.
+func safeDialer(timeout time.Duration, allowPrivate bool) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, conn syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !isPublicAddr(addr) {
+				return fmt.Errorf("%w: %s", errForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// errRedirectLoop is returned for destinations that lead back to a short
+// link on this server, directly or through redirects
+var errRedirectLoop = errors.New("destination redirects in a loop")
+
+// errRedirectChainTooLong is returned for destinations that redirect more
+// than REDIRECT_MAX_HOPS times
+var errRedirectChainTooLong = errors.New("destination redirects too many times")
+
+// isSelfLink reports whether u may be a short link on this server: a
+// single path segment on the host of BASE_URL that is not a route or a
+// reserved word. Aliases that do not exist yet count too, since they could
+// be created later.
+func isSelfLink(config *Config, u *url.URL) bool {
+	base, err := url.Parse(config.BaseURL)
+	if err != nil || normalizeHost(base, URLNormalization{}) != nil || u.Host != base.Host {
+		return false
+	}
+
+	// Paths below BASE_URL's path, when the server is mounted under one
+	path := strings.TrimPrefix(u.Path, strings.TrimSuffix(base.Path, "/"))
+	segment := strings.Trim(path, "/") // Gin redirects "/alias/" to "/alias"
+	if segment == "" || strings.Contains(segment, "/") || !strings.HasPrefix(path, "/") {
+		return false
+	}
+	return config.aliasPolicy.allows(normalizeAlias(segment))
+}
+
+// checkSelfLink returns errRedirectLoop if u is a short link on this server
+func checkSelfLink(config *Config, u *url.URL) error {
+	if isSelfLink(config, u) {
+		return fmt.Errorf("%w: %s is a short link on this server", errRedirectLoop, u)
+	}
+	return nil
+}
+
+// resolveRedirects follows the redirects of a sanitized destination and
+// returns where it ends up, in canonical form. It fails when a redirect
+// leads back to this server, revisits a URL or when the chain is longer
+// than REDIRECT_MAX_HOPS. When the destination cannot be reached, the
+// chain ends at the last URL that could.
+func resolveRedirects(config *Config, rawURL string) (string, error) {
+	dialer := safeDialer(config.RedirectResolveTimeout, config.OutboundAllowPrivate)
+	client := &http.Client{
+		Timeout: config.RedirectResolveTimeout,
+		Transport: &http.Transport{
+			Proxy:             nil, // A proxy would dial on the resolver's behalf
+			DialContext:       dialer.DialContext,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse // Each hop is checked below
+		},
+	}
+
+	policy := config.GetURLNormalization()
+	current := rawURL
+	visited := map[string]bool{current: true}
+
+	for hops := 0; ; hops++ {
+		location, err := nextHop(client, current)
+		if err != nil {
+			log.Printf("Warning: could not resolve redirects of %s: %v", current, err)
+			return current, nil
+		}
+		if location == nil {
+			return current, nil
+		}
+
+		if hops == config.RedirectMaxHops {
+			return "", fmt.Errorf("%w: more than %d redirects", errRedirectChainTooLong, config.RedirectMaxHops)
+		}
+
+		next, err := normalizeURL(location.String(), policy)
+		if err != nil {
+			// Redirects to app schemes and the like end the chain
+			return current, nil
+		}
+		if err := checkSelfLink(config, next); err != nil {
+			return "", err
+		}
+		if visited[next.String()] {
+			return "", fmt.Errorf("%w: %s redirects back to %s", errRedirectLoop, current, next)
+		}
+
+		current = next.String()
+		visited[current] = true
+	}
+}
+
+// nextHop requests rawURL without following redirects and returns the
+// redirect target, or nil when the response is not a redirect. Servers
+// that do not support HEAD are asked with GET.
+func nextHop(client *http.Client, rawURL string) (*url.URL, error) {
+	resp, err := hopRequest(client, http.MethodHead, rawURL)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = hopRequest(client, http.MethodGet, rawURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		location, err := resp.Location()
+		if errors.Is(err, http.ErrNoLocation) {
+			return nil, nil
+		}
+		return location, err
+	}
+	return nil, nil
+}
+
+// hopRequest sends one request of a redirect chain, discarding the body
+func hopRequest(client *http.Client, method, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", metadataUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newRedirectChainServer serves /hop/N, which redirects to /hop/N-1 until
+// /hop/0, the final page. Other paths are registered by the test.
+func newRedirectChainServer(t *testing.T, mux *http.ServeMux) *httptest.Server {
+	t.Helper()
+	mux.HandleFunc("/hop/", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+		if n == 0 {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Redirect(w, r, "/hop/"+strconv.Itoa(n-1), http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newRedirectChainConfig loads a configuration that may resolve redirects
+// on loopback test servers
+func newRedirectChainConfig(t *testing.T, baseURL string) *Config {
+	t.Helper()
+	discardLogs(t)
+	t.Setenv("BASE_URL", baseURL)
+	t.Setenv("OUTBOUND_ALLOW_PRIVATE", "true")
+	t.Setenv("REDIRECT_MAX_HOPS", "3")
+	return newTestConfig(t)
+}
+
+func TestResolveRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	server := newRedirectChainServer(t, mux)
+	shortener := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(shortener.Close)
+
+	mux.HandleFunc("/loop/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop/b", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/loop/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop/a", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/to-shortener", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, shortener.URL+"/some-alias", http.StatusFound)
+	})
+	mux.HandleFunc("/to-app", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "myapp://open", http.StatusFound)
+	})
+
+	config := newRedirectChainConfig(t, shortener.URL)
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string // Path of the resolved URL
+		wantErr error
+	}{
+		{"no redirect", "/hop/0", "/hop/0", nil},
+		{"within hop limit", "/hop/3", "/hop/0", nil},
+		{"over hop limit", "/hop/4", "", errRedirectChainTooLong},
+		{"loop", "/loop/a", "", errRedirectLoop},
+		{"ends on this server", "/to-shortener", "", errRedirectLoop},
+		{"app scheme ends the chain", "/to-app", "/to-app", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRedirects(config, server.URL+tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resolveRedirects error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got != server.URL+tt.want {
+				t.Errorf("resolveRedirects = %q, want %q", got, server.URL+tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRedirectsFallsBackToGet(t *testing.T) {
+	var mu sync.Mutex
+	var methods []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		http.Redirect(w, r, "/hop/0", http.StatusSeeOther)
+	})
+	server := newRedirectChainServer(t, mux)
+	config := newRedirectChainConfig(t, "https://sho.rt")
+
+	got, err := resolveRedirects(config, server.URL+"/get-only")
+	if err != nil {
+		t.Fatalf("resolveRedirects: %v", err)
+	}
+	if got != server.URL+"/hop/0" {
+		t.Errorf("resolveRedirects = %q, want %q", got, server.URL+"/hop/0")
+	}
+	if strings.Join(methods, ",") != "HEAD,GET" {
+		t.Errorf("methods = %v, want HEAD then GET", methods)
+	}
+}
+
+func TestResolveRedirectsUnreachable(t *testing.T) {
+	config := newRedirectChainConfig(t, "https://sho.rt")
+	server := httptest.NewServer(http.NotFoundHandler())
+	unreachable := server.URL + "/gone"
+	server.Close()
+
+	// The destination is kept as it is when it cannot be checked
+	got, err := resolveRedirects(config, unreachable)
+	if err != nil || got != unreachable {
+		t.Errorf("resolveRedirects = %q, %v, want %q", got, err, unreachable)
+	}
+}
+
+func TestIsSelfLink(t *testing.T) {
+	discardLogs(t)
+	t.Setenv("BASE_URL", "https://sho.rt/links")
+	config := newTestConfig(t)
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://sho.rt/links/abc123", true},
+		{"https://sho.rt/links/abc123/", true},
+		{"http://sho.rt/links/abc123", true},
+		{"https://sho.rt/links/not-created-yet", true},
+		{"https://sho.rt/links/", false},
+		{"https://sho.rt/links/a/b", false},
+		{"https://sho.rt/other/abc123", false},
+		{"https://sho.rt/linksabc", false},
+		{"https://sho.rt/links/admin", false},
+		{"https://sho.rt/links/health", false},
+		{"https://www.sho.rt/links/abc123", false},
+		{"https://example.com/links/abc123", false},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isSelfLink(config, u); got != tt.want {
+			t.Errorf("isSelfLink(%s) = %t, want %t", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRedirectRules limits the number of rules on a single link
+const maxRedirectRules = 20
+
+// defaultRuleName is recorded in analytics when no rule matched
+const defaultRuleName = "default"
+
+var (
+	validRuleDevices = []string{"mobile", "tablet", "desktop"}
+	validRuleOS      = []string{"ios", "android", "windows", "macos", "linux", "chromeos"}
+	validRuleDays    = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// ruleContext is what rules are matched against for a single request
+type ruleContext struct {
+	clientIP  string
+	country   string
+	countryOK bool // Whether country has been looked up
+	device    string
+	os        string
+	languages []string
+	now       time.Time
+}
+
+// newRuleContext extracts the request attributes rules can match on. The
+// country is looked up lazily since only country rules need it.
+func newRuleContext(r *http.Request, clientIP string) *ruleContext {
+	device, os := parseDevice(r.UserAgent())
+	return &ruleContext{
+		clientIP:  clientIP,
+		device:    device,
+		os:        os,
+		languages: parseAcceptLanguage(r.Header.Get("Accept-Language")),
+		now:       time.Now(),
+	}
+}
+
+// countryCode returns the visitor's country, looking it up on first use
+func (ctx *ruleContext) countryCode() string {
+	if !ctx.countryOK {
+		ctx.country = lookupCountry(ctx.clientIP)
+		ctx.countryOK = true
+	}
+	return ctx.country
+}
+
+// resolveDestination picks the destination for a request. Redirect rules are
+// evaluated first; without a matching rule, A/B split links assign the
+// visitor a variant and other links use the original URL. The choice is
+// recorded on the click event.
+func resolveDestination(c *gin.Context, urlData *URLData, event *ClickEvent) string {
+	if len(urlData.Rules) > 0 {
+		destination, rule := selectDestination(urlData, newRuleContext(c.Request, c.ClientIP()))
+		event.Rule = rule
+		if rule != defaultRuleName {
+			return destination
+		}
+	}
+
+	if len(urlData.Destinations) > 0 {
+		event.Variant = assignVariant(c, urlData)
+		return urlData.Destinations[event.Variant-1].URL
+	}
+
+	return urlData.URL
+}
+
+// selectDestination evaluates a link's rules in order and returns the
+// destination of the first match and its name. Without a match it falls back
+// to the link's original URL and defaultRuleName.
+func selectDestination(urlData *URLData, ctx *ruleContext) (string, string) {
+	for i, rule := range urlData.Rules {
+		if rule.matches(ctx) {
+			return rule.Destination, ruleName(rule, i)
+		}
+	}
+	return urlData.URL, defaultRuleName
+}
+
+// ruleName identifies a rule in analytics: its name, or its 1-based position
+func ruleName(rule RedirectRule, index int) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return "rule-" + strconv.Itoa(index+1)
+}
+
+// matches reports whether every condition of a rule holds. Within a
+// condition any listed value may match.
+func (rule RedirectRule) matches(ctx *ruleContext) bool {
+	if len(rule.Devices) > 0 && !slices.Contains(rule.Devices, ctx.device) {
+		return false
+	}
+
+	if len(rule.OS) > 0 && !slices.Contains(rule.OS, ctx.os) {
+		return false
+	}
+
+	if len(rule.Languages) > 0 && !matchLanguages(rule.Languages, ctx.languages) {
+		return false
+	}
+
+	if rule.TimeWindow != nil && !rule.TimeWindow.contains(ctx.now) {
+		return false
+	}
+
+	// Checked last so the GeoIP lookup only happens when everything else matched
+	if len(rule.Countries) > 0 && !slices.Contains(rule.Countries, ctx.countryCode()) {
+		return false
+	}
+
+	return true
+}
+
+// contains reports whether a moment falls inside the window
+func (w *RuleTimeWindow) contains(now time.Time) bool {
+	if w.From != nil && now.Before(*w.From) {
+		return false
+	}
+	if w.Until != nil && !now.Before(*w.Until) {
+		return false
+	}
+
+	location := w.location
+	if location == nil {
+		location = time.UTC
+	}
+	local := now.In(location)
+
+	if len(w.Days) > 0 && !slices.Contains(w.Days, validRuleDays[local.Weekday()]) {
+		return false
+	}
+
+	if w.Start != "" && w.End != "" {
+		start, _ := parseClock(w.Start)
+		end, _ := parseClock(w.End)
+		minute := local.Hour()*60 + local.Minute()
+		if start <= end {
+			return minute >= start && minute < end
+		}
+		// The window wraps past midnight, e.g. 22:00-06:00
+		return minute >= start || minute < end
+	}
+
+	return true
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid HH:MM time", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// parseDevice classifies a User-Agent into a device type and operating system
+func parseDevice(userAgent string) (string, string) {
+	ua := strings.ToLower(userAgent)
+
+	os := ""
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		os = "ios"
+	case strings.Contains(ua, "android"):
+		os = "android"
+	case strings.Contains(ua, "windows"):
+		os = "windows"
+	case strings.Contains(ua, "cros"):
+		os = "chromeos"
+	case strings.Contains(ua, "macintosh"), strings.Contains(ua, "mac os x"):
+		os = "macos"
+	case strings.Contains(ua, "linux"):
+		os = "linux"
+	}
+
+	device := "desktop"
+	switch {
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"),
+		os == "android" && !strings.Contains(ua, "mobile"):
+		device = "tablet"
+	case strings.Contains(ua, "mobi"), os == "ios", os == "android":
+		device = "mobile"
+	}
+
+	return device, os
+}
+
+// parseAcceptLanguage returns the lowercase language tags a client accepts,
+// skipping tags explicitly refused with q=0
+func parseAcceptLanguage(header string) []string {
+	var languages []string
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight <= 0 {
+				continue
+			}
+		}
+
+		languages = append(languages, tag)
+	}
+	return languages
+}
+
+// matchLanguages reports whether any accepted language matches a rule
+// language. A bare rule language such as "de" also matches regional tags
+// such as "de-at".
+func matchLanguages(ruleLanguages, accepted []string) bool {
+	for _, want := range ruleLanguages {
+		for _, tag := range accepted {
+			if tag == want || (!strings.Contains(want, "-") && strings.HasPrefix(tag, want+"-")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateRedirectRules checks rules submitted by a client and returns them
+// normalized: destinations sanitized, values lowercased (countries
+// uppercased) and duplicates of a rule name rejected
+func validateRedirectRules(config *Config, rules []RedirectRule) ([]RedirectRule, error) {
+	if len(rules) > maxRedirectRules {
+		return nil, fmt.Errorf("a link can have at most %d rules", maxRedirectRules)
+	}
+
+	names := make(map[string]bool)
+	normalized := make([]RedirectRule, 0, len(rules))
+	for i, rule := range rules {
+		label := fmt.Sprintf("rule %d", i+1)
+
+		rule.Name = strings.TrimSpace(rule.Name)
+		if rule.Name != "" {
+			if rule.Name == defaultRuleName {
+				return nil, fmt.Errorf("%s: the name %q is reserved", label, defaultRuleName)
+			}
+			if names[rule.Name] {
+				return nil, fmt.Errorf("%s: duplicate rule name %q", label, rule.Name)
+			}
+			names[rule.Name] = true
+			label = fmt.Sprintf("rule %q", rule.Name)
+		}
+
+		destination, err := sanitizeURL(config, rule.Destination)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid destination: %v", label, err)
+		}
+		rule.Destination = destination
+
+		for j, country := range rule.Countries {
+			country = strings.ToUpper(strings.TrimSpace(country))
+			if len(country) != 2 {
+				return nil, fmt.Errorf("%s: %q is not a two-letter country code", label, country)
+			}
+			rule.Countries[j] = country
+		}
+		if len(rule.Countries) > 0 && config.GeoIPDBPath == "" {
+			return nil, fmt.Errorf("%s: country conditions need GEOIP_DB_PATH to be configured", label)
+		}
+
+		if rule.Devices, err = normalizeRuleValues(rule.Devices, validRuleDevices); err != nil {
+			return nil, fmt.Errorf("%s: devices: %v", label, err)
+		}
+		if rule.OS, err = normalizeRuleValues(rule.OS, validRuleOS); err != nil {
+			return nil, fmt.Errorf("%s: os: %v", label, err)
+		}
+
+		for j, language := range rule.Languages {
+			language = strings.ToLower(strings.TrimSpace(language))
+			if language == "" {
+				return nil, fmt.Errorf("%s: empty language", label)
+			}
+			rule.Languages[j] = language
+		}
+
+		if rule.TimeWindow != nil {
+			if err := validateTimeWindow(rule.TimeWindow); err != nil {
+				return nil, fmt.Errorf("%s: time_window: %v", label, err)
+			}
+		}
+
+		if len(rule.Countries) == 0 && len(rule.Devices) == 0 && len(rule.OS) == 0 &&
+			len(rule.Languages) == 0 && rule.TimeWindow == nil {
+			return nil, fmt.Errorf("%s: at least one condition is required", label)
+		}
+
+		normalized = append(normalized, rule)
+	}
+
+	return normalized, nil
+}
+
+// normalizeRuleValues lowercases values and checks them against a fixed set
+func normalizeRuleValues(values, valid []string) ([]string, error) {
+	for i, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if !slices.Contains(valid, value) {
+			return nil, fmt.Errorf("invalid value %q (valid values: %s)", value, strings.Join(valid, ", "))
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// loadLocation resolves the window's timezone, so redirects do not read
+// zoneinfo for every visit
+func (w *RuleTimeWindow) loadLocation() error {
+	if w.Timezone == "" {
+		w.location = time.UTC
+		return nil
+	}
+
+	location, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return fmt.Errorf("unknown timezone %q", w.Timezone)
+	}
+	w.location = location
+	return nil
+}
+
+// validateTimeWindow checks and normalizes a rule's time window
+func validateTimeWindow(w *RuleTimeWindow) error {
+	if w.From != nil && w.Until != nil && !w.From.Before(*w.Until) {
+		return fmt.Errorf("from must be before until")
+	}
+
+	if err := w.loadLocation(); err != nil {
+		return err
+	}
+
+	var err error
+	if w.Days, err = normalizeRuleValues(w.Days, validRuleDays); err != nil {
+		return fmt.Errorf("days: %v", err)
+	}
+
+	if (w.Start == "") != (w.End == "") {
+		return fmt.Errorf("start and end must be given together")
+	}
+	if w.Start != "" {
+		if _, err := parseClock(w.Start); err != nil {
+			return err
+		}
+		if _, err := parseClock(w.End); err != nil {
+			return err
+		}
+	}
+
+	if w.From == nil && w.Until == nil && len(w.Days) == 0 && w.Start == "" {
+		return fmt.Errorf("at least one of from, until, days or start/end is required")
+	}
+
+	return nil
+}
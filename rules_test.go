@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecodedTimeWindowKeepsLocation(t *testing.T) {
+	rules, err := decodeRules(`[{"url":"https://example.com/night","time_window":{"start":"22:00","end":"06:00","timezone":"Asia/Tokyo"}}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	window := rules[0].TimeWindow
+	if window.location == nil || window.location.String() != "Asia/Tokyo" {
+		t.Fatalf("location = %v, want Asia/Tokyo", window.location)
+	}
+
+	// 14:00 UTC is 23:00 in Tokyo, inside the window; 04:00 UTC is 13:00
+	if !window.contains(time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)) {
+		t.Error("23:00 Tokyo time is outside the window")
+	}
+	if window.contains(time.Date(2026, 3, 2, 4, 0, 0, 0, time.UTC)) {
+		t.Error("13:00 Tokyo time is inside the window")
+	}
+}
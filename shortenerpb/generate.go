@@ -0,0 +1,5 @@
+// Package shortenerpb holds the protobuf messages and gRPC service
+// generated from shortener.proto.
+package shortenerpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative shortener.proto
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestClientUpToDate fails when client/api_gen.go was not regenerated
+// after client/openapi.json changed
+func TestClientUpToDate(t *testing.T) {
+	data, err := os.ReadFile("../../client/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := generate(data, "openapi.json", "client")
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	got, err := os.ReadFile("../../client/api_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("client/api_gen.go is out of date, run go generate ./client")
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// allowedScriptSets lists the scripts that may be mixed in one alias or
+// domain label, following the "highly restrictive" level of Unicode TS #39.
+// Any single script is allowed on its own.
+var allowedScriptSets = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"}, // Japanese
+	{"Latin", "Han", "Hangul"},               // Korean
+	{"Latin", "Han", "Bopomofo"},             // Chinese
+}
+
+// confusables maps letters that look like Latin letters to them. It covers
+// the common Cyrillic, Greek and Armenian homoglyphs; fullwidth and other
+// compatibility forms are folded by NFKC before the lookup.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'е': 'e', 'ё': 'ë', 'һ': 'h', 'і': 'i', 'ї': 'ï', 'ј': 'j', 'ӏ': 'l',
+	'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's', 'у': 'y', 'ԝ': 'w', 'х': 'x', 'с': 'c',
+	'ԁ': 'd',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'Ё': 'Ë', 'Һ': 'H', 'І': 'I', 'Ї': 'Ï', 'Ј': 'J',
+	'К': 'K', 'Ӏ': 'I', 'М': 'M', 'Н': 'H', 'О': 'O', 'Р': 'P', 'Ԛ': 'Q', 'Ѕ': 'S',
+	'Т': 'T', 'У': 'Y', 'Ԝ': 'W', 'Х': 'X', 'С': 'C', 'Ԁ': 'D',
+
+	// Greek
+	'α': 'a', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u',
+	'χ': 'x', 'γ': 'y', 'ω': 'w',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M',
+	'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+
+	// Armenian
+	'օ': 'o', 'ս': 'u', 'ց': 'g', 'հ': 'h', 'ո': 'n',
+
+	// Latin letters that pass for others
+	'ı': 'i', 'ɑ': 'a', 'ɡ': 'g', 'ʏ': 'y', 'ɩ': 'i',
+}
+
+// normalizeAlias returns the NFC form of an alias, so aliases typed with
+// combining characters match their precomposed form
+func normalizeAlias(alias string) string {
+	return norm.NFC.String(alias)
+}
+
+// aliasParam returns the normalized alias route parameter
+func aliasParam(c *gin.Context) string {
+	return normalizeAlias(c.Param("alias"))
+}
+
+// isAliasRune reports whether a character may appear in a custom alias:
+// letters and digits of any script, combining marks, hyphens, underscores
+func isAliasRune(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsMark(char) || unicode.IsDigit(char) ||
+		char == '-' || char == '_'
+}
+
+// runeScript returns the Unicode script of a character
+func runeScript(char rune) string {
+	if char < utf8.RuneSelf {
+		if unicode.IsLetter(char) {
+			return "Latin"
+		}
+		return "Common"
+	}
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, char) {
+			return name
+		}
+	}
+	return "Unknown"
+}
+
+// mixesScripts reports whether s combines scripts that are not commonly
+// written together, the usual sign of a homoglyph spoof like "pаypal" with
+// a Cyrillic "а"
+func mixesScripts(s string) bool {
+	scripts := make(map[string]bool)
+	for _, char := range s {
+		script := runeScript(char)
+		if script != "Common" && script != "Inherited" {
+			scripts[script] = true
+		}
+	}
+	if len(scripts) <= 1 {
+		return false
+	}
+
+	for _, set := range allowedScriptSets {
+		allowed := true
+		for script := range scripts {
+			if !slices.Contains(set, script) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// aliasSkeleton maps an alias to the Latin letters it looks like, so
+// "раураl" written in Cyrillic has the skeleton "paypal". With foldCase the
+// skeleton is lowercased as well; that form is stored in urls.alias_skeleton.
+func aliasSkeleton(alias string, foldCase bool) string {
+	var b strings.Builder
+	for _, char := range norm.NFKC.String(alias) {
+		if latin, found := confusables[char]; found {
+			char = latin
+		}
+		if foldCase {
+			char = unicode.ToLower(char)
+		}
+		b.WriteRune(char)
+	}
+	return b.String()
+}
+
+// isASCII reports whether s only holds ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// confusableAliases reports whether two different aliases look alike. Two
+// ASCII aliases never count as confusable; they are told apart by the
+// unique constraint alone, as before Unicode aliases were allowed.
+func confusableAliases(config *Config, a, b string) bool {
+	if a == b || (isASCII(a) && isASCII(b)) {
+		return false
+	}
+	foldCase := config.AliasCaseInsensitive
+	return aliasSkeleton(a, foldCase) == aliasSkeleton(b, foldCase)
+}
+
+// asciiHost converts an internationalized host name to punycode for
+// storage. ASCII hosts and IP addresses are returned unchanged.
+func asciiHost(host string) (string, error) {
+	if isASCII(host) || net.ParseIP(host) != nil {
+		return host, nil
+	}
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid internationalized domain name %q: %v", host, err)
+	}
+	return ascii, nil
+}
+
+// displayURL returns rawURL with a punycode host shown in Unicode, such as
+// http://bücher.de for http://xn--bcher-kva.de. Hosts whose labels mix
+// scripts stay in punycode, as browsers do, so homographs remain visible.
+func displayURL(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || !strings.Contains(strings.ToLower(parsedURL.Host), "xn--") {
+		return rawURL
+	}
+
+	host := parsedURL.Hostname()
+	unicodeHost, err := idna.Display.ToUnicode(host)
+	if err != nil {
+		return rawURL
+	}
+	for _, label := range strings.Split(unicodeHost, ".") {
+		if mixesScripts(label) {
+			return rawURL
+		}
+		// A label written entirely in look-alikes of Latin letters, such
+		// as a Cyrillic "аррle", spoofs the Latin name
+		if skeleton := aliasSkeleton(label, true); !isASCII(label) && isASCII(skeleton) {
+			return rawURL
+		}
+	}
+
+	// Replace the host in place; url.URL.String would percent-encode it
+	start := strings.Index(rawURL, host)
+	if start < 0 {
+		return rawURL
+	}
+	return rawURL[:start] + unicodeHost + rawURL[start+len(host):]
+}
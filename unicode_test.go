@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGenerateCustomAlias(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"ASCII", "my-link_1", "my-link_1", false},
+		{"surrounding whitespace", "  promo  ", "promo", false},
+		{"accented Latin", "café", "café", false},
+		{"combining accent composed", "cafe\u0301", "café", false},
+		{"Cyrillic", "ПРОМО", "ПРОМО", false},
+		{"Japanese mixes Latin, Han and Katakana", "東京タワーTV", "東京タワーTV", false},
+		{"Korean mixes Latin and Hangul", "서울-seoul", "서울-seoul", false},
+		{"length counts characters, not bytes", "東京都", "東京都", false},
+		{"length counts composed characters", "e\u0301e\u0301", "", true},
+		{"too long", strings.Repeat("я", 51), "", true},
+
+		{"empty", "   ", "", true},
+		{"slash", "a/b/c", "", true},
+		{"dot", "file.txt", "", true},
+		{"emoji", "hi😀there", "", true},
+		{"leading combining mark", "\u0301abc", "", true},
+		{"Latin with a Cyrillic a", "pаypal", "", true},
+		{"Latin with Greek omicron", "gοogle", "", true},
+		{"Cyrillic with Greek", "сοр", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := generateCustomAlias(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("generateCustomAlias(%q) error = %v, want error %t", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("generateCustomAlias(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMixesScripts(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"paypal", false},
+		{"пример", false},
+		{"123-456", false},
+		{"café-42", false},
+		{"東京タワーtv", false},
+		{"ひらがなカタカナ漢字", false},
+		{"서울seoul", false},
+		{"注音ㄅㄆ", false},
+		{"pаypal", true},
+		{"αβγ-abc", true},
+		{"서울タワー", true},
+		{"тест東京", true},
+	}
+
+	for _, tt := range tests {
+		if got := mixesScripts(tt.s); got != tt.want {
+			t.Errorf("mixesScripts(%q) = %t, want %t", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestAliasSkeleton(t *testing.T) {
+	tests := []struct {
+		alias    string
+		foldCase bool
+		want     string
+	}{
+		{"сор", false, "cop"},         // Cyrillic сор
+		{"раураl", false, "paypal"},   // Cyrillic раураl
+		{"ορτ", false, "opt"},         // Greek ορτ
+		{"ｃｏｐ", false, "cop"},         // Fullwidth ｃｏｐ, folded by NFKC
+		{"СОР", false, "COP"},         // Cyrillic СОР
+		{"СОР", true, "cop"},          // Case folded
+		{"Promo", false, "Promo"},     // ASCII is unchanged
+		{"東京", false, "東京"},           // No Latin lookalikes
+		{"сa\u0301fе", false, "cáfe"}, // Composed by NFKC
+		{"օս", false, "ou"},           // Armenian
+		{"ıɑɡ", false, "iag"},         // Latin lookalikes of Latin letters
+		{"ІНІ", true, "ihi"},          // Cyrillic І and Н
+		{"абв", false, "aбв"},         // Only look-alike letters map
+		{"іоѕ-арр", false, "ios-app"}, // Hyphens are kept
+	}
+
+	for _, tt := range tests {
+		if got := aliasSkeleton(tt.alias, tt.foldCase); got != tt.want {
+			t.Errorf("aliasSkeleton(%q, %t) = %q, want %q", tt.alias, tt.foldCase, got, tt.want)
+		}
+	}
+}
+
+func TestConfusableAliases(t *testing.T) {
+	caseSensitive := &Config{}
+	caseInsensitive := &Config{AliasCaseInsensitive: true}
+
+	tests := []struct {
+		name   string
+		config *Config
+		a, b   string
+		want   bool
+	}{
+		{"Cyrillic lookalike of Latin", caseSensitive, "сор", "cop", true},
+		{"fullwidth lookalike", caseSensitive, "ｃｏｐ", "cop", true},
+		{"same alias", caseSensitive, "cop", "cop", false},
+		{"two ASCII aliases", caseSensitive, "cop", "COP", false},
+		{"case differs", caseSensitive, "СОР", "cop", false},
+		{"case differs, case-insensitive", caseInsensitive, "СОР", "cop", true},
+		{"different skeletons", caseSensitive, "сор", "cap", false},
+	}
+
+	for _, tt := range tests {
+		if got := confusableAliases(tt.config, tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: confusableAliases(%q, %q) = %t, want %t", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// TestShortenRefusesLookalikeAlias follows the readme's example: a
+// Cyrillic "сор" after "cop" is refused with 409
+func TestShortenRefusesLookalikeAlias(t *testing.T) {
+	discardLogs(t)
+	config := newTestConfig(t)
+	router := gin.New()
+	registerRoutes(router, config)
+
+	shorten := func(alias string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(ShortenRequest{URL: "https://example.com/", Alias: alias})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := shorten("cop"); w.Code != http.StatusCreated {
+		t.Fatalf("cop: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+
+	w := shorten("сор")
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), errCodeAliasTooSimilar) {
+		t.Errorf("Cyrillic сор: status = %d %s, want %d %s", w.Code, w.Body, http.StatusConflict, errCodeAliasTooSimilar)
+	}
+
+	// A combining accent names the same alias as its precomposed form
+	if w := shorten("café"); w.Code != http.StatusCreated {
+		t.Fatalf("café: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if w := shorten("cafe\u0301"); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), errCodeAliasTaken) {
+		t.Errorf("decomposed café: status = %d %s, want %d %s", w.Code, w.Body, http.StatusConflict, errCodeAliasTaken)
+	}
+
+	// Mixed scripts are refused before any lookup
+	if w := shorten("pаypal"); w.Code != http.StatusBadRequest {
+		t.Errorf("mixed-script alias: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
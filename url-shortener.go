@@ -1,13 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// A -h flag prints the command's usage and is not a failure
+	if err := runCLI(os.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runServer starts the HTTP server
+func runServer() {
 	// Load configuration
 	config := LoadConfig()
 	defer config.CloseDB()
@@ -15,6 +32,29 @@ func main() {
 	// Print configuration in development mode
 	config.PrintConfig()
 
+	// Reload safe settings on SIGHUP
+	watchConfigReload(config)
+	watchBotSignatures(config)
+
+	// Open the GeoIP database used by country rules
+	openGeoIPDatabase(config)
+	defer closeGeoIPDatabase()
+
+	// Count clicks in memory when CLICK_WRITES=buffered
+	startClickBuffer(config)
+	defer stopClickBuffer(config)
+
+	// Check link destinations in the background when LINK_CHECK_INTERVAL is set
+	startLinkChecker(config)
+	defer stopLinkChecker(config)
+
+	// Fetch titles and favicons of destinations when METADATA_FETCH is set
+	startMetadataFetcher(config)
+	defer stopMetadataFetcher(config)
+
+	// Publish link events to /api/v1/events streams
+	startEventHub(config)
+
 	// Set Gin mode
 	gin.SetMode(config.GinMode)
 
@@ -22,16 +62,64 @@ func main() {
 	router := gin.New()
 
 	// Load HTML templates from static directory
+	router.SetFuncMap(templateFuncs())
 	router.LoadHTMLGlob("static/*.html")
 
 	// Add middleware
 	router.Use(requestLoggingMiddleware())
-	router.Use(gin.Recovery())
+	router.Use(gin.CustomRecovery(recoveryHandler))
+
+	router.Use(corsMiddleware(config))
+
+	registerRoutes(router, config)
+	if !config.AdminEnabled() {
+		log.Println("ℹ️  Admin dashboard disabled (set ADMIN_PASSWORD to enable)")
+	}
 
-	if config.EnableCORS {
-		router.Use(corsMiddleware())
+	// Keep custom aliases from shadowing any registered route
+	config.aliasPolicy.reserveRoutes(router.Routes())
+
+	// Keep the OpenAPI spec in step with the registered routes
+	for _, problem := range undocumentedRoutes(router.Routes()) {
+		log.Printf("Warning: OpenAPI spec out of date: %s", problem)
 	}
 
+	// Serve the gRPC API when GRPC_PORT is set
+	startGRPCServer(config)
+	defer stopGRPCServer(config)
+
+	// Start server
+	log.Printf("🚀 Server starting on port %s", config.Port)
+	log.Printf("🌐 Access the application at: %s", config.BaseURL)
+
+	server := &http.Server{Addr: ":" + config.Port, Handler: router}
+	server.RegisterOnShutdown(func() { stopEventHub(config) })
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// Shut down gracefully so buffered clicks are written before exiting
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	case sig := <-signals:
+		log.Printf("🛑 %v received, shutting down", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("Warning: graceful shutdown failed: %v", err)
+		}
+	}
+}
+
+// registerRoutes adds all HTTP routes of the server to router
+func registerRoutes(router *gin.Engine, config *Config) {
 	// Serve static files
 	router.Static("/static", "./static")
 
@@ -41,32 +129,40 @@ func main() {
 	})
 
 	// Main shorten endpoint (this is what your frontend calls)
-	router.POST("/shorten", shortenHandler(config))
+	router.POST("/shorten", idempotencyMiddleware(config), shortenHandler(config))
 
 	// Health check
 	router.GET("/health", healthHandler(config))
 
-	// API routes group (optional, for additional API endpoints)
-	api := router.Group("/api")
-	{
-		api.POST("/shorten", shortenHandler(config))
-		api.GET("/stats", statsHandler(config))
-		api.GET("/urls", listURLsHandler(config))
-		api.POST("/cleanup", cleanupHandler(config))
-		api.GET("/info/:alias", urlInfoHandler(config))
+	// App association files for Universal Links and App Links
+	router.GET("/.well-known/apple-app-site-association", appleAppSiteAssociationHandler(config))
+	router.GET("/.well-known/assetlinks.json", assetLinksHandler(config))
+
+	// API documentation, readable without an API key
+	router.GET("/api/openapi.json", openAPIHandler(config))
+	router.GET("/api/docs", apiDocsHandler())
+
+	// Versioned JSON API, plus the unversioned routes it replaces
+	registerAPIRoutes(router, config)
+
+	// Admin dashboard (only when credentials are configured)
+	if config.AdminEnabled() {
+		registerAdminRoutes(router, config)
 	}
 
 	// Redirect handler (must be last to catch all remaining routes)
 	router.GET("/:alias", redirectHandler(config))
+	router.HEAD("/:alias", redirectHandler(config))
 
 	// 404 handler
 	router.NoRoute(notFoundHandler())
+}
 
-	// Start server
-	log.Printf("🚀 Server starting on port %s", config.Port)
-	log.Printf("🌐 Access the application at: %s", config.BaseURL)
-
-	if err := http.ListenAndServe(":"+config.Port, router); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
-	}
+// reserveServerRoutes reserves the server's routes as aliases without
+// starting it, for commands that create links directly in the database
+func reserveServerRoutes(config *Config) {
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	registerRoutes(router, config)
+	config.aliasPolicy.reserveRoutes(router.Routes())
 }
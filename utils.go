@@ -1,13 +1,17 @@
 package main
 
 import (
+	"bufio"
 	"crypto/rand"
 	"fmt"
+	"io"
 	"math/big"
 	mathrand "math/rand"
 	"net/url"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // isValidURL checks if a string is a valid URL
@@ -26,10 +30,7 @@ func isValidURL(str string) bool {
 }
 
 // generateRandomAlias generates a cryptographically secure random alias for URLs
-func generateRandomAlias() string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	const length = 6
-
+func generateRandomAlias(charset string, length int) string {
 	b := make([]byte, length)
 	charsetLen := big.NewInt(int64(len(charset)))
 
@@ -39,7 +40,7 @@ func generateRandomAlias() string {
 		if err != nil {
 			// Fallback to a simpler method if crypto/rand fails
 			// This should rarely happen
-			return generateFallbackAlias()
+			return generateFallbackAlias(charset, length+2) // Slightly longer for fallback
 		}
 		b[i] = charset[n.Int64()]
 	}
@@ -49,10 +50,7 @@ func generateRandomAlias() string {
 
 // generateFallbackAlias generates a fallback alias using time-based approach
 // This is used only if crypto/rand fails (which should be very rare)
-func generateFallbackAlias() string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	const length = 8 // Slightly longer for fallback
-
+func generateFallbackAlias(charset string, length int) string {
 	// Create a new local random generator with time-based seed
 	source := mathrand.NewSource(time.Now().UnixNano())
 	rng := mathrand.New(source)
@@ -75,61 +73,39 @@ func generateCustomAlias(customAlias string) (string, error) {
 		return "", fmt.Errorf("custom alias cannot be empty")
 	}
 
+	// Compose accented characters, so equal-looking aliases are stored alike
+	customAlias = normalizeAlias(customAlias)
+
 	// Check length (between 3 and 50 characters)
-	if len(customAlias) < 3 || len(customAlias) > 50 {
+	length := utf8.RuneCountInString(customAlias)
+	if length < 3 || length > 50 {
 		return "", fmt.Errorf("custom alias must be between 3 and 50 characters")
 	}
 
-	// Check for valid characters (alphanumeric, hyphens, underscores)
-	for _, char := range customAlias {
-		if !((char >= 'a' && char <= 'z') ||
-			(char >= 'A' && char <= 'Z') ||
-			(char >= '0' && char <= '9') ||
-			char == '-' || char == '_') {
+	// Check for valid characters (letters and numbers of any script, hyphens, underscores)
+	for i, char := range customAlias {
+		if !isAliasRune(char) || (i == 0 && unicode.IsMark(char)) {
 			return "", fmt.Errorf("custom alias can only contain letters, numbers, hyphens, and underscores")
 		}
 	}
 
-	// Check for reserved words
-	reservedWords := []string{
-		"api", "admin", "www", "mail", "ftp", "localhost", "health", "stats",
-		"static", "assets", "public", "private", "secure", "login", "logout",
-		"register", "signup", "signin", "dashboard", "profile", "settings",
-	}
-
-	lowerAlias := strings.ToLower(customAlias)
-	for _, reserved := range reservedWords {
-		if lowerAlias == reserved {
-			return "", fmt.Errorf("'%s' is a reserved word and cannot be used as alias", customAlias)
-		}
+	// Refuse homoglyph spoofs such as a Latin alias with a Cyrillic letter
+	if mixesScripts(customAlias) {
+		return "", fmt.Errorf("custom alias mixes letters from different scripts")
 	}
 
 	return customAlias, nil
 }
 
-// sanitizeURL cleans and validates a URL
-func sanitizeURL(rawURL string) (string, error) {
-	// Trim whitespace
-	rawURL = strings.TrimSpace(rawURL)
-
-	if rawURL == "" {
-		return "", fmt.Errorf("URL cannot be empty")
-	}
-
-	// Add scheme if missing
-	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
-		rawURL = "http://" + rawURL
-	}
-
-	// Parse and validate URL
-	parsedURL, err := url.Parse(rawURL)
+// sanitizeURL validates a URL and returns its canonical form, see
+// normalizeURL. Short links on this server are refused, as they would loop.
+func sanitizeURL(config *Config, rawURL string) (string, error) {
+	parsedURL, err := normalizeURL(rawURL, config.GetURLNormalization())
 	if err != nil {
-		return "", fmt.Errorf("invalid URL format: %v", err)
+		return "", err
 	}
-
-	// Check if host is present
-	if parsedURL.Host == "" {
-		return "", fmt.Errorf("URL must have a valid host")
+	if err := checkSelfLink(config, parsedURL); err != nil {
+		return "", err
 	}
 
 	// Check for localhost in production (optional security measure)
@@ -144,3 +120,18 @@ func sanitizeURL(rawURL string) (string, error) {
 
 	return parsedURL.String(), nil
 }
+
+// parseWordList reads one lowercase entry per line, skipping blank lines
+// and # comments
+func parseWordList(r io.Reader) ([]string, error) {
+	var entries []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Number suffixes of word aliases start at two digits and grow up to six
+const (
+	minWordAliasDigits = 2
+	maxWordAliasDigits = 6
+)
+
+// aliasWordList holds the adjectives and nouns of word aliases
+//
+//go:embed alias_words.txt
+var aliasWordList string
+
+// defaultProfanityList is the profanity blocklist built into the binary
+//
+//go:embed profanity.txt
+var defaultProfanityList string
+
+// profanityWords holds the entries of the profanity blocklist in the form
+// of blockedWords
+var profanityWords []string
+
+func init() {
+	words, _ := parseWordList(strings.NewReader(defaultProfanityList))
+	profanityWords = blockedWords(words)
+}
+
+// containsProfanity reports whether s contains a blocked word, ignoring case
+func containsProfanity(s string) bool {
+	return containsBlockedWord(s, profanityWords)
+}
+
+// aliasTokens splits s into lowercase words at every character that is not
+// a letter or digit, between letters and digits, between Latin and other
+// letters, and where a lowercase letter is followed by an uppercase one.
+// An uppercase run before a lowercase letter ends one letter early, so
+// "ASSFile" gives "ass" and "file".
+func aliasTokens(s string) []string {
+	runes := []rune(s)
+	var tokens []string
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				tokens = append(tokens, strings.ToLower(string(runes[start:i])))
+				start = -1
+			}
+			continue
+		}
+		if start >= 0 && isTokenBoundary(runes, i) {
+			tokens = append(tokens, strings.ToLower(string(runes[start:i])))
+			start = i
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, strings.ToLower(string(runes[start:])))
+	}
+	return tokens
+}
+
+// isTokenBoundary reports whether a new word starts at runes[i], given
+// that runes[i-1] is a letter or digit
+func isTokenBoundary(runes []rune, i int) bool {
+	prev, r := runes[i-1], runes[i]
+	switch {
+	case unicode.IsDigit(prev) != unicode.IsDigit(r):
+		return true
+	case unicode.IsDigit(r):
+		return false
+	case unicode.Is(unicode.Latin, prev) != unicode.Is(unicode.Latin, r):
+		return true
+	case unicode.IsLower(prev) && unicode.IsUpper(r):
+		return true
+	case unicode.IsUpper(prev) && unicode.IsUpper(r):
+		return i+1 < len(runes) && unicode.IsLower(runes[i+1])
+	}
+	return false
+}
+
+// blockedWords converts blocklist entries to their words joined by single
+// spaces, the form containsBlockedWord matches
+func blockedWords(entries []string) []string {
+	words := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if tokens := aliasTokens(entry); len(tokens) > 0 {
+			words = append(words, strings.Join(tokens, " "))
+		}
+	}
+	return words
+}
+
+// containsBlockedWord reports whether the words of s include one of the
+// blocked words as whole words, so "grape" does not match "rape"
+func containsBlockedWord(s string, blocked []string) bool {
+	if len(blocked) == 0 {
+		return false
+	}
+
+	joined := " " + strings.Join(aliasTokens(s), " ") + " "
+	for _, word := range blocked {
+		if strings.Contains(joined, " "+word+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAliasWords splits the word list into adjectives and nouns, dropping
+// words that contain profanity
+func parseAliasWords(list string) (adjectives, nouns []string) {
+	words, _ := parseWordList(strings.NewReader(list))
+
+	section := ""
+	for _, word := range words {
+		switch {
+		case word == "[adjectives]" || word == "[nouns]":
+			section = word
+		case containsProfanity(word):
+			log.Printf("Warning: dropping alias word %q, it matches the profanity list", word)
+		case section == "[adjectives]":
+			adjectives = append(adjectives, word)
+		case section == "[nouns]":
+			nouns = append(nouns, word)
+		}
+	}
+	return adjectives, nouns
+}
+
+// wordAliasStrategy builds aliases that are easy to read aloud, such as
+// brave-otter-42. The number gets another digit when too many candidates
+// collide.
+type wordAliasStrategy struct {
+	adjectives []string
+	nouns      []string
+
+	mu     sync.Mutex
+	digits int
+	growth collisionTracker
+}
+
+// newWordAliasStrategy creates the words strategy
+func newWordAliasStrategy(config *Config) aliasStrategy {
+	adjectives, nouns := parseAliasWords(aliasWordList)
+	return &wordAliasStrategy{
+		adjectives: adjectives,
+		nouns:      nouns,
+		digits:     minWordAliasDigits,
+		growth:     collisionTracker{threshold: config.AliasGrowthThreshold},
+	}
+}
+
+// alias returns a random adjective-noun-number candidate
+func (s *wordAliasStrategy) alias(id int64, attempt int) string {
+	s.mu.Lock()
+	digits := s.digits
+	s.mu.Unlock()
+
+	// Numbers have exactly digits digits, e.g. 10-99 for two
+	low := 1
+	for i := 1; i < digits; i++ {
+		low *= 10
+	}
+	number := low + rand.IntN(9*low)
+
+	// Words were checked for profanity when loaded
+	return fmt.Sprintf("%s-%s-%d",
+		s.adjectives[rand.IntN(len(s.adjectives))],
+		s.nouns[rand.IntN(len(s.nouns))],
+		number)
+}
+
+// observe tracks the collision rate and lengthens the number when it is too high
+func (s *wordAliasStrategy) observe(collided bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rate, grow := s.growth.record(collided); grow && s.digits < maxWordAliasDigits {
+		s.digits++
+		log.Printf("📏 Alias collision rate %.0f%% exceeds %.0f%%, word aliases now end in %d digits",
+			rate*100, s.growth.threshold*100, s.digits)
+	}
+}
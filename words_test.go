@@ -0,0 +1,94 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestAliasTokens(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"brave-otter-42", []string{"brave", "otter", "42"}},
+		{"my_link", []string{"my", "link"}},
+		{"bigAss", []string{"big", "ass"}},
+		{"ASSFile", []string{"ass", "file"}},
+		{"HTMLParser2", []string{"html", "parser", "2"}},
+		{"promo2024sale", []string{"promo", "2024", "sale"}},
+		{"café-привет", []string{"café", "привет"}},
+		{"shopпривет", []string{"shop", "привет"}},
+		{"--", nil},
+	}
+
+	for _, tt := range tests {
+		if got := aliasTokens(tt.input); !slices.Equal(got, tt.want) {
+			t.Errorf("aliasTokens(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestContainsProfanity(t *testing.T) {
+	tests := []struct {
+		alias string
+		want  bool
+	}{
+		// Innocent words that contain a listed word
+		{"class", false},
+		{"passport", false},
+		{"assets", false},
+		{"title", false},
+		{"document", false},
+		{"analytics", false},
+		{"button", false},
+		{"grape", false},
+		{"scrap", false},
+		{"cocktail", false},
+		{"sussex", false},
+		{"spring-class-2024", false},
+		{"ButtonAnalytics", false},
+
+		// Listed words on their own or between word boundaries
+		{"ass", true},
+		{"ASS", true},
+		{"my-ass", true},
+		{"my_ass_link", true},
+		{"bigAss", true},
+		{"ass42", true},
+		{"42ass", true},
+		{"promo-Crap-sale", true},
+	}
+
+	for _, tt := range tests {
+		if got := containsProfanity(tt.alias); got != tt.want {
+			t.Errorf("containsProfanity(%q) = %t, want %t", tt.alias, got, tt.want)
+		}
+	}
+}
+
+func TestAliasPolicyBlocklistFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blocklist.txt")
+	writeTestFile(t, path, "# Internal names\nacme corp\nfoo\n")
+
+	policy, err := newAliasPolicy(&Config{AliasBlocklistFiles: []string{path}})
+	if err != nil {
+		t.Fatalf("newAliasPolicy: %v", err)
+	}
+
+	// Entries of several words match those words in a row
+	for alias, blocked := range map[string]bool{
+		"foo":          true,
+		"foo-bar":      true,
+		"food":         false,
+		"acme-corp":    true,
+		"AcmeCorp2024": true,
+		"acme":         false,
+		"acmecorp":     false,
+	} {
+		if got := !policy.allows(alias); got != blocked {
+			t.Errorf("%q blocked = %t, want %t", alias, got, blocked)
+		}
+	}
+}